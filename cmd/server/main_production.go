@@ -20,16 +20,22 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
 
+	"github.com/user/pr-reviewer/internal/audit"
 	"github.com/user/pr-reviewer/internal/auth"
 	"github.com/user/pr-reviewer/internal/cache"
 	"github.com/user/pr-reviewer/internal/config"
 	"github.com/user/pr-reviewer/internal/database"
+	"github.com/user/pr-reviewer/internal/featureflags"
 	"github.com/user/pr-reviewer/internal/handler"
 	"github.com/user/pr-reviewer/internal/health"
 	"github.com/user/pr-reviewer/internal/logger"
 	"github.com/user/pr-reviewer/internal/metrics"
 	"github.com/user/pr-reviewer/internal/middleware"
+	"github.com/user/pr-reviewer/internal/notify"
 	"github.com/user/pr-reviewer/internal/service"
+	"github.com/user/pr-reviewer/internal/stream"
+	"github.com/user/pr-reviewer/internal/tracing"
+	"github.com/user/pr-reviewer/internal/webhook"
 )
 
 const (
@@ -122,32 +128,262 @@ func main() {
 		log.Info("Redis not configured, using no-op cache")
 		cacheClient = cache.NewNoOpCache()
 	}
-	defer cacheClient.Close()
+
+	// Флаг redis_cache переключает кеш приложения на no-op без рестарта
+	var flags *featureflags.Manager
+	appCache := cache.NewToggleCache(cacheClient, cache.NewNoOpCache(), func() bool {
+		return flags == nil || flags.IsEnabled(featureflags.FlagRedisCache)
+	})
+	defer appCache.Close()
 
 	// Инициализация health checks
 	healthChecker := health.New(Version, log)
 	healthChecker.RegisterChecker(health.NewDatabaseChecker(db.DB))
 	healthChecker.RegisterChecker(health.NewSystemChecker())
 
+	// Инициализация сервисов
+	svc := service.New(db)
+	svc.SetRefreshTokenTTL(getEnvAsDuration("JWT_REFRESH_EXPIRATION", service.DefaultRefreshTokenTTL))
+
 	// Инициализация JWT аутентификации (опционально)
 	var jwtAuth *auth.JWTAuth
-	jwtSecret := getEnv("JWT_SECRET", "")
-	if jwtSecret != "" && jwtSecret != "change_me_in_production" {
-		jwtExpiration := getEnvAsDuration("JWT_EXPIRATION", 24*time.Hour)
-		jwtAuth = auth.NewJWTAuth(jwtSecret, jwtExpiration, log)
-		log.Info("JWT authentication enabled")
-	} else {
-		log.Warn("JWT authentication disabled (JWT_SECRET not set)")
+	var jwtKeys *auth.KeyRing
+	jwtExpiration := getEnvAsDuration("JWT_EXPIRATION", 24*time.Hour)
+	switch jwtAlg := getEnv("JWT_SIGNING_ALG", auth.AlgHS256); jwtAlg {
+	case auth.AlgRS256, auth.AlgEdDSA:
+		// Асимметричная подпись: ключи хранятся в БД и ротируются по расписанию,
+		// проверяющим сервисам достаточно публичных ключей из /.well-known/jwks.json.
+		// Выведенный ключ принимается, пока не истекут подписанные им токены.
+		gracePeriod := getEnvAsDuration("JWT_KEY_GRACE_PERIOD", jwtExpiration)
+		if gracePeriod < jwtExpiration {
+			log.Warnw("JWT_KEY_GRACE_PERIOD is shorter than token expiration, using JWT_EXPIRATION",
+				"grace_period", gracePeriod, "expiration", jwtExpiration)
+			gracePeriod = jwtExpiration
+		}
+		// Приватные ключи хранятся в БД зашифрованными ключом из секрета
+		keyEncryptor, err := auth.NewKeyEncryptor(getEnv("JWT_KEY_ENCRYPTION_KEY", ""))
+		if err != nil {
+			log.Fatalw("JWT_KEY_ENCRYPTION_KEY is required for asymmetric JWT signing", "error", err)
+		}
+		svc.SetKeyEncryptor(keyEncryptor)
+		keys, err := auth.NewKeyRing(auth.KeyRingConfig{
+			Algorithm:        jwtAlg,
+			RotationInterval: getEnvAsDuration("JWT_KEY_ROTATION_INTERVAL", 30*24*time.Hour),
+			GracePeriod:      gracePeriod,
+		}, svc, log)
+		if err != nil {
+			log.Fatalw("Failed to initialize JWT signing keys", "error", err)
+		}
+		jwtKeys = keys
+		jwtAuth = auth.NewJWTAuthWithKeyRing(keys, jwtExpiration, log)
+		log.Infow("JWT authentication enabled", "algorithm", jwtAlg, "kid", keys.Active().ID)
+	case auth.AlgHS256:
+		jwtSecret := getEnv("JWT_SECRET", "")
+		if jwtSecret != "" && jwtSecret != "change_me_in_production" {
+			jwtAuth = auth.NewJWTAuth(jwtSecret, jwtExpiration, log)
+			log.Info("JWT authentication enabled")
+		} else {
+			log.Warn("JWT authentication disabled (JWT_SECRET not set)")
+		}
+	default:
+		log.Fatalw("Unsupported JWT_SIGNING_ALG", "algorithm", jwtAlg)
 	}
 
-	// Инициализация сервисов
-	svc := service.New(db)
+	// Список отзыва access токенов (jti и все токены пользователя). Это не кеш:
+	// список хранится в Redis независимо от флага redis_cache.
+	if jwtAuth != nil {
+		revocationCache := cacheClient
+		if _, noop := cacheClient.(*cache.NoOpCache); noop {
+			// Без Redis список хранится в памяти: отзыв действует только на этой реплике
+			revocationCache = cache.NewMemoryCache()
+		}
+		jwtAuth.SetRevocationStore(auth.NewRevocationStore(revocationCache, jwtExpiration))
+	}
+
+	// Инициализация feature flags: значения по умолчанию задаются в коде и
+	// окружении, их переопределяет файл флагов (ConfigMap), а файл - флаги
+	// из /admin/flags, которые хранятся в БД
+	flags = featureflags.NewManager(appCache, log)
+	flags.SetMetrics(met)
+	if jwtAuth != nil && getEnv("JWT_AUTH_REQUIRED", "false") == "true" {
+		// Проверка ролей на маршрутах API (матрица доступа в handler)
+		_ = flags.EnableFlag(featureflags.FlagJWTAuth)
+	}
+	flags.SetStore(featureflags.NewPostgresStore(db.DB))
+	if err := flags.Reload(context.Background()); err != nil {
+		log.Warnw("Failed to load feature flags, using defaults", "error", err)
+	}
+	flagsListener := featureflags.NewListener(cfg.DatabaseURL, flags, log)
+
+	var flagsFile *featureflags.FileProvider
+	if path := getEnv("FEATURE_FLAGS_FILE", ""); path != "" {
+		// Ошибка загрузки логируется провайдером и видна в /health;
+		// до исправления файла действуют остальные источники
+		flagsFile = featureflags.NewFileProvider(path, flags, log)
+		_ = flagsFile.Load()
+		healthChecker.RegisterNonCritical(flagsFile)
+	}
+
+	// Инициализация webhook: события пишутся в outbox в транзакции изменения,
+	// relay доставляет их подписчикам (at-least-once, порядок внутри PR)
+	appBaseURL := getEnv("APP_BASE_URL", "")
+	webhookDeliverer := webhook.NewHTTPDeliverer(log)
+	webhookDeliverer.SetSource(getEnv("WEBHOOK_EVENT_SOURCE", webhook.DefaultEventSource))
+	chatDeliverer := webhook.NewChatDeliverer(webhookDeliverer, webhook.NewUserDirectory(db.DB), appBaseURL, log)
+	webhookManager := webhook.NewManager(chatDeliverer, log)
+	webhookManager.SetFeatureFlags(flags)
+	outbox := webhook.NewOutbox(db.DB, log)
+	webhookManager.SetOutbox(outbox)
+	svc.SetEventRecorder(outbox)
+
+	// Подписки загружаются из БД; неудачные доставки сохраняются в dead letters,
+	// подписка отключается после серии ошибок подряд
+	subscriptionStore := webhook.NewSubscriptionStore(db.DB, log)
+	webhookManager.SetSubscriptionStore(subscriptionStore)
+	webhookManager.SetDeadLetters(webhook.NewDeadLetterStore(db.DB, log))
+	webhookManager.SetMaxFailureStreak(getEnvAsInt("WEBHOOK_MAX_FAILURE_STREAK", 10))
+
+	// Изменения подписок (ротация секрета, отключение) на любой реплике
+	// доходят до остальных через LISTEN/NOTIFY
+	if err := webhookManager.Reload(context.Background()); err != nil {
+		log.Warnw("Failed to load webhook subscriptions", "error", err)
+	}
+	subscriptionsListener := webhook.NewListener(cfg.DatabaseURL, webhookManager, log)
+
+	relayConfig := webhook.DefaultRelayConfig()
+	relayConfig.MaxAttempts = getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", relayConfig.MaxAttempts)
+	relay := webhook.NewRelay(db.DB, relayConfig, log)
+	relay.AddHandler(webhook.HandlerName, webhookManager)
+
+	// Inbox уведомления в приложении
+	inbox := notify.NewInbox(svc, log)
+	relay.AddHandler(notify.InboxHandlerName, inbox)
+
+	// Live stream событий PR: relay публикует события через NOTIFY,
+	// каждая реплика слушает канал и раздает события своим SSE клиентам
+	eventBroker := stream.NewBroker(getEnvAsInt("STREAM_BUFFER_SIZE", stream.DefaultBufferSize))
+	relay.AddHandler(stream.HandlerName, stream.NewNotifier(db.DB, log))
+	streamListener := stream.NewListener(cfg.DatabaseURL, db.DB, eventBroker, log)
+
+	// Email уведомления о назначениях и ежедневный дайджест (если настроен SMTP)
+	var digestScheduler *notify.DigestScheduler
+	if smtpHost := getEnv("SMTP_HOST", ""); smtpHost != "" {
+		mailer := notify.NewSMTPMailer(notify.SMTPConfig{
+			Host:          smtpHost,
+			Port:          getEnvAsInt("SMTP_PORT", 587),
+			Username:      getEnv("SMTP_USERNAME", ""),
+			Password:      getEnv("SMTP_PASSWORD", ""),
+			From:          getEnv("SMTP_FROM", "pr-reviewer@localhost"),
+			AllowInsecure: getEnv("SMTP_ALLOW_INSECURE", "false") == "true",
+		})
+		relay.AddHandler(notify.EmailHandlerName, notify.NewEmailNotifier(mailer, svc, appBaseURL, log))
+		digestScheduler = notify.NewDigestScheduler(svc, mailer, getEnvAsInt("DIGEST_HOUR", 8), time.Local, appBaseURL, log)
+		log.Infow("Email notifications enabled", "smtp_host", smtpHost)
+	}
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go relay.Run(workersCtx)
+	go func() {
+		if err := streamListener.Run(workersCtx); err != nil {
+			log.Errorw("Stream listener failed", "error", err)
+		}
+	}()
+	go func() {
+		if err := flagsListener.Run(workersCtx); err != nil {
+			log.Errorw("Feature flags listener failed", "error", err)
+		}
+	}()
+	go func() {
+		if err := subscriptionsListener.Run(workersCtx); err != nil {
+			log.Errorw("Webhook subscriptions listener failed", "error", err)
+		}
+	}()
+	if flagsFile != nil {
+		go flagsFile.Run(workersCtx, getEnvAsDuration("FEATURE_FLAGS_FILE_INTERVAL", 10*time.Second))
+	}
+	if digestScheduler != nil {
+		go digestScheduler.Run(workersCtx)
+	}
+	go inbox.RunRetention(workersCtx, time.Hour,
+		getEnvAsDuration("NOTIFICATION_RETENTION", 90*24*time.Hour))
+	if jwtKeys != nil {
+		// Ротация ключей подписи и подхват ключей, выпущенных другими репликами
+		go jwtKeys.Run(workersCtx, time.Minute)
+	}
+
+	// Месячные партиции audit log: создание на будущие месяцы и архивирование
+	// партиций старше срока хранения в gzip NDJSON. Архивирование по умолчанию
+	// включается только вместе с каталогом архивов на постоянном томе.
+	auditArchiveDir := getEnv("AUDIT_ARCHIVE_DIR", "")
+	auditRetentionMonths := 0
+	if auditArchiveDir != "" {
+		auditRetentionMonths = 12
+	}
+	auditRetention := audit.NewRetention(db.DB, audit.RetentionConfig{
+		RetentionMonths: getEnvAsInt("AUDIT_RETENTION_MONTHS", auditRetentionMonths),
+		ArchiveDir:      auditArchiveDir,
+		PartitionsAhead: getEnvAsInt("AUDIT_PARTITIONS_AHEAD", 3),
+	}, log)
+	go auditRetention.Run(workersCtx, time.Hour)
 
 	// Инициализация HTTP обработчиков
 	h := handler.New(svc, log)
+	h.SetWebhookManager(webhookManager)
+	h.SetEventBroker(eventBroker)
+	h.SetAuditLogger(audit.NewLogger(db.DB, log))
+	h.SetFeatureFlags(flags)
+	if err := h.SetTrustedProxies(splitAndTrim(getEnv("TRUSTED_PROXIES", ""), ",")); err != nil {
+		log.Fatalw("Invalid TRUSTED_PROXIES", "error", err)
+	}
+	if jwtAuth != nil {
+		h.SetJWTAuth(jwtAuth)
+		h.SetWebSocketServer(stream.NewWebSocketServer(eventBroker, getAllowedOrigins(), log))
+
+		// Вход через корпоративный IdP (OpenID Connect)
+		if issuer := getEnv("OIDC_ISSUER_URL", ""); issuer != "" {
+			oidcProvider, err := auth.NewOIDCProvider(workersCtx, auth.OIDCConfig{
+				IssuerURL:    issuer,
+				ClientID:     getEnv("OIDC_CLIENT_ID", ""),
+				ClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+				RedirectURL:  getEnv("OIDC_REDIRECT_URL", ""),
+				Scopes:       splitAndTrim(getEnv("OIDC_SCOPES", ""), ","),
+				RoleClaim:    getEnv("OIDC_ROLE_CLAIM", auth.DefaultOIDCRoleClaim),
+				RoleMapping:  auth.ParseOIDCMapping(getEnv("OIDC_ROLE_MAPPING", "")),
+				DefaultRole:  getEnv("OIDC_DEFAULT_ROLE", auth.RoleMember),
+				GroupsClaim:  getEnv("OIDC_GROUPS_CLAIM", auth.DefaultOIDCGroupsClaim),
+				GroupTeams:   auth.ParseOIDCMapping(getEnv("OIDC_GROUP_TEAMS", "")),
+			}, log)
+			if err != nil {
+				log.Errorw("OIDC login disabled", "issuer", issuer, "error", err)
+			} else {
+				h.SetOIDCProvider(oidcProvider, getEnv("OIDC_POST_LOGIN_REDIRECT", ""))
+				log.Infow("OIDC login enabled", "issuer", issuer)
+			}
+		}
+	}
 
 	// Настройка middleware
 	mw := middleware.New(log, met)
+	mw.SetFeatureFlags(flags)
+
+	// Distributed tracing: экспортер настраивается при запуске, флаг
+	// distributed_tracing включает и выключает трейсинг запросов без рестарта
+	tracingConfig := tracing.Config{
+		Enabled:     getEnv("TRACING_ENABLED", "false") == "true",
+		ServiceName: ServiceName,
+		Environment: environment,
+		OTLPURL:     getEnv("OTLP_ENDPOINT", "localhost:4318"),
+		SampleRate:  getEnvAsFloat("TRACING_SAMPLE_RATE", 1.0),
+	}
+	tracer, err := tracing.Init(tracingConfig, log)
+	if err != nil {
+		log.Fatalw("Failed to initialize tracing", "error", err)
+	}
+	if tracingConfig.Enabled {
+		_ = flags.EnableFlag(featureflags.FlagDistributedTracing)
+	}
+	tracer.SetFeatureFlags(flags)
 
 	// Настройка маршрутов
 	router := mux.NewRouter()
@@ -166,6 +402,7 @@ func main() {
 	// Применяем middleware
 	middlewareChain := middleware.Chain(
 		mw.RequestID,
+		tracer.Middleware,
 		mw.Logging,
 		mw.Metrics,
 		mw.Recovery,
@@ -182,17 +419,19 @@ func main() {
 
 	// Опционально: добавляем JWT аутентификацию
 	if jwtAuth != nil {
-		// Можно сделать селективную аутентификацию:
-		// - Публичные эндпоинты (GET /teams, GET /users) - без аутентификации
-		// - Мутирующие эндпоинты - с аутентификацией
-		// router.Use(jwtAuth.OptionalMiddleware)
+		// Middleware только разбирает токен; роли проверяются на маршрутах
+		// по матрице доступа, когда включен флаг jwt_auth
+		router.Use(jwtAuth.OptionalMiddleware)
 	}
 
+	// API ключи сервисных аккаунтов (CI боты): заполняют тот же контекст, что и JWT
+	router.Use(auth.NewAPIKeyAuth(svc, log).Middleware)
+
 	// Настройка CORS
 	corsHandler := cors.New(cors.Options{
 		AllowedOrigins:   getAllowedOrigins(),
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Requested-With", "Accept", "X-Request-ID"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Requested-With", "Accept", "X-Request-ID", auth.APIKeyHeader},
 		ExposedHeaders:   []string{"X-Request-ID"},
 		AllowCredentials: true,
 		MaxAge:           3600,
@@ -234,6 +473,9 @@ func main() {
 		log.Errorw("Server forced to shutdown", "error", err)
 	}
 
+	// Останавливаем relay и фоновые задачи после HTTP сервера: все принятые события уже в outbox
+	stopWorkers()
+
 	log.Info("Server exited gracefully")
 }
 
@@ -256,6 +498,18 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+	var value float64
+	if _, err := fmt.Sscanf(valueStr, "%g", &value); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	valueStr := getEnv(key, "")
 	if valueStr == "" {
@@ -5,6 +5,7 @@ import { CodeBracketIcon, UserIcon, LockClosedIcon } from '@heroicons/react/24/o
 import { toast } from 'react-hot-toast';
 import Button from '../UI/Button';
 import { useAuthStore, useThemeStore, useAppStore } from '../../store';
+import { apiService } from '../../services/api';
 import { motion } from 'framer-motion';
 
 const Login: React.FC = () => {
@@ -29,24 +30,22 @@ const Login: React.FC = () => {
 
     setIsLoading(true);
 
-    // Mock authentication - in production, this would call the API
-    setTimeout(() => {
-      // Accept any credentials for demo
-      const mockUser = {
-        id: 1,
-        username: credentials.username,
-        name: credentials.username.charAt(0).toUpperCase() + credentials.username.slice(1),
-        role: 'admin',
-      };
-      
-      // Mock token
-      localStorage.setItem('authToken', 'mock-jwt-token');
-      
-      login(mockUser);
+    try {
+      const { user } = await apiService.login(credentials.username, credentials.password);
+      login({
+        id: user.id,
+        username: user.username,
+        name: user.name,
+        role: user.role,
+      });
       toast.success('Login successful!');
       navigate('/');
+    } catch (error) {
+      const status = (error as { response?: { status?: number } }).response?.status;
+      toast.error(status === 401 ? 'Invalid username or password' : 'Login failed');
+    } finally {
       setIsLoading(false);
-    }, 1000);
+    }
   };
 
   const handleLanguageToggle = () => {
@@ -156,13 +155,6 @@ const Login: React.FC = () => {
               {t('nav.login')}
             </Button>
           </form>
-
-          {/* Demo note */}
-          <div className="mt-6 p-4 bg-blue-50 dark:bg-blue-900/20 rounded-lg">
-            <p className="text-sm text-blue-700 dark:text-blue-400">
-              <strong>Demo mode:</strong> Use any username and password to login
-            </p>
-          </div>
         </div>
       </motion.div>
     </div>
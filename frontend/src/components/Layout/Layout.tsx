@@ -17,6 +17,7 @@ import {
 } from '@heroicons/react/24/outline';
 import { cn } from '../../utils';
 import { useThemeStore, useAppStore, useAuthStore } from '../../store';
+import { apiService } from '../../services/api';
 import { motion, AnimatePresence } from 'framer-motion';
 
 const Layout: React.FC = () => {
@@ -43,7 +44,12 @@ const Layout: React.FC = () => {
     i18n.changeLanguage(newLang);
   };
 
-  const handleLogout = () => {
+  const handleLogout = async () => {
+    try {
+      await apiService.logout();
+    } catch {
+      // Сессия будет завершена локально в любом случае
+    }
     logout();
     navigate('/login');
   };
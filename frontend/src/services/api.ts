@@ -12,6 +12,7 @@ import type {
   RemoveUserFromTeamRequest,
   DeactivateUsersRequest,
   ReassignReviewerRequest,
+  AuthResponse,
 } from '../types';
 
 class ApiService {
@@ -49,10 +50,27 @@ class ApiService {
     // Response interceptor
     this.api.interceptors.response.use(
       (response) => response,
-      (error) => {
-        if (error.response?.status === 401) {
+      async (error) => {
+        const original = error.config;
+        const refreshToken = localStorage.getItem('refreshToken');
+        const isAuthRequest = original?.url?.startsWith('/auth/');
+
+        // Access токен истек: один раз пробуем обновить его по refresh токену
+        if (error.response?.status === 401 && refreshToken && !isAuthRequest && !original._retry) {
+          original._retry = true;
+          try {
+            const tokens = await this.refresh(refreshToken);
+            original.headers.Authorization = `Bearer ${tokens.accessToken}`;
+            return this.api(original);
+          } catch {
+            // Refresh токен недействителен - переходим на страницу входа
+          }
+        }
+
+        if (error.response?.status === 401 && !isAuthRequest) {
           // Обработка неавторизованного доступа
           localStorage.removeItem('authToken');
+          localStorage.removeItem('refreshToken');
           window.location.href = '/login';
         }
         return Promise.reject(error);
@@ -60,6 +78,36 @@ class ApiService {
     );
   }
 
+  // Authentication
+  async login(username: string, password: string): Promise<AuthResponse> {
+    const response = await this.api.post('/auth/login', { username, password });
+    this.storeTokens(response.data);
+    return response.data;
+  }
+
+  async refresh(refreshToken: string): Promise<AuthResponse> {
+    const response = await this.api.post('/auth/refresh', { refreshToken });
+    this.storeTokens(response.data);
+    return response.data;
+  }
+
+  async logout(): Promise<void> {
+    const refreshToken = localStorage.getItem('refreshToken');
+    if (refreshToken) {
+      await this.api.post('/auth/logout', { refreshToken });
+    }
+  }
+
+  async me(): Promise<User> {
+    const response = await this.api.get('/auth/me');
+    return response.data;
+  }
+
+  private storeTokens(tokens: AuthResponse) {
+    localStorage.setItem('authToken', tokens.accessToken);
+    localStorage.setItem('refreshToken', tokens.refreshToken);
+  }
+
   // Health check
   async health(): Promise<{ status: string }> {
     const response = await this.api.get('/health');
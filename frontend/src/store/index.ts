@@ -79,6 +79,7 @@ export const useAuthStore = create<AuthState>()(
       login: (user) => set({ isAuthenticated: true, user }),
       logout: () => {
         localStorage.removeItem('authToken');
+        localStorage.removeItem('refreshToken');
         set({ isAuthenticated: false, user: null });
       },
     }),
@@ -12,6 +12,7 @@ export interface User {
   username: string;
   name: string;
   isActive: boolean;
+  role?: string;
   createdAt: string;
   updatedAt: string;
   teams?: Team[];
@@ -93,3 +94,11 @@ export interface ApiError {
   error: string;
   message?: string;
 }
+
+export interface AuthResponse {
+  accessToken: string;
+  refreshToken: string;
+  tokenType: string;
+  expiresIn: number;
+  user: User;
+}
@@ -5,6 +5,8 @@ go 1.23.0
 toolchain go1.24.1
 
 require (
+	// OpenID Connect SSO
+	github.com/coreos/go-oidc/v3 v3.9.0
 	// JWT authentication
 	github.com/golang-jwt/jwt/v5 v5.2.0
 	// Existing dependencies
@@ -13,6 +15,9 @@ require (
 	// UUID for request ID
 	github.com/google/uuid v1.6.0
 	github.com/gorilla/mux v1.8.1
+
+	// WebSocket API
+	github.com/gorilla/websocket v1.5.3
 	github.com/lib/pq v1.10.9
 
 	// Metrics
@@ -36,8 +41,17 @@ require (
 	// Logging
 	go.uber.org/zap v1.27.0
 
+	// Password hashing
+	golang.org/x/crypto v0.41.0
+
+	// OAuth2 authorization code flow для OIDC
+	golang.org/x/oauth2 v0.30.0
+
 	// Rate limiting
 	golang.org/x/time v0.5.0
+
+	// Feature flags из файла (ConfigMap)
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -60,11 +74,11 @@ require (
 	go.uber.org/multierr v1.11.0 // indirect
 	golang.org/x/sys v0.35.0 // indirect
 	google.golang.org/protobuf v1.36.8 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 require (
 	github.com/cenkalti/backoff/v5 v5.0.3 // indirect
+	github.com/go-jose/go-jose/v3 v3.0.5 // indirect
 	github.com/grpc-ecosystem/grpc-gateway/v2 v2.27.2 // indirect
 	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
 	go.opentelemetry.io/otel/exporters/otlp/otlptrace v1.38.0 // indirect
@@ -29,6 +29,15 @@ const (
 	EntityTeam        Entity = "team"
 	EntityPullRequest Entity = "pull_request"
 	EntityReviewer    Entity = "reviewer"
+
+	// EntityServiceAccount сервисный аккаунт и его API ключи
+	EntityServiceAccount Entity = "service_account"
+
+	// EntityFeatureFlag feature flag; ключ флага передается в changes
+	EntityFeatureFlag Entity = "feature_flag"
+
+	// EntityWebhookSubscription подписка на webhook
+	EntityWebhookSubscription Entity = "webhook_subscription"
 )
 
 // Entry запись в audit log
@@ -45,6 +54,13 @@ type Entry struct {
 	RequestID   string                 `json:"request_id,omitempty"`
 	Changes     map[string]interface{} `json:"changes,omitempty"`
 	Description string                 `json:"description,omitempty"`
+
+	// ServiceAccountID автор изменения, если запрос выполнен по API ключу
+	ServiceAccountID int64 `json:"service_account_id,omitempty"`
+
+	// PrevHash и Hash звено цепочки хешей: SHA-256 содержимого записи вместе с хешем предыдущей
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
 }
 
 // Logger логирует действия пользователей
@@ -61,40 +77,19 @@ func NewLogger(db *sql.DB, log *logger.Logger) *Logger {
 	}
 }
 
-// Log записывает действие в audit log
+// Log записывает действие в audit log и добавляет его в цепочку хешей
 func (l *Logger) Log(ctx context.Context, entry *Entry) error {
-	entry.Timestamp = time.Now()
-
 	// Сериализуем changes
 	changesJSON, err := json.Marshal(entry.Changes)
+	if err == nil {
+		changesJSON, err = canonicalJSON(changesJSON)
+	}
 	if err != nil {
 		l.logger.Errorw("Failed to marshal audit changes", "error", err)
 		changesJSON = []byte("{}")
 	}
 
-	query := `
-		INSERT INTO audit_logs (
-			timestamp, action, entity, entity_id, user_id, user_email,
-			ip, user_agent, request_id, changes, description
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		RETURNING id
-	`
-
-	err = l.db.QueryRowContext(ctx, query,
-		entry.Timestamp,
-		entry.Action,
-		entry.Entity,
-		entry.EntityID,
-		sql.NullInt64{Int64: entry.UserID, Valid: entry.UserID > 0},
-		entry.UserEmail,
-		entry.IP,
-		entry.UserAgent,
-		entry.RequestID,
-		changesJSON,
-		entry.Description,
-	).Scan(&entry.ID)
-
-	if err != nil {
+	if err := l.insertChained(ctx, entry, changesJSON); err != nil {
 		l.logger.Errorw("Failed to write audit log",
 			"action", entry.Action,
 			"entity", entry.Entity,
@@ -114,108 +109,6 @@ func (l *Logger) Log(ctx context.Context, entry *Entry) error {
 	return nil
 }
 
-// Query возвращает записи audit log
-func (l *Logger) Query(ctx context.Context, filter Filter) ([]*Entry, error) {
-	query := `
-		SELECT id, timestamp, action, entity, entity_id, 
-		       COALESCE(user_id, 0), user_email, ip, user_agent, 
-		       request_id, changes, description
-		FROM audit_logs
-		WHERE 1=1
-	`
-	args := []interface{}{}
-	argNum := 1
-
-	if filter.UserID > 0 {
-		query += ` AND user_id = $` + string(rune(argNum+'0'))
-		args = append(args, filter.UserID)
-		argNum++
-	}
-
-	if filter.Entity != "" {
-		query += ` AND entity = $` + string(rune(argNum+'0'))
-		args = append(args, filter.Entity)
-		argNum++
-	}
-
-	if filter.Action != "" {
-		query += ` AND action = $` + string(rune(argNum+'0'))
-		args = append(args, filter.Action)
-		argNum++
-	}
-
-	if !filter.From.IsZero() {
-		query += ` AND timestamp >= $` + string(rune(argNum+'0'))
-		args = append(args, filter.From)
-		argNum++
-	}
-
-	if !filter.To.IsZero() {
-		query += ` AND timestamp <= $` + string(rune(argNum+'0'))
-		args = append(args, filter.To)
-		argNum++
-	}
-
-	query += ` ORDER BY timestamp DESC LIMIT $` + string(rune(argNum+'0'))
-	args = append(args, filter.Limit)
-
-	rows, err := l.db.QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	entries := []*Entry{}
-	for rows.Next() {
-		entry := &Entry{}
-		var changesJSON []byte
-		var userID sql.NullInt64
-
-		err := rows.Scan(
-			&entry.ID,
-			&entry.Timestamp,
-			&entry.Action,
-			&entry.Entity,
-			&entry.EntityID,
-			&userID,
-			&entry.UserEmail,
-			&entry.IP,
-			&entry.UserAgent,
-			&entry.RequestID,
-			&changesJSON,
-			&entry.Description,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if userID.Valid {
-			entry.UserID = userID.Int64
-		}
-
-		if len(changesJSON) > 0 {
-			if err := json.Unmarshal(changesJSON, &entry.Changes); err != nil {
-				// Log error but continue processing other entries
-				continue
-			}
-		}
-
-		entries = append(entries, entry)
-	}
-
-	return entries, rows.Err()
-}
-
-// Filter фильтр для запросов audit log
-type Filter struct {
-	UserID int64
-	Entity Entity
-	Action Action
-	From   time.Time
-	To     time.Time
-	Limit  int
-}
-
 // Helper функции для логирования различных действий
 
 // LogUserCreated логирует создание пользователя
@@ -282,3 +175,37 @@ func (l *Logger) LogReviewerAssigned(ctx context.Context, prID int64, reviewerID
 		Description: "Reviewer assigned",
 	})
 }
+
+// LogLogin логирует попытку входа
+func (l *Logger) LogLogin(ctx context.Context, userID int64, username string, success bool, ip, userAgent string) error {
+	description := "User logged in"
+	if !success {
+		description = "Login failed"
+	}
+	return l.Log(ctx, &Entry{
+		Action:    ActionLogin,
+		Entity:    EntityUser,
+		EntityID:  userID,
+		UserID:    userID,
+		IP:        ip,
+		UserAgent: userAgent,
+		Changes: map[string]interface{}{
+			"username": username,
+			"success":  success,
+		},
+		Description: description,
+	})
+}
+
+// LogLogout логирует выход пользователя
+func (l *Logger) LogLogout(ctx context.Context, userID int64, ip, userAgent string) error {
+	return l.Log(ctx, &Entry{
+		Action:      ActionLogout,
+		Entity:      EntityUser,
+		EntityID:    userID,
+		UserID:      userID,
+		IP:          ip,
+		UserAgent:   userAgent,
+		Description: "User logged out",
+	})
+}
@@ -0,0 +1,329 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// verifyBatchSize сколько записей читать за один запрос при проверке цепочки
+const verifyBatchSize = 1000
+
+// Причины разрыва цепочки
+const (
+	BrokenReasonModified = "entry modified: hash does not match content"
+	BrokenReasonLink     = "chain broken: previous entries were deleted or inserted"
+	BrokenReasonNoHash   = "entry has no hash"
+	BrokenReasonHead     = "chain head mismatch: latest entries were deleted"
+)
+
+// VerifyResult результат проверки цепочки хешей audit log
+type VerifyResult struct {
+	Valid   bool  `json:"valid"`
+	Checked int64 `json:"checked"`
+	// Unchained записи, созданные до включения цепочки
+	Unchained int64       `json:"unchained,omitempty"`
+	LastID    int64       `json:"lastId,omitempty"`
+	Broken    *BrokenLink `json:"brokenLink,omitempty"`
+}
+
+// BrokenLink первое место, где цепочка нарушена
+type BrokenLink struct {
+	EntryID int64 `json:"entryId"`
+	// PrevEntryID последняя запись, прошедшая проверку
+	PrevEntryID int64  `json:"prevEntryId,omitempty"`
+	Reason      string `json:"reason"`
+}
+
+// chainHead голова цепочки из таблицы audit_chain
+type chainHead struct {
+	lastID   int64
+	lastHash string
+}
+
+// insertChained записывает entry, связывая ее с предыдущей записью цепочки.
+// Блокировка головы цепочки упорядочивает записи всех реплик.
+func (l *Logger) insertChained(ctx context.Context, entry *Entry, changesJSON []byte) error {
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `SELECT last_hash FROM audit_chain WHERE id = 1 FOR UPDATE`).Scan(&entry.PrevHash)
+	if err != nil {
+		return fmt.Errorf("failed to lock audit chain head: %w", err)
+	}
+
+	// id и время входят в хеш, поэтому берем их до вставки. Время берется
+	// из часов БД под блокировкой цепочки: порядок времени совпадает с порядком
+	// id, и архивируемые по месяцам партиции содержат начало цепочки.
+	err = tx.QueryRowContext(ctx, `
+		SELECT nextval(pg_get_serial_sequence('audit_logs', 'id')),
+		       clock_timestamp() AT TIME ZONE 'UTC'`).Scan(&entry.ID, &entry.Timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to allocate audit entry id: %w", err)
+	}
+	entry.Hash = hashEntry(entry, changesJSON)
+
+	query := `
+		INSERT INTO audit_logs (
+			id, timestamp, action, entity, entity_id, user_id, user_email,
+			ip, user_agent, request_id, changes, description, service_account_id,
+			prev_hash, hash
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`
+	_, err = tx.ExecContext(ctx, query,
+		entry.ID,
+		entry.Timestamp,
+		entry.Action,
+		entry.Entity,
+		entry.EntityID,
+		sql.NullInt64{Int64: entry.UserID, Valid: entry.UserID > 0},
+		entry.UserEmail,
+		entry.IP,
+		entry.UserAgent,
+		entry.RequestID,
+		changesJSON,
+		entry.Description,
+		sql.NullInt64{Int64: entry.ServiceAccountID, Valid: entry.ServiceAccountID > 0},
+		entry.PrevHash,
+		entry.Hash,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE audit_chain SET last_id = $1, last_hash = $2, updated_at = NOW() WHERE id = 1`,
+		entry.ID, entry.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to update audit chain head: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit audit entry: %w", err)
+	}
+	return nil
+}
+
+// Verify проверяет цепочку хешей от первой записи (или конца последнего
+// архива) до головы и возвращает первое нарушение: измененную запись,
+// пропуск или удаление последних записей
+func (l *Logger) Verify(ctx context.Context) (*VerifyResult, error) {
+	var head chainHead
+	var lastID sql.NullInt64
+	err := l.db.QueryRowContext(ctx, `SELECT last_id, last_hash FROM audit_chain WHERE id = 1`).
+		Scan(&lastID, &head.lastHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit chain head: %w", err)
+	}
+	head.lastID = lastID.Int64
+
+	// Записи, добавленные после чтения головы, проверит следующий запуск
+	upperBound := ""
+	if head.lastID > 0 {
+		upperBound = " AND id <= " + strconv.FormatInt(head.lastID, 10)
+	}
+
+	// Начало цепочки в БД - последняя запись последнего архива
+	v := &chainVerifier{}
+	var anchorID sql.NullInt64
+	err = l.db.QueryRowContext(ctx, `
+		SELECT last_id, last_hash FROM audit_archives
+		ORDER BY last_id DESC NULLS LAST
+		LIMIT 1`).Scan(&anchorID, &v.prevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to read audit archives: %w", err)
+	}
+	v.prevID = anchorID.Int64
+	v.started = v.prevHash != ""
+
+	var afterID int64
+	for {
+		rows, err := l.db.QueryContext(ctx, `
+			SELECT `+entryColumns+`
+			FROM audit_logs
+			WHERE id > $1`+upperBound+`
+			ORDER BY id
+			LIMIT $2`, afterID, verifyBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+
+		n := 0
+		for rows.Next() {
+			entry, changesJSON, err := scanEntry(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			n++
+			afterID = entry.ID
+			if !v.check(entry, changesJSON) {
+				rows.Close()
+				return v.result(), nil
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+
+		if n < verifyBatchSize {
+			break
+		}
+	}
+
+	v.checkHead(head)
+	return v.result(), nil
+}
+
+// chainVerifier последовательно проверяет записи в порядке id
+type chainVerifier struct {
+	started   bool
+	prevID    int64
+	prevHash  string
+	checked   int64
+	unchained int64
+	broken    *BrokenLink
+}
+
+// check проверяет очередную запись; false - цепочка нарушена
+func (v *chainVerifier) check(entry *Entry, changesJSON []byte) bool {
+	if entry.Hash == "" {
+		if !v.started {
+			v.unchained++
+			return true
+		}
+		return v.fail(entry.ID, BrokenReasonNoHash)
+	}
+	v.started = true
+
+	if entry.PrevHash != v.prevHash {
+		return v.fail(entry.ID, BrokenReasonLink)
+	}
+
+	canonical, err := canonicalJSON(changesJSON)
+	if err != nil || hashEntry(entry, canonical) != entry.Hash {
+		return v.fail(entry.ID, BrokenReasonModified)
+	}
+
+	v.prevID = entry.ID
+	v.prevHash = entry.Hash
+	v.checked++
+	return true
+}
+
+// checkHead сверяет последнюю проверенную запись с головой цепочки
+func (v *chainVerifier) checkHead(head chainHead) {
+	if head.lastID != v.prevID || head.lastHash != v.prevHash {
+		v.fail(head.lastID, BrokenReasonHead)
+	}
+}
+
+func (v *chainVerifier) fail(entryID int64, reason string) bool {
+	v.broken = &BrokenLink{EntryID: entryID, PrevEntryID: v.prevID, Reason: reason}
+	return false
+}
+
+func (v *chainVerifier) result() *VerifyResult {
+	return &VerifyResult{
+		Valid:     v.broken == nil,
+		Checked:   v.checked,
+		Unchained: v.unchained,
+		LastID:    v.prevID,
+		Broken:    v.broken,
+	}
+}
+
+// hashedEntry содержимое записи, покрываемое хешем. Порядок полей фиксирован.
+type hashedEntry struct {
+	ID               int64           `json:"id"`
+	Timestamp        string          `json:"timestamp"`
+	Action           Action          `json:"action"`
+	Entity           Entity          `json:"entity"`
+	EntityID         int64           `json:"entity_id"`
+	UserID           int64           `json:"user_id"`
+	UserEmail        string          `json:"user_email"`
+	ServiceAccountID int64           `json:"service_account_id"`
+	IP               string          `json:"ip"`
+	UserAgent        string          `json:"user_agent"`
+	RequestID        string          `json:"request_id"`
+	Description      string          `json:"description"`
+	Changes          json.RawMessage `json:"changes"`
+	PrevHash         string          `json:"prev_hash"`
+}
+
+// hashEntry считает SHA-256 записи; changesJSON должен быть в каноническом виде
+func hashEntry(entry *Entry, changesJSON []byte) string {
+	data, _ := json.Marshal(hashedEntry{
+		ID:               entry.ID,
+		Timestamp:        entry.Timestamp.UTC().Format(time.RFC3339Nano),
+		Action:           entry.Action,
+		Entity:           entry.Entity,
+		EntityID:         entry.EntityID,
+		UserID:           entry.UserID,
+		UserEmail:        entry.UserEmail,
+		ServiceAccountID: entry.ServiceAccountID,
+		IP:               entry.IP,
+		UserAgent:        entry.UserAgent,
+		RequestID:        entry.RequestID,
+		Description:      entry.Description,
+		Changes:          changesJSON,
+		PrevHash:         entry.PrevHash,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalJSON приводит JSON к виду, не зависящему от хранения: JSONB
+// меняет порядок ключей, пробелы и экранирование, а числа выводит без экспоненты
+func canonicalJSON(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return []byte("null"), nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+
+	return json.Marshal(normalizeNumbers(value))
+}
+
+// normalizeNumbers записывает числа единообразно: целые как есть, остальные
+// в кратчайшем представлении float64
+func normalizeNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return v
+		}
+		if f, err := v.Float64(); err == nil {
+			return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	}
+	return value
+}
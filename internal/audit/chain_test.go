@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type chainedRow struct {
+	entry   *Entry
+	changes []byte
+}
+
+// buildChain строит цепочку так же, как insertChained
+func buildChain(t *testing.T, n int) []chainedRow {
+	t.Helper()
+	rows := make([]chainedRow, 0, n)
+	prevHash := ""
+	for i := 1; i <= n; i++ {
+		changes, err := json.Marshal(map[string]interface{}{"name": map[string]interface{}{"new": i}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := &Entry{
+			ID:        int64(i),
+			Timestamp: time.Date(2026, 1, 1, 0, 0, i, 1000, time.UTC),
+			Action:    ActionUpdate,
+			Entity:    EntityTeam,
+			EntityID:  1,
+			UserID:    2,
+			PrevHash:  prevHash,
+		}
+		entry.Hash = hashEntry(entry, changes)
+		prevHash = entry.Hash
+		rows = append(rows, chainedRow{entry: entry, changes: changes})
+	}
+	return rows
+}
+
+func verifyRows(rows []chainedRow, head chainHead) *VerifyResult {
+	v := &chainVerifier{}
+	for _, row := range rows {
+		if !v.check(row.entry, row.changes) {
+			return v.result()
+		}
+	}
+	v.checkHead(head)
+	return v.result()
+}
+
+func headOf(rows []chainedRow) chainHead {
+	last := rows[len(rows)-1].entry
+	return chainHead{lastID: last.ID, lastHash: last.Hash}
+}
+
+func TestVerifyChain(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		rows := buildChain(t, 5)
+		result := verifyRows(rows, headOf(rows))
+		if !result.Valid || result.Checked != 5 || result.LastID != 5 {
+			t.Errorf("expected valid chain of 5, got %+v", result)
+		}
+	})
+
+	t.Run("modified entry", func(t *testing.T) {
+		rows := buildChain(t, 5)
+		head := headOf(rows)
+		rows[2].entry.UserEmail = "someone@example.com"
+		assertBroken(t, verifyRows(rows, head), 3, BrokenReasonModified)
+	})
+
+	t.Run("modified changes", func(t *testing.T) {
+		rows := buildChain(t, 5)
+		head := headOf(rows)
+		rows[1].changes = []byte(`{"name": {"new": 42}}`)
+		assertBroken(t, verifyRows(rows, head), 2, BrokenReasonModified)
+	})
+
+	t.Run("deleted entry", func(t *testing.T) {
+		rows := buildChain(t, 5)
+		head := headOf(rows)
+		rows = append(rows[:2], rows[3:]...)
+		result := verifyRows(rows, head)
+		assertBroken(t, result, 4, BrokenReasonLink)
+		if result.Broken.PrevEntryID != 2 {
+			t.Errorf("expected prevEntryId 2, got %d", result.Broken.PrevEntryID)
+		}
+	})
+
+	t.Run("deleted tail", func(t *testing.T) {
+		rows := buildChain(t, 5)
+		head := headOf(rows)
+		assertBroken(t, verifyRows(rows[:3], head), 5, BrokenReasonHead)
+	})
+
+	t.Run("legacy entries before chain", func(t *testing.T) {
+		rows := buildChain(t, 3)
+		legacy := []chainedRow{{entry: &Entry{ID: 1}}, {entry: &Entry{ID: 2}}}
+		result := verifyRows(append(legacy, rows...), headOf(rows))
+		if !result.Valid || result.Unchained != 2 || result.Checked != 3 {
+			t.Errorf("expected valid chain with 2 unchained entries, got %+v", result)
+		}
+	})
+
+	t.Run("continues after archive", func(t *testing.T) {
+		rows := buildChain(t, 5)
+		archived := rows[1].entry
+		v := &chainVerifier{prevID: archived.ID, prevHash: archived.Hash, started: true}
+		for _, row := range rows[2:] {
+			if !v.check(row.entry, row.changes) {
+				t.Fatalf("unexpected break: %+v", v.result())
+			}
+		}
+		v.checkHead(headOf(rows))
+		if result := v.result(); !result.Valid || result.Checked != 3 {
+			t.Errorf("expected valid chain of 3 after archive, got %+v", result)
+		}
+	})
+
+	t.Run("entry without hash inside chain", func(t *testing.T) {
+		rows := buildChain(t, 3)
+		head := headOf(rows)
+		rows[1].entry.Hash = ""
+		assertBroken(t, verifyRows(rows, head), 2, BrokenReasonNoHash)
+	})
+}
+
+func assertBroken(t *testing.T, result *VerifyResult, entryID int64, reason string) {
+	t.Helper()
+	if result.Valid || result.Broken == nil {
+		t.Fatalf("expected broken chain, got %+v", result)
+	}
+	if result.Broken.EntryID != entryID || result.Broken.Reason != reason {
+		t.Errorf("expected break at %d (%s), got %d (%s)", entryID, reason, result.Broken.EntryID, result.Broken.Reason)
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	// Так changes возвращает JSONB: другой порядок ключей, пробелы, без экранирования HTML
+	written, err := json.Marshal(map[string]interface{}{
+		"title": map[string]interface{}{"old": "<a>", "new": "b & c"},
+		"big":   1e21,
+		"id":    int64(9007199254740993),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := []byte(`{"id": 9007199254740993, "big": 1000000000000000000000, "title": {"new": "b & c", "old": "<a>"}}`)
+
+	a, err := canonicalJSON(written)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := canonicalJSON(stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a) != string(b) {
+		t.Errorf("canonical forms differ:\n%s\n%s", a, b)
+	}
+
+	if null, _ := canonicalJSON(nil); string(null) != "null" {
+		t.Errorf("expected null for empty changes, got %s", null)
+	}
+}
@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// diffIgnoredFields поля, которые меняются при любом изменении и не несут информации
+var diffIgnoredFields = map[string]bool{
+	"updatedAt":  true,
+	"updated_at": true,
+}
+
+// Diff возвращает изменившиеся поля в виде {"поле": {"old": ..., "new": ...}}.
+// Сущности сравниваются по JSON представлению, поэтому скрытые из API поля
+// (хеши паролей и ключей) в audit log не попадают. before == nil - создание,
+// after == nil - удаление.
+func Diff(before, after interface{}) map[string]interface{} {
+	oldFields := toFields(before)
+	newFields := toFields(after)
+
+	changes := make(map[string]interface{})
+	for field, oldValue := range oldFields {
+		if diffIgnoredFields[field] {
+			continue
+		}
+		newValue, ok := newFields[field]
+		if !ok {
+			changes[field] = map[string]interface{}{"old": oldValue}
+		} else if !reflect.DeepEqual(oldValue, newValue) {
+			changes[field] = map[string]interface{}{"old": oldValue, "new": newValue}
+		}
+	}
+	for field, newValue := range newFields {
+		if _, ok := oldFields[field]; !ok && !diffIgnoredFields[field] {
+			changes[field] = map[string]interface{}{"new": newValue}
+		}
+	}
+
+	return changes
+}
+
+// toFields преобразует сущность в map полей ее JSON представления
+func toFields(entity interface{}) map[string]interface{} {
+	if entity == nil {
+		return nil
+	}
+	if v := reflect.ValueOf(entity); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	return fields
+}
@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type diffEntity struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Secret    string    `json:"-"`
+	Tags      []string  `json:"tags,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func TestDiff(t *testing.T) {
+	before := &diffEntity{ID: 1, Name: "old", Secret: "a", Tags: []string{"x"}, UpdatedAt: time.Unix(1, 0)}
+	after := &diffEntity{ID: 1, Name: "new", Secret: "b", UpdatedAt: time.Unix(2, 0)}
+
+	tests := []struct {
+		name   string
+		before interface{}
+		after  interface{}
+		want   map[string]interface{}
+	}{
+		{
+			name:   "update",
+			before: before,
+			after:  after,
+			want: map[string]interface{}{
+				"name": map[string]interface{}{"old": "old", "new": "new"},
+				"tags": map[string]interface{}{"old": []interface{}{"x"}},
+			},
+		},
+		{
+			name:   "create",
+			before: nil,
+			after:  &diffEntity{ID: 2, Name: "team"},
+			want: map[string]interface{}{
+				"id":   map[string]interface{}{"new": float64(2)},
+				"name": map[string]interface{}{"new": "team"},
+			},
+		},
+		{
+			name:   "delete with typed nil after",
+			before: &diffEntity{ID: 3},
+			after:  (*diffEntity)(nil),
+			want: map[string]interface{}{
+				"id":   map[string]interface{}{"old": float64(3)},
+				"name": map[string]interface{}{"old": ""},
+			},
+		},
+		{
+			name:   "no changes",
+			before: before,
+			after:  before,
+			want:   map[string]interface{}{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff(tt.before, tt.after)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
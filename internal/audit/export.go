@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Форматы выгрузки audit log
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// EntryWriter пишет записи audit log в поток
+type EntryWriter interface {
+	Write(entry *Entry) error
+	// Flush сбрасывает буфер в нижележащий поток
+	Flush() error
+}
+
+// NewEntryWriter создает writer для формата выгрузки
+func NewEntryWriter(format string, w io.Writer) (EntryWriter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// ndjsonWriter пишет по одной JSON записи на строку
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(entry *Entry) error {
+	return w.enc.Encode(entry)
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return nil
+}
+
+// csvHeader колонки CSV выгрузки; changes пишется JSON строкой
+var csvHeader = []string{
+	"id", "timestamp", "action", "entity", "entity_id", "user_id", "user_email",
+	"service_account_id", "ip", "user_agent", "request_id", "description", "changes",
+	"prev_hash", "hash",
+}
+
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (w *csvWriter) Write(entry *Entry) error {
+	if !w.headerWritten {
+		if err := w.w.Write(csvHeader); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+
+	changes := ""
+	if len(entry.Changes) > 0 {
+		data, err := json.Marshal(entry.Changes)
+		if err != nil {
+			return fmt.Errorf("failed to marshal changes: %w", err)
+		}
+		changes = string(data)
+	}
+
+	return w.w.Write([]string{
+		strconv.FormatInt(entry.ID, 10),
+		entry.Timestamp.UTC().Format(time.RFC3339Nano),
+		string(entry.Action),
+		string(entry.Entity),
+		strconv.FormatInt(entry.EntityID, 10),
+		formatOptionalID(entry.UserID),
+		entry.UserEmail,
+		formatOptionalID(entry.ServiceAccountID),
+		entry.IP,
+		entry.UserAgent,
+		entry.RequestID,
+		entry.Description,
+		changes,
+		entry.PrevHash,
+		entry.Hash,
+	})
+}
+
+// Flush пишет заголовок, если записей не было, и сбрасывает буфер
+func (w *csvWriter) Flush() error {
+	if !w.headerWritten {
+		if err := w.w.Write(csvHeader); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func formatOptionalID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewEntryWriter(FormatCSV, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entry := &Entry{
+		ID:          7,
+		Timestamp:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Action:      ActionUpdate,
+		Entity:      EntityTeam,
+		EntityID:    3,
+		UserID:      1,
+		Description: "Team renamed, \"quoted\"",
+		Changes:     map[string]interface{}{"name": map[string]interface{}{"old": "a", "new": "b"}},
+	}
+	if err := w.Write(entry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected header and 1 row, got %d records", len(records))
+	}
+	row := map[string]string{}
+	for i, column := range records[0] {
+		row[column] = records[1][i]
+	}
+	if row["timestamp"] != "2026-01-02T03:04:05Z" || row["service_account_id"] != "" || row["description"] != entry.Description {
+		t.Errorf("unexpected row: %v", row)
+	}
+	if row["changes"] != `{"name":{"new":"b","old":"a"}}` {
+		t.Errorf("unexpected changes: %s", row["changes"])
+	}
+}
+
+func TestCSVWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w, _ := NewEntryWriter(FormatCSV, &buf)
+	if err := w.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != strings.Join(csvHeader, ",") {
+		t.Errorf("expected header only, got %q", got)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, _ := NewEntryWriter(FormatNDJSON, &buf)
+	for i := int64(1); i <= 2; i++ {
+		if err := w.Write(&Entry{ID: i, Action: ActionCreate}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	var entry Entry
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil || entry.ID != 2 {
+		t.Errorf("unexpected line %q: %v", lines[1], err)
+	}
+}
+
+func TestNewEntryWriterUnsupported(t *testing.T) {
+	if _, err := NewEntryWriter("xml", &bytes.Buffer{}); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
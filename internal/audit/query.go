@@ -0,0 +1,225 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLimit размер страницы по умолчанию
+	DefaultLimit = 100
+	// MaxLimit максимальный размер страницы
+	MaxLimit = 1000
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Filter фильтр для запросов audit log
+type Filter struct {
+	UserID           int64
+	ServiceAccountID int64
+	Entity           Entity
+	EntityID         int64
+	Action           Action
+	RequestID        string
+	From             time.Time
+	To               time.Time
+	Limit            int
+
+	// Cursor курсор страницы из Page.NextCursor, пустой - первая страница
+	Cursor string
+}
+
+// Page страница записей audit log, от новых к старым
+type Page struct {
+	Entries    []*Entry `json:"entries"`
+	NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+// Query возвращает страницу записей audit log. Страницы строятся по id
+// (keyset), поэтому новые записи не сдвигают уже выданные страницы.
+func (l *Logger) Query(ctx context.Context, filter Filter) (*Page, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	var q queryBuilder
+	if filter.UserID > 0 {
+		q.where("user_id = ?", filter.UserID)
+	}
+	if filter.ServiceAccountID > 0 {
+		q.where("service_account_id = ?", filter.ServiceAccountID)
+	}
+	if filter.Entity != "" {
+		q.where("entity = ?", filter.Entity)
+	}
+	if filter.EntityID > 0 {
+		q.where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Action != "" {
+		q.where("action = ?", filter.Action)
+	}
+	if filter.RequestID != "" {
+		q.where("request_id = ?", filter.RequestID)
+	}
+	if !filter.From.IsZero() {
+		q.where("timestamp >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		q.where("timestamp <= ?", filter.To)
+	}
+	if filter.Cursor != "" {
+		beforeID, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		q.where("id < ?", beforeID)
+	}
+
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	query := `
+		SELECT ` + entryColumns + `
+		FROM audit_logs` + q.sql() + `
+		ORDER BY id DESC
+		LIMIT ` + q.arg(limit+1)
+
+	rows, err := l.db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	page := &Page{Entries: []*Entry{}}
+	for rows.Next() {
+		entry, changesJSON, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(changesJSON) > 0 {
+			if err := json.Unmarshal(changesJSON, &entry.Changes); err != nil {
+				l.logger.Warnw("Invalid audit changes", "id", entry.ID, "error", err)
+			}
+		}
+
+		page.Entries = append(page.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	if len(page.Entries) > limit {
+		page.Entries = page.Entries[:limit]
+		page.NextCursor = encodeCursor(page.Entries[limit-1].ID)
+	}
+	return page, nil
+}
+
+// Export передает в fn все записи, подходящие под фильтр, читая их
+// страницами по MaxLimit. Limit фильтра игнорируется.
+func (l *Logger) Export(ctx context.Context, filter Filter, fn func(*Entry) error) error {
+	filter.Limit = MaxLimit
+	for {
+		page, err := l.Query(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, entry := range page.Entries {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+// entryColumns колонки записи в порядке scanEntry
+const entryColumns = `id, timestamp, action, entity, entity_id,
+		       COALESCE(user_id, 0), COALESCE(user_email, ''), COALESCE(ip, ''),
+		       COALESCE(user_agent, ''), COALESCE(request_id, ''), changes,
+		       COALESCE(description, ''), COALESCE(service_account_id, 0),
+		       COALESCE(prev_hash, ''), COALESCE(hash, '')`
+
+// scanEntry читает запись; changes возвращаются как есть, для проверки хеша
+func scanEntry(rows *sql.Rows) (*Entry, []byte, error) {
+	entry := &Entry{}
+	var changesJSON []byte
+
+	err := rows.Scan(
+		&entry.ID,
+		&entry.Timestamp,
+		&entry.Action,
+		&entry.Entity,
+		&entry.EntityID,
+		&entry.UserID,
+		&entry.UserEmail,
+		&entry.IP,
+		&entry.UserAgent,
+		&entry.RequestID,
+		&changesJSON,
+		&entry.Description,
+		&entry.ServiceAccountID,
+		&entry.PrevHash,
+		&entry.Hash,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to scan audit entry: %w", err)
+	}
+	return entry, changesJSON, nil
+}
+
+// queryBuilder собирает WHERE с нумерованными плейсхолдерами PostgreSQL
+type queryBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+// arg добавляет аргумент и возвращает его плейсхолдер
+func (b *queryBuilder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+// where добавляет условие; ? заменяется плейсхолдером аргумента
+func (b *queryBuilder) where(condition string, value interface{}) {
+	b.conditions = append(b.conditions, strings.Replace(condition, "?", b.arg(value), 1))
+}
+
+func (b *queryBuilder) sql() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+	return "\n\t\tWHERE " + strings.Join(b.conditions, " AND ")
+}
+
+// encodeCursor кодирует id последней записи страницы. Курсор непрозрачен
+// для клиентов, чтобы формат можно было поменять.
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id, err := decodeCursor(encodeCursor(12345))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id != 12345 {
+		t.Errorf("expected 12345, got %d", id)
+	}
+
+	for _, cursor := range []string{"!!!", encodeCursor(0), "YWJj"} {
+		if _, err := decodeCursor(cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q: expected ErrInvalidCursor, got %v", cursor, err)
+		}
+	}
+}
+
+func TestQueryBuilderPlaceholders(t *testing.T) {
+	var q queryBuilder
+	for i := 0; i < 11; i++ {
+		q.where("entity_id = ?", i)
+	}
+
+	sql := q.sql()
+	// Плейсхолдеры после девятого раньше превращались в символы ':' и ';'
+	for _, want := range []string{"$9", "$10", "$11"} {
+		if !strings.Contains(sql, "entity_id = "+want) {
+			t.Errorf("expected %s in %q", want, sql)
+		}
+	}
+	if len(q.args) != 11 {
+		t.Errorf("expected 11 args, got %d", len(q.args))
+	}
+}
@@ -0,0 +1,306 @@
+package audit
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+// partitionPrefix префикс месячных партиций audit_logs: audit_logs_2026_01
+const partitionPrefix = "audit_logs_"
+
+// retentionLockKey ключ advisory lock: обслуживание партиций выполняет одна реплика
+const retentionLockKey = 7_310_046
+
+// RetentionConfig настройки хранения audit log
+type RetentionConfig struct {
+	// RetentionMonths сколько полных месяцев хранить в БД; 0 - не архивировать
+	RetentionMonths int
+	// ArchiveDir каталог архивов: абсолютный путь на постоянном томе, при
+	// нескольких репликах - общий для всех. Без него архивирование выключено.
+	ArchiveDir string
+	// PartitionsAhead на сколько месяцев вперед создавать партиции
+	PartitionsAhead int
+}
+
+// Retention создает партиции audit_logs на будущие месяцы и выгружает
+// партиции старше срока хранения в архивы NDJSON (gzip)
+type Retention struct {
+	db     *sql.DB
+	cfg    RetentionConfig
+	logger *logger.Logger
+}
+
+// NewRetention создает задачу обслуживания партиций audit log.
+// Архивирование включается только с абсолютным ArchiveDir: относительный
+// путь указывает во временную файловую систему пода, и архив потерялся бы
+// вместе с удаленной партицией.
+func NewRetention(db *sql.DB, cfg RetentionConfig, log *logger.Logger) *Retention {
+	if cfg.PartitionsAhead < 1 {
+		cfg.PartitionsAhead = 1
+	}
+	if cfg.RetentionMonths > 0 && !filepath.IsAbs(cfg.ArchiveDir) {
+		log.Errorw("Audit log archiving disabled: archive directory must be an absolute path",
+			"archive_dir", cfg.ArchiveDir,
+			"retention_months", cfg.RetentionMonths,
+		)
+		cfg.RetentionMonths = 0
+	}
+	return &Retention{db: db, cfg: cfg, logger: log}
+}
+
+// Run обслуживает партиции с интервалом до отмены контекста
+func (r *Retention) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.RunOnce(ctx, time.Now()); err != nil {
+			r.logger.Errorw("Audit log retention failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce создает недостающие партиции и архивирует устаревшие. Если
+// обслуживание уже выполняет другая реплика, ничего не делает.
+func (r *Retention) RunOnce(ctx context.Context, now time.Time) error {
+	// Session advisory lock требует одного соединения на все запросы
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, retentionLockKey).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to acquire retention lock: %w", err)
+	}
+	if !locked {
+		return nil
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, retentionLockKey)
+
+	if err := r.createPartitions(ctx, conn, now); err != nil {
+		return err
+	}
+	if r.cfg.RetentionMonths <= 0 {
+		return nil
+	}
+	return r.archivePartitions(ctx, conn, now)
+}
+
+// createPartitions создает партиции текущего и следующих месяцев
+func (r *Retention) createPartitions(ctx context.Context, conn *sql.Conn, now time.Time) error {
+	current := monthStart(now)
+	for i := 0; i <= r.cfg.PartitionsAhead; i++ {
+		from := current.AddDate(0, i, 0)
+		to := from.AddDate(0, 1, 0)
+		query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF audit_logs FOR VALUES FROM (%s) TO (%s)`,
+			pq.QuoteIdentifier(partitionName(from)),
+			pq.QuoteLiteral(from.Format("2006-01-02")),
+			pq.QuoteLiteral(to.Format("2006-01-02")))
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to create partition %s: %w", partitionName(from), err)
+		}
+	}
+	return nil
+}
+
+// archivePartitions выгружает партиции, целиком вышедшие за срок хранения,
+// от старых к новым. Отсоединенная, но не удаленная после сбоя партиция
+// архивируется повторно.
+func (r *Retention) archivePartitions(ctx context.Context, conn *sql.Conn, now time.Time) error {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT tablename FROM pg_tables
+		WHERE schemaname = current_schema() AND tablename LIKE 'audit\_logs\_%'`)
+	if err != nil {
+		return fmt.Errorf("failed to list audit partitions: %w", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan partition name: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list audit partitions: %w", err)
+	}
+
+	cutoff := monthStart(now).AddDate(0, -r.cfg.RetentionMonths, 0)
+	for _, month := range expiredPartitions(tables, cutoff) {
+		if err := r.archivePartition(ctx, conn, month); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archivePartition отсоединяет партицию месяца, пишет ее в архив, запоминает
+// архив вместе с последним хешем цепочки и удаляет партицию
+func (r *Retention) archivePartition(ctx context.Context, conn *sql.Conn, month time.Time) error {
+	name := partitionName(month)
+	table := pq.QuoteIdentifier(name)
+
+	var attached bool
+	err := conn.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+			WHERE c.relname = $1 AND i.inhparent = 'audit_logs'::regclass
+		)`, name).Scan(&attached)
+	if err != nil {
+		return fmt.Errorf("failed to check partition %s: %w", name, err)
+	}
+	if attached {
+		if _, err := conn.ExecContext(ctx, `ALTER TABLE audit_logs DETACH PARTITION `+table); err != nil {
+			return fmt.Errorf("failed to detach partition %s: %w", name, err)
+		}
+	}
+
+	path := filepath.Join(r.cfg.ArchiveDir, name+".ndjson.gz")
+	var entries, lastID int64
+	var lastHash string
+	err = writeArchive(path, func(w EntryWriter) error {
+		rows, err := conn.QueryContext(ctx, `SELECT `+entryColumns+` FROM `+table+` ORDER BY id`)
+		if err != nil {
+			return fmt.Errorf("failed to read partition: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			entry, changesJSON, err := scanEntry(rows)
+			if err != nil {
+				return err
+			}
+			if len(changesJSON) > 0 {
+				if err := json.Unmarshal(changesJSON, &entry.Changes); err != nil {
+					return fmt.Errorf("invalid changes in entry %d: %w", entry.ID, err)
+				}
+			}
+			if err := w.Write(entry); err != nil {
+				return err
+			}
+			entries++
+			lastID, lastHash = entry.ID, entry.Hash
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive partition %s: %w", name, err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_archives (partition_name, period_start, period_end, file_path, entries, last_id, last_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (partition_name) DO UPDATE SET
+			file_path = EXCLUDED.file_path, entries = EXCLUDED.entries,
+			last_id = EXCLUDED.last_id, last_hash = EXCLUDED.last_hash, archived_at = NOW()`,
+		name, month, month.AddDate(0, 1, 0), path, entries,
+		sql.NullInt64{Int64: lastID, Valid: lastID > 0}, lastHash)
+	if err != nil {
+		return fmt.Errorf("failed to record archive %s: %w", name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DROP TABLE `+table); err != nil {
+		return fmt.Errorf("failed to drop partition %s: %w", name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit archive %s: %w", name, err)
+	}
+
+	r.logger.Infow("Audit log partition archived", "partition", name, "entries", entries, "file", path)
+	return nil
+}
+
+// writeArchive пишет gzip NDJSON во временный файл и переименовывает его
+// только после успешной записи на диск
+func writeArchive(path string, write func(EntryWriter) error) (err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	gz := gzip.NewWriter(f)
+	w, err := NewEntryWriter(FormatNDJSON, gz)
+	if err != nil {
+		return err
+	}
+	if err := write(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// expiredPartitions возвращает месяцы партиций, закончившихся до cutoff,
+// от старых к новым. Таблицы с другими именами (audit_logs_default) пропускаются.
+func expiredPartitions(tables []string, cutoff time.Time) []time.Time {
+	var months []time.Time
+	for _, name := range tables {
+		month, err := time.Parse("2006_01", strings.TrimPrefix(name, partitionPrefix))
+		if err != nil || partitionName(month) != name {
+			continue
+		}
+		if !month.AddDate(0, 1, 0).After(cutoff) {
+			months = append(months, month)
+		}
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+	return months
+}
+
+func partitionName(month time.Time) string {
+	return partitionPrefix + month.Format("2006_01")
+}
+
+// monthStart начало месяца в UTC: время записей хранится в UTC
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
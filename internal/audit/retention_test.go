@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+func TestExpiredPartitions(t *testing.T) {
+	tables := []string{
+		"audit_logs_2026_03",
+		"audit_logs_default",
+		"audit_logs_2025_12",
+		"audit_logs_2026_01",
+		"audit_logs_2026_1",
+		"audit_logs_legacy",
+		"audit_logs_2026_02",
+	}
+	// Срок хранения 2 месяца на 15 апреля: в БД остаются февраль, март и апрель
+	cutoff := monthStart(time.Date(2026, 4, 15, 10, 0, 0, 0, time.UTC)).AddDate(0, -2, 0)
+
+	got := expiredPartitions(tables, cutoff)
+	want := []time.Time{
+		time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMonthStart(t *testing.T) {
+	// 31 января 23:30 в UTC-5 - уже февраль в UTC
+	t1 := time.Date(2026, 1, 31, 23, 30, 0, 0, time.FixedZone("EST", -5*3600))
+	if got := partitionName(monthStart(t1)); got != "audit_logs_2026_02" {
+		t.Errorf("expected audit_logs_2026_02, got %s", got)
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive", "audit_logs_2026_01.ndjson.gz")
+
+	err := writeArchive(path, func(w EntryWriter) error {
+		for i := int64(1); i <= 3; i++ {
+			if err := w.Write(&Entry{ID: i, Hash: "h"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("archive not written: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("archive is not gzip: %v", err)
+	}
+
+	var ids []int64
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, entry.ID)
+	}
+	if !reflect.DeepEqual(ids, []int64{1, 2, 3}) {
+		t.Errorf("expected ids 1..3, got %v", ids)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestWriteArchive_FailureLeavesNoFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit_logs_2026_01.ndjson.gz")
+
+	err := writeArchive(path, func(w EntryWriter) error {
+		return errors.New("read failed")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, p := range []string{path, path + ".tmp"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s should not exist: %v", p, err)
+		}
+	}
+}
+
+func TestNewRetention_RequiresAbsoluteArchiveDir(t *testing.T) {
+	log, err := logger.New("error", "test")
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	tests := []struct {
+		dir  string
+		want int
+	}{
+		{"", 0},
+		{"audit-archive", 0},
+		{"/var/lib/pr-reviewer/audit-archive", 12},
+	}
+	for _, tt := range tests {
+		r := NewRetention(nil, RetentionConfig{RetentionMonths: 12, ArchiveDir: tt.dir}, log)
+		if r.cfg.RetentionMonths != tt.want {
+			t.Errorf("archive dir %q: expected retention %d, got %d", tt.dir, tt.want, r.cfg.RetentionMonths)
+		}
+	}
+}
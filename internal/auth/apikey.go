@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+// APIKeyHeader заголовок с API ключом
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyPrefix префикс API ключей, по нему ключи находят сканеры секретов
+const APIKeyPrefix = "prr_"
+
+// apiKeyDisplayLength длина видимой части ключа (вместе с префиксом)
+const apiKeyDisplayLength = 12
+
+// Области доступа API ключей
+const (
+	ScopePRRead     = "pr:read"
+	ScopePRWrite    = "pr:write"
+	ScopeTeamsRead  = "teams:read"
+	ScopeTeamsWrite = "teams:write"
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+	ScopeStatsRead  = "stats:read"
+	ScopeAuditRead  = "audit:read"
+)
+
+const (
+	contextKeyServiceAccountID contextKey = "service_account_id"
+	contextKeyScopes           contextKey = "scopes"
+)
+
+var (
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	ErrExpiredAPIKey = errors.New("API key expired")
+)
+
+// ValidScope проверяет, что область доступа известна
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopePRRead, ScopePRWrite, ScopeTeamsRead, ScopeTeamsWrite,
+		ScopeUsersRead, ScopeUsersWrite, ScopeStatsRead, ScopeAuditRead:
+		return true
+	}
+	return false
+}
+
+// APIKeyPrincipal сервисный аккаунт, которому принадлежит ключ
+type APIKeyPrincipal struct {
+	ServiceAccountID int64
+	Name             string
+	Role             string
+	TeamID           int64
+	Scopes           []string
+}
+
+// APIKeyValidator проверяет API ключ и возвращает его владельца
+type APIKeyValidator interface {
+	ValidateAPIKey(ctx context.Context, key string) (*APIKeyPrincipal, error)
+}
+
+// APIKeyAuth аутентификация сервисных аккаунтов по заголовку X-API-Key
+type APIKeyAuth struct {
+	validator APIKeyValidator
+	logger    *logger.Logger
+}
+
+// NewAPIKeyAuth создает новый API key auth
+func NewAPIKeyAuth(validator APIKeyValidator, log *logger.Logger) *APIKeyAuth {
+	return &APIKeyAuth{
+		validator: validator,
+		logger:    log,
+	}
+}
+
+// GenerateAPIKey генерирует новый ключ. Возвращает сам ключ (показывается
+// один раз), видимую часть для списка ключей и хеш для хранения.
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return key, key[:apiKeyDisplayLength], HashAPIKey(key), nil
+}
+
+// HashAPIKey возвращает SHA-256 хеш ключа
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Middleware аутентифицирует запрос по X-API-Key и заполняет те же ключи
+// контекста, что и JWT middleware (роль и команда сервисного аккаунта).
+// Запросы без заголовка пропускаются без изменений, неверный ключ — 401.
+func (a *APIKeyAuth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(APIKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !strings.HasPrefix(key, APIKeyPrefix) {
+			a.sendError(w, ErrInvalidAPIKey, http.StatusUnauthorized)
+			return
+		}
+
+		principal, err := a.validator.ValidateAPIKey(r.Context(), key)
+		if err != nil {
+			a.logger.Warnw("API key authentication failed",
+				"error", err,
+				"path", r.URL.Path,
+			)
+			if errors.Is(err, ErrExpiredAPIKey) {
+				a.sendError(w, ErrExpiredAPIKey, http.StatusUnauthorized)
+			} else {
+				a.sendError(w, ErrInvalidAPIKey, http.StatusUnauthorized)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), contextKeyRole, principal.Role)
+		ctx = context.WithValue(ctx, contextKeyTeamID, principal.TeamID)
+		ctx = context.WithValue(ctx, contextKeyServiceAccountID, principal.ServiceAccountID)
+		ctx = context.WithValue(ctx, contextKeyScopes, principal.Scopes)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetServiceAccountID извлекает ID сервисного аккаунта из контекста
+func GetServiceAccountID(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(contextKeyServiceAccountID).(int64)
+	return id, ok
+}
+
+// GetScopes извлекает области доступа API ключа из контекста.
+// ok равен false, если запрос аутентифицирован не по API ключу.
+func GetScopes(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(contextKeyScopes).([]string)
+	return scopes, ok
+}
+
+// HasScope проверяет, что API ключ запроса имеет область доступа
+func HasScope(ctx context.Context, scope string) bool {
+	scopes, _ := GetScopes(ctx)
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// sendError отправляет ошибку в формате JSON
+func (a *APIKeyAuth) sendError(w http.ResponseWriter, err error, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	fmt.Fprintf(w, `{"error":"%s"}`, err.Error())
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+type stubValidator struct {
+	principal *APIKeyPrincipal
+	err       error
+}
+
+func (s *stubValidator) ValidateAPIKey(_ context.Context, _ string) (*APIKeyPrincipal, error) {
+	return s.principal, s.err
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("failed to generate API key: %v", err)
+	}
+
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		t.Errorf("expected key with %s prefix, got %s", APIKeyPrefix, key)
+	}
+	if !strings.HasPrefix(key, prefix) || len(prefix) >= len(key) {
+		t.Errorf("expected display prefix to be the start of the key, got %s", prefix)
+	}
+	if hash != HashAPIKey(key) || len(hash) != 64 {
+		t.Errorf("expected SHA-256 hex hash of the key, got %s", hash)
+	}
+
+	other, _, _, _ := GenerateAPIKey()
+	if other == key {
+		t.Error("expected unique keys")
+	}
+}
+
+func TestAPIKeyAuth_Middleware(t *testing.T) {
+	log, _ := logger.New("error", "test")
+	validator := &stubValidator{principal: &APIKeyPrincipal{
+		ServiceAccountID: 7,
+		Role:             RoleMember,
+		TeamID:           3,
+		Scopes:           []string{ScopePRWrite},
+	}}
+	a := NewAPIKeyAuth(validator, log)
+
+	var ctx context.Context
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	}))
+
+	req := httptest.NewRequest("POST", "/pull-requests", nil)
+	req.Header.Set(APIKeyHeader, "prr_test")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if role, _ := GetUserRole(ctx); role != RoleMember {
+		t.Errorf("expected role %s in context, got %s", RoleMember, role)
+	}
+	if teamID, _ := GetTeamID(ctx); teamID != 3 {
+		t.Errorf("expected team 3 in context, got %d", teamID)
+	}
+	if id, _ := GetServiceAccountID(ctx); id != 7 {
+		t.Errorf("expected service account 7 in context, got %d", id)
+	}
+	if !HasScope(ctx, ScopePRWrite) || HasScope(ctx, ScopeStatsRead) {
+		t.Error("expected only pr:write scope in context")
+	}
+
+	// Без заголовка запрос проходит без изменений
+	ctx = nil
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/teams", nil))
+	if _, ok := GetScopes(ctx); ok {
+		t.Error("expected no scopes without API key")
+	}
+
+	// Просроченный ключ
+	validator.principal, validator.err = nil, ErrExpiredAPIKey
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "expired") {
+		t.Errorf("expected 401 for expired key, got %d: %s", w.Code, w.Body.String())
+	}
+}
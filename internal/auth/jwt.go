@@ -34,12 +34,17 @@ type Claims struct {
 	Email  string `json:"email"`
 	Role   string `json:"role"`
 	TeamID int64  `json:"team_id,omitempty"`
+	// IssuedAtMs время выпуска в миллисекундах: iat хранит только секунды,
+	// а отзыв всех токенов пользователя сравнивается точнее
+	IssuedAtMs int64 `json:"iat_ms,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // JWTAuth управляет JWT аутентификацией
 type JWTAuth struct {
 	secretKey       []byte
+	keys            *KeyRing
+	revocations     *RevocationStore
 	tokenExpiration time.Duration
 	logger          *logger.Logger
 }
@@ -53,24 +58,67 @@ func NewJWTAuth(secretKey string, tokenExpiration time.Duration, log *logger.Log
 	}
 }
 
+// NewJWTAuthWithKeyRing создает JWT auth с асимметричной подписью (RS256/EdDSA).
+// Проверяющим сервисам достаточно публичных ключей из JWKS.
+func NewJWTAuthWithKeyRing(keys *KeyRing, tokenExpiration time.Duration, log *logger.Logger) *JWTAuth {
+	return &JWTAuth{
+		keys:            keys,
+		tokenExpiration: tokenExpiration,
+		logger:          log,
+	}
+}
+
+// KeyRing возвращает набор ключей подписи или nil при HS256
+func (a *JWTAuth) KeyRing() *KeyRing {
+	return a.keys
+}
+
+// SetRevocationStore включает проверку отозванных токенов
+func (a *JWTAuth) SetRevocationStore(store *RevocationStore) {
+	a.revocations = store
+}
+
+// Revocations возвращает список отзыва токенов или nil, если он не подключен
+func (a *JWTAuth) Revocations() *RevocationStore {
+	return a.revocations
+}
+
 // GenerateToken генерирует новый JWT token
 func (a *JWTAuth) GenerateToken(userID int64, email, role string, teamID int64) (string, error) {
+	// jti позволяет отозвать конкретный токен до истечения срока
+	tokenID, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+
 	now := time.Now()
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
-		TeamID: teamID,
+		UserID:     userID,
+		Email:      email,
+		Role:       role,
+		TeamID:     teamID,
+		IssuedAtMs: now.UnixMilli(),
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(a.tokenExpiration)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "pr-reviewer",
+			ID:        tokenID,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(a.secretKey)
+	var tokenString string
+	if a.keys != nil {
+		key := a.keys.Active()
+		if key == nil {
+			return "", ErrUnknownSigningKey
+		}
+		token := jwt.NewWithClaims(key.signingMethod(), claims)
+		token.Header["kid"] = key.ID
+		tokenString, err = token.SignedString(key.PrivateKey)
+	} else {
+		tokenString, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.secretKey)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -78,15 +126,14 @@ func (a *JWTAuth) GenerateToken(userID int64, email, role string, teamID int64)
 	return tokenString, nil
 }
 
+// TokenExpiration возвращает время жизни access токена
+func (a *JWTAuth) TokenExpiration() time.Duration {
+	return a.tokenExpiration
+}
+
 // ValidateToken валидирует JWT token
 func (a *JWTAuth) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Проверяем алгоритм подписи
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return a.secretKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, a.verificationKey)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -102,6 +149,31 @@ func (a *JWTAuth) ValidateToken(tokenString string) (*Claims, error) {
 	return nil, ErrInvalidToken
 }
 
+// verificationKey выбирает ключ проверки подписи: при асимметричной подписи
+// по kid из заголовка токена, алгоритм токена должен совпадать с алгоритмом ключа
+func (a *JWTAuth) verificationKey(token *jwt.Token) (interface{}, error) {
+	if a.keys == nil {
+		// Проверяем алгоритм подписи
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return a.secretKey, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, ErrUnknownSigningKey
+	}
+	key, err := a.keys.Key(kid)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.PrivateKey.Public(), nil
+}
+
 // Middleware JWT authentication middleware
 func (a *JWTAuth) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -121,8 +193,8 @@ func (a *JWTAuth) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Валидируем токен
-		claims, err := a.ValidateToken(parts[1])
+		// Валидируем токен и проверяем, что он не отозван
+		claims, err := a.authenticate(r.Context(), parts[1])
 		if err != nil {
 			a.logger.Warnw("Token validation failed", "error", err, "path", r.URL.Path)
 			status := http.StatusUnauthorized
@@ -152,7 +224,7 @@ func (a *JWTAuth) OptionalMiddleware(next http.Handler) http.Handler {
 		if authHeader != "" {
 			parts := strings.Split(authHeader, " ")
 			if len(parts) == 2 && parts[0] == "Bearer" {
-				if claims, err := a.ValidateToken(parts[1]); err == nil {
+				if claims, err := a.authenticate(r.Context(), parts[1]); err == nil {
 					ctx := context.WithValue(r.Context(), contextKeyUserID, claims.UserID)
 					ctx = context.WithValue(ctx, contextKeyEmail, claims.Email)
 					ctx = context.WithValue(ctx, contextKeyRole, claims.Role)
@@ -166,6 +238,67 @@ func (a *JWTAuth) OptionalMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// WebSocketProtocol подпротокол WebSocket, следом за которым браузер передает
+// access токен: new WebSocket(url, ["bearer", token]). Браузер не позволяет
+// задать Authorization при подключении, а токен в URL попадает в логи прокси.
+const WebSocketProtocol = "bearer"
+
+// AuthenticateRequest валидирует токен из заголовка Authorization или, если
+// заголовка нет, из Sec-WebSocket-Protocol (см. WebSocketProtocol)
+func (a *JWTAuth) AuthenticateRequest(r *http.Request) (*Claims, error) {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		parts := strings.Split(authHeader, " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			return nil, ErrInvalidToken
+		}
+		return a.authenticate(r.Context(), parts[1])
+	}
+
+	if token := webSocketToken(r); token != "" {
+		return a.authenticate(r.Context(), token)
+	}
+
+	return nil, ErrMissingToken
+}
+
+// webSocketToken возвращает токен, переданный подпротоколом после "bearer"
+func webSocketToken(r *http.Request) string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			protocols = append(protocols, strings.TrimSpace(protocol))
+		}
+	}
+	for i := 0; i+1 < len(protocols); i++ {
+		if protocols[i] == WebSocketProtocol {
+			return protocols[i+1]
+		}
+	}
+	return ""
+}
+
+// authenticate валидирует токен и проверяет список отзыва. Если список
+// недоступен (ошибка Redis), токен принимается: отказ кеша не должен
+// блокировать всех пользователей.
+func (a *JWTAuth) authenticate(ctx context.Context, tokenString string) (*Claims, error) {
+	claims, err := a.ValidateToken(tokenString)
+	if err != nil || a.revocations == nil {
+		return claims, err
+	}
+
+	revoked, err := a.revocations.IsRevoked(ctx, claims)
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Warnw("Failed to check token revocation", "error", err, "user_id", claims.UserID)
+		}
+		return claims, nil
+	}
+	if revoked {
+		return nil, ErrRevokedToken
+	}
+	return claims, nil
+}
+
 // RequireRole middleware для проверки роли
 func (a *JWTAuth) RequireRole(roles ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
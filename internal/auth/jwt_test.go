@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -90,3 +91,47 @@ func TestJWTAuth_Structure(t *testing.T) {
 		t.Error("expected 1 hour expiration")
 	}
 }
+
+func TestJWTAuth_AuthenticateRequest(t *testing.T) {
+	a := NewJWTAuth("test-secret", time.Hour, nil)
+	token, err := a.GenerateToken(7, "alice@example.com", "user", 1)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		header    string
+		protocols string
+		query     string
+		wantErr   error
+	}{
+		{"bearer header", "Bearer " + token, "", "", nil},
+		{"websocket protocol", "", "bearer, " + token, "", nil},
+		{"invalid header format", "Token " + token, "", "", ErrInvalidToken},
+		{"invalid token", "", "bearer, garbage", "", ErrInvalidToken},
+		{"access_token query is not accepted", "", "", "?access_token=" + token, ErrMissingToken},
+		{"protocol without token", "", "bearer", "", ErrMissingToken},
+		{"missing token", "", "", "", ErrMissingToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/events/ws"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.protocols != "" {
+				req.Header.Set("Sec-WebSocket-Protocol", tt.protocols)
+			}
+
+			claims, err := a.AuthenticateRequest(req)
+			if err != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && claims.UserID != 7 {
+				t.Errorf("expected user 7, got %d", claims.UserID)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// encryptedKeyPrefix префикс зашифрованного приватного ключа в БД;
+// без него значение считается открытым PEM, сохраненным до шифрования
+const encryptedKeyPrefix = "enc:v1:"
+
+var (
+	ErrInvalidKEK       = errors.New("key encryption key must be 32 bytes encoded in base64")
+	ErrKeyNotEncrypted  = errors.New("signing key is not encrypted")
+	ErrKeyDecryptFailed = errors.New("failed to decrypt signing key")
+)
+
+// KeyEncryptor шифрует приватные ключи подписи JWT ключом шифрования (KEK)
+// перед сохранением в БД: AES-256-GCM, kid ключа входит в associated data,
+// поэтому зашифрованный ключ нельзя подставить в строку другого kid.
+type KeyEncryptor struct {
+	aead cipher.AEAD
+}
+
+// NewKeyEncryptor создает шифратор из KEK в base64 (32 байта)
+func NewKeyEncryptor(encodedKEK string) (*KeyEncryptor, error) {
+	kek, err := base64.StdEncoding.DecodeString(encodedKEK)
+	if err != nil || len(kek) != 32 {
+		return nil, ErrInvalidKEK
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return &KeyEncryptor{aead: aead}, nil
+}
+
+// Encrypt шифрует приватный ключ kid
+func (e *KeyEncryptor) Encrypt(kid string, privateKey []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := e.aead.Seal(nonce, nonce, privateKey, []byte(kid))
+	return []byte(encryptedKeyPrefix + base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+// Decrypt расшифровывает приватный ключ kid. Для ключа, сохраненного до
+// шифрования, возвращает ErrKeyNotEncrypted.
+func (e *KeyEncryptor) Decrypt(kid string, stored []byte) ([]byte, error) {
+	if !IsEncryptedKey(stored) {
+		return nil, ErrKeyNotEncrypted
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(string(stored[len(encryptedKeyPrefix):]))
+	if err != nil || len(sealed) < e.aead.NonceSize() {
+		return nil, ErrKeyDecryptFailed
+	}
+	nonce, ciphertext := sealed[:e.aead.NonceSize()], sealed[e.aead.NonceSize():]
+	privateKey, err := e.aead.Open(nil, nonce, ciphertext, []byte(kid))
+	if err != nil {
+		return nil, ErrKeyDecryptFailed
+	}
+	return privateKey, nil
+}
+
+// IsEncryptedKey проверяет, что ключ сохранен зашифрованным
+func IsEncryptedKey(stored []byte) bool {
+	return bytes.HasPrefix(stored, []byte(encryptedKeyPrefix))
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestKeyEncryptor_RoundTrip(t *testing.T) {
+	enc, err := NewKeyEncryptor(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))
+	if err != nil {
+		t.Fatalf("failed to create encryptor: %v", err)
+	}
+
+	key, err := GenerateSigningKey(AlgEdDSA)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pemKey, err := key.MarshalPrivateKey()
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	stored, err := enc.Encrypt(key.ID, pemKey)
+	if err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+	if !IsEncryptedKey(stored) || bytes.Contains(stored, []byte("PRIVATE KEY")) {
+		t.Fatalf("expected encrypted key, got %s", stored)
+	}
+
+	decrypted, err := enc.Decrypt(key.ID, stored)
+	if err != nil {
+		t.Fatalf("failed to decrypt key: %v", err)
+	}
+	if !bytes.Equal(decrypted, pemKey) {
+		t.Error("expected decrypted key to match the original")
+	}
+
+	// Зашифрованный ключ нельзя подставить под другой kid
+	if _, err := enc.Decrypt("other", stored); !errors.Is(err, ErrKeyDecryptFailed) {
+		t.Errorf("expected ErrKeyDecryptFailed for another kid, got %v", err)
+	}
+	if _, err := enc.Decrypt(key.ID, pemKey); !errors.Is(err, ErrKeyNotEncrypted) {
+		t.Errorf("expected ErrKeyNotEncrypted for plaintext key, got %v", err)
+	}
+}
+
+func TestNewKeyEncryptor_RequiresKEK(t *testing.T) {
+	for _, kek := range []string{"", "not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := NewKeyEncryptor(kek); !errors.Is(err, ErrInvalidKEK) {
+			t.Errorf("expected ErrInvalidKEK for %q, got %v", kek, err)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+// Алгоритмы подписи access токенов
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+const (
+	// rsaKeyBits размер генерируемых RSA ключей
+	rsaKeyBits = 2048
+	// keyReloadInterval минимальный интервал перезагрузки ключей при неизвестном kid
+	keyReloadInterval = 10 * time.Second
+)
+
+var ErrUnknownSigningKey = errors.New("unknown signing key")
+
+// SigningKey ключ подписи access токенов. Ключ активен, пока не задан RetiredAt;
+// выведенный из работы ключ еще принимается при проверке в течение grace периода.
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+	CreatedAt  time.Time
+	RetiredAt  *time.Time
+}
+
+// GenerateSigningKey создает новый ключ подписи со случайным kid
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	var signer crypto.Signer
+	switch algorithm {
+	case AlgRS256:
+		key, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+		}
+		signer = key
+	case AlgEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate Ed25519 key: %w", err)
+		}
+		signer = key
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate key id: %w", err)
+	}
+
+	return &SigningKey{
+		ID:         hex.EncodeToString(id),
+		Algorithm:  algorithm,
+		PrivateKey: signer,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// ParsePrivateKey разбирает приватный ключ в формате PKCS#8 PEM
+func ParsePrivateKey(algorithm string, data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM private key")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if algorithm == AlgRS256 {
+			return k, nil
+		}
+	case ed25519.PrivateKey:
+		if algorithm == AlgEdDSA {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("private key does not match algorithm %s", algorithm)
+}
+
+// MarshalPrivateKey кодирует приватный ключ в PKCS#8 PEM для хранения
+func (k *SigningKey) MarshalPrivateKey() ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// signingMethod возвращает метод подписи golang-jwt для алгоритма ключа
+func (k *SigningKey) signingMethod() jwt.SigningMethod {
+	if k.Algorithm == AlgEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// JWK публичный ключ в формате JSON Web Key (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 (RFC 8037)
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKSet набор публичных ключей для /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK возвращает публичную часть ключа
+func (k *SigningKey) JWK() JWK {
+	jwk := JWK{KeyID: k.ID, Use: "sig", Algorithm: k.Algorithm}
+	switch pub := k.PrivateKey.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+	return jwk
+}
+
+// KeyStore хранилище ключей подписи, общее для всех реплик сервиса
+type KeyStore interface {
+	LoadSigningKeys() ([]*SigningKey, error)
+	SaveSigningKey(key *SigningKey) error
+	// RetireOlderSigningKeys выводит из работы все ключи, кроме самого нового
+	RetireOlderSigningKeys(at time.Time) error
+	// DeleteSigningKeysRetiredBefore удаляет ключи, выведенные из работы до before
+	DeleteSigningKeysRetiredBefore(before time.Time) error
+}
+
+// KeyRingConfig настройки набора ключей подписи
+type KeyRingConfig struct {
+	Algorithm string
+	// RotationInterval как часто выпускается новый ключ (0 - без ротации)
+	RotationInterval time.Duration
+	// GracePeriod сколько принимаются токены выведенного из работы ключа.
+	// Должен быть не меньше времени жизни access токена.
+	GracePeriod time.Duration
+}
+
+// KeyRing набор ключей подписи: самый новый ключ подписывает токены,
+// выведенные из работы ключи принимаются до конца grace периода.
+// Без KeyStore ключи живут только в памяти процесса.
+type KeyRing struct {
+	config KeyRingConfig
+	store  KeyStore
+	logger *logger.Logger
+
+	mu         sync.RWMutex
+	keys       []*SigningKey // от новых к старым
+	lastReload time.Time
+}
+
+// NewKeyRing загружает ключи из хранилища и выпускает первый ключ, если их нет
+func NewKeyRing(cfg KeyRingConfig, store KeyStore, log *logger.Logger) (*KeyRing, error) {
+	if cfg.Algorithm != AlgRS256 && cfg.Algorithm != AlgEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", cfg.Algorithm)
+	}
+
+	r := &KeyRing{config: cfg, store: store, logger: log}
+	if err := r.Refresh(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Active возвращает ключ, которым подписываются новые токены
+func (r *KeyRing) Active() *SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.keys) == 0 {
+		return nil
+	}
+	return r.keys[0]
+}
+
+// Key возвращает ключ по kid, если он активен или еще в grace периоде.
+// Неизвестный kid мог быть выпущен другой репликой, поэтому ключи перечитываются
+// из хранилища (не чаще keyReloadInterval).
+func (r *KeyRing) Key(kid string) (*SigningKey, error) {
+	if key := r.lookup(kid); key != nil {
+		return key, nil
+	}
+
+	if r.store != nil {
+		r.mu.RLock()
+		stale := time.Since(r.lastReload) > keyReloadInterval
+		r.mu.RUnlock()
+		if stale {
+			if err := r.reload(); err != nil {
+				r.logf("Failed to reload signing keys", err)
+			} else if key := r.lookup(kid); key != nil {
+				return key, nil
+			}
+		}
+	}
+
+	return nil, ErrUnknownSigningKey
+}
+
+func (r *KeyRing) lookup(kid string) *SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.ID == kid && r.accepted(key, time.Now()) {
+			return key
+		}
+	}
+	return nil
+}
+
+// accepted проверяет, что ключ активен или выведен из работы не раньше grace периода
+func (r *KeyRing) accepted(key *SigningKey, now time.Time) bool {
+	return key.RetiredAt == nil || now.Before(key.RetiredAt.Add(r.config.GracePeriod))
+}
+
+// JWKS возвращает публичные ключи, которые принимаются при проверке токенов
+func (r *KeyRing) JWKS() JWKSet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	set := JWKSet{Keys: make([]JWK, 0, len(r.keys))}
+	for _, key := range r.keys {
+		if r.accepted(key, now) {
+			set.Keys = append(set.Keys, key.JWK())
+		}
+	}
+	return set
+}
+
+// Rotate выпускает новый ключ подписи и выводит из работы предыдущие
+func (r *KeyRing) Rotate() error {
+	key, err := GenerateSigningKey(r.config.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	if r.store == nil {
+		r.mu.Lock()
+		now := time.Now()
+		for _, old := range r.keys {
+			if old.RetiredAt == nil {
+				old.RetiredAt = &now
+			}
+		}
+		r.keys = append([]*SigningKey{key}, r.keys...)
+		r.mu.Unlock()
+	} else {
+		if err := r.store.SaveSigningKey(key); err != nil {
+			return err
+		}
+		// Если другая реплика одновременно выпустила ключ, активным останется
+		// самый новый; оба ключа принимаются при проверке
+		if err := r.store.RetireOlderSigningKeys(time.Now()); err != nil {
+			return err
+		}
+		if err := r.reload(); err != nil {
+			return err
+		}
+	}
+
+	if r.logger != nil {
+		r.logger.Infow("JWT signing key rotated", "kid", key.ID, "algorithm", key.Algorithm)
+	}
+	return nil
+}
+
+// Refresh перечитывает ключи, удаляет ключи с истекшим grace периодом
+// и выпускает новый ключ, если активного нет или пора ротировать
+func (r *KeyRing) Refresh() error {
+	if r.store != nil {
+		if err := r.store.DeleteSigningKeysRetiredBefore(time.Now().Add(-r.config.GracePeriod)); err != nil {
+			return err
+		}
+		if err := r.reload(); err != nil {
+			return err
+		}
+	} else {
+		r.prune()
+	}
+
+	r.mu.RLock()
+	// После смены алгоритма ключи прежнего алгоритма принимаются до конца
+	// grace периода, но новые токены подписываются ключом нового алгоритма
+	rotate := len(r.keys) == 0 || r.keys[0].RetiredAt != nil || r.keys[0].Algorithm != r.config.Algorithm ||
+		(r.config.RotationInterval > 0 && time.Since(r.keys[0].CreatedAt) >= r.config.RotationInterval)
+	r.mu.RUnlock()
+
+	if rotate {
+		return r.Rotate()
+	}
+	return nil
+}
+
+// Run периодически проверяет ключи: подхватывает ключи других реплик и ротирует по расписанию
+func (r *KeyRing) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Refresh(); err != nil {
+				r.logf("Failed to refresh signing keys", err)
+			}
+		}
+	}
+}
+
+// reload загружает ключи из хранилища
+func (r *KeyRing) reload() error {
+	keys, err := r.store.LoadSigningKeys()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	accepted := make([]*SigningKey, 0, len(keys))
+	for _, key := range keys {
+		if r.accepted(key, now) {
+			accepted = append(accepted, key)
+		}
+	}
+	sortKeys(accepted)
+
+	r.mu.Lock()
+	r.keys = accepted
+	r.lastReload = now
+	r.mu.Unlock()
+	return nil
+}
+
+// prune удаляет из памяти ключи с истекшим grace периодом
+func (r *KeyRing) prune() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	keys := r.keys[:0]
+	for _, key := range r.keys {
+		if r.accepted(key, now) {
+			keys = append(keys, key)
+		}
+	}
+	r.keys = keys
+}
+
+func (r *KeyRing) logf(msg string, err error) {
+	if r.logger != nil {
+		r.logger.Errorw(msg, "error", err)
+	}
+}
+
+// sortKeys упорядочивает ключи от новых к старым
+func sortKeys(keys []*SigningKey) {
+	sort.SliceStable(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID > keys[j].ID
+		}
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+}
@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// memKeyStore общее хранилище ключей для нескольких реплик в тестах
+type memKeyStore struct {
+	mu   sync.Mutex
+	keys []*SigningKey
+}
+
+func (s *memKeyStore) LoadSigningKeys() ([]*SigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]*SigningKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		copied := *k
+		keys = append(keys, &copied)
+	}
+	return keys, nil
+}
+
+func (s *memKeyStore) SaveSigningKey(key *SigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *key
+	s.keys = append(s.keys, &copied)
+	return nil
+}
+
+func (s *memKeyStore) RetireOlderSigningKeys(at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sortKeys(s.keys)
+	for _, k := range s.keys[1:] {
+		if k.RetiredAt == nil {
+			k.RetiredAt = &at
+		}
+	}
+	return nil
+}
+
+func (s *memKeyStore) DeleteSigningKeysRetiredBefore(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := s.keys[:0]
+	for _, k := range s.keys {
+		if k.RetiredAt == nil || !k.RetiredAt.Before(before) {
+			keys = append(keys, k)
+		}
+	}
+	s.keys = keys
+	return nil
+}
+
+func newTestKeyRing(t *testing.T, algorithm string, store KeyStore) *KeyRing {
+	t.Helper()
+
+	keys, err := NewKeyRing(KeyRingConfig{Algorithm: algorithm, GracePeriod: time.Hour}, store, nil)
+	if err != nil {
+		t.Fatalf("failed to create key ring: %v", err)
+	}
+	return keys
+}
+
+func TestJWTAuth_AsymmetricSigning(t *testing.T) {
+	for _, alg := range []string{AlgRS256, AlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			keys := newTestKeyRing(t, alg, nil)
+			a := NewJWTAuthWithKeyRing(keys, time.Hour, nil)
+
+			token, err := a.GenerateToken(7, "alice@example.com", RoleMember, 1)
+			if err != nil {
+				t.Fatalf("failed to generate token: %v", err)
+			}
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+			if err != nil {
+				t.Fatalf("failed to parse token: %v", err)
+			}
+			if parsed.Header["alg"] != alg || parsed.Header["kid"] != keys.Active().ID {
+				t.Errorf("unexpected header: %v", parsed.Header)
+			}
+
+			claims, err := a.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("failed to validate token: %v", err)
+			}
+			if claims.UserID != 7 {
+				t.Errorf("expected user 7, got %d", claims.UserID)
+			}
+		})
+	}
+}
+
+func TestJWTAuth_RotationGracePeriod(t *testing.T) {
+	keys := newTestKeyRing(t, AlgEdDSA, nil)
+	a := NewJWTAuthWithKeyRing(keys, time.Hour, nil)
+
+	oldKey := keys.Active()
+	oldToken, _ := a.GenerateToken(1, "", RoleMember, 0)
+
+	if err := keys.Rotate(); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	if keys.Active().ID == oldKey.ID {
+		t.Fatal("expected new active key after rotation")
+	}
+	if oldKey.RetiredAt == nil {
+		t.Fatal("expected previous key to be retired")
+	}
+
+	// Токен выведенного ключа принимается в течение grace периода
+	if _, err := a.ValidateToken(oldToken); err != nil {
+		t.Errorf("expected token of retired key to be accepted, got %v", err)
+	}
+	if got := len(keys.JWKS().Keys); got != 2 {
+		t.Errorf("expected 2 keys in JWKS during grace period, got %d", got)
+	}
+
+	// После grace периода ключ не принимается и не публикуется
+	expired := time.Now().Add(-2 * time.Hour)
+	oldKey.RetiredAt = &expired
+	if _, err := a.ValidateToken(oldToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected token of expired key to be rejected, got %v", err)
+	}
+	if err := keys.Refresh(); err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+	if got := len(keys.JWKS().Keys); got != 1 {
+		t.Errorf("expected 1 key in JWKS after grace period, got %d", got)
+	}
+}
+
+func TestJWTAuth_RejectsForgedTokens(t *testing.T) {
+	keys := newTestKeyRing(t, AlgRS256, nil)
+	a := NewJWTAuthWithKeyRing(keys, time.Hour, nil)
+	claims := &Claims{UserID: 1, Role: RoleAdmin, RegisteredClaims: jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}}
+
+	// HS256 с публичным ключом в качестве секрета (algorithm confusion)
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	hmacToken.Header["kid"] = keys.Active().ID
+	forged, _ := hmacToken.SignedString([]byte(keys.Active().JWK().N))
+
+	// Подпись ключом, которого нет в наборе
+	foreign, _ := GenerateSigningKey(AlgRS256)
+	foreignToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	foreignToken.Header["kid"] = foreign.ID
+	unknown, _ := foreignToken.SignedString(foreign.PrivateKey)
+
+	// Токен без kid
+	noKid, _ := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(keys.Active().PrivateKey)
+
+	// HS256 токен прежней конфигурации
+	legacy, _ := NewJWTAuth("test-secret", time.Hour, nil).GenerateToken(1, "", RoleAdmin, 0)
+
+	for name, token := range map[string]string{
+		"algorithm confusion": forged,
+		"unknown key":         unknown,
+		"missing kid":         noKid,
+		"legacy HS256":        legacy,
+	} {
+		if _, err := a.ValidateToken(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected invalid token, got %v", name, err)
+		}
+	}
+}
+
+func TestKeyRing_SharedStore(t *testing.T) {
+	store := &memKeyStore{}
+	replicaA := newTestKeyRing(t, AlgRS256, store)
+	replicaB := newTestKeyRing(t, AlgRS256, store)
+
+	if replicaA.Active().ID != replicaB.Active().ID {
+		t.Fatal("expected replicas to share the active key")
+	}
+
+	// Реплика A ротирует ключ, реплика B подхватывает его по неизвестному kid
+	if err := replicaA.Rotate(); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	replicaB.lastReload = time.Time{}
+
+	token, _ := NewJWTAuthWithKeyRing(replicaA, time.Hour, nil).GenerateToken(3, "", RoleMember, 0)
+	claims, err := NewJWTAuthWithKeyRing(replicaB, time.Hour, nil).ValidateToken(token)
+	if err != nil {
+		t.Fatalf("expected replica B to accept token signed after rotation, got %v", err)
+	}
+	if claims.UserID != 3 || replicaB.Active().ID != replicaA.Active().ID {
+		t.Errorf("expected replica B to switch to the new key")
+	}
+
+	// Смена алгоритма выпускает новый ключ, старый принимается до конца grace периода
+	replicaC := newTestKeyRing(t, AlgEdDSA, store)
+	if replicaC.Active().Algorithm != AlgEdDSA {
+		t.Errorf("expected EdDSA active key, got %s", replicaC.Active().Algorithm)
+	}
+	if _, err := NewJWTAuthWithKeyRing(replicaC, time.Hour, nil).ValidateToken(token); err != nil {
+		t.Errorf("expected RS256 token to be accepted after algorithm change, got %v", err)
+	}
+}
+
+func TestSigningKey_MarshalAndJWK(t *testing.T) {
+	for _, alg := range []string{AlgRS256, AlgEdDSA} {
+		key, err := GenerateSigningKey(alg)
+		if err != nil {
+			t.Fatalf("failed to generate %s key: %v", alg, err)
+		}
+
+		data, err := key.MarshalPrivateKey()
+		if err != nil {
+			t.Fatalf("failed to marshal %s key: %v", alg, err)
+		}
+		parsed, err := ParsePrivateKey(alg, data)
+		if err != nil {
+			t.Fatalf("failed to parse %s key: %v", alg, err)
+		}
+		if !key.PrivateKey.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(parsed.Public()) {
+			t.Errorf("%s: parsed key does not match", alg)
+		}
+
+		raw, _ := json.Marshal(key.JWK())
+		var jwk map[string]string
+		_ = json.Unmarshal(raw, &jwk)
+		if jwk["kid"] != key.ID || jwk["alg"] != alg || jwk["use"] != "sig" {
+			t.Errorf("%s: unexpected JWK %v", alg, jwk)
+		}
+	}
+
+	rsaKey, _ := GenerateSigningKey(AlgRS256)
+	data, _ := rsaKey.MarshalPrivateKey()
+	if _, err := ParsePrivateKey(AlgEdDSA, data); err == nil {
+		t.Error("expected error for key that does not match algorithm")
+	}
+}
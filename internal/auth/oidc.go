@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/user/pr-reviewer/internal/logger"
+	"golang.org/x/oauth2"
+)
+
+// Значения claims по умолчанию
+const (
+	DefaultOIDCRoleClaim   = "roles"
+	DefaultOIDCGroupsClaim = "groups"
+)
+
+var ErrOIDCNonceMismatch = errors.New("id token nonce mismatch")
+
+// rolePrecedence порядок выбора роли, если IdP вернул несколько: выбирается самая сильная
+var rolePrecedence = []string{RoleAdmin, RoleTeamLead, RoleMember, RoleReadonly}
+
+// OIDCConfig настройки OpenID Connect провайдера
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes дополнительные scopes к openid (по умолчанию profile и email)
+	Scopes []string
+
+	// RoleClaim claim ID токена со списком ролей IdP
+	RoleClaim string
+	// RoleMapping роль IdP -> роль сервиса
+	RoleMapping map[string]string
+	// DefaultRole роль, если ни одна роль IdP не сопоставлена
+	DefaultRole string
+
+	// GroupsClaim claim ID токена со списком групп
+	GroupsClaim string
+	// GroupTeams группа IdP -> название команды
+	GroupTeams map[string]string
+}
+
+// OIDCIdentity пользователь, подтвержденный IdP, с сопоставленными ролью и командой
+type OIDCIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	Name          string
+	Role          string
+	// ManagedRole роль управляется IdP (задан RoleMapping) и перезаписывает локальную
+	ManagedRole bool
+	// Team название команды по группам IdP, пустое если группа не сопоставлена
+	Team string
+}
+
+// OIDCFlow параметры одного входа: state против CSRF, nonce против
+// повторного использования ID токена и PKCE verifier
+type OIDCFlow struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// NewOIDCFlow генерирует параметры нового входа
+func NewOIDCFlow() (*OIDCFlow, error) {
+	state, err := randomString(24)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomString(24)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCFlow{
+		State:    state,
+		Nonce:    nonce,
+		Verifier: oauth2.GenerateVerifier(),
+	}, nil
+}
+
+// OIDCProvider реализует authorization code flow с PKCE
+type OIDCProvider struct {
+	config   OIDCConfig
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	logger   *logger.Logger
+}
+
+// NewOIDCProvider загружает discovery документ IdP и создает провайдер.
+// ctx используется и для последующей загрузки JWKS, поэтому должен жить
+// все время работы сервиса.
+func NewOIDCProvider(ctx context.Context, cfg OIDCConfig, log *logger.Logger) (*OIDCProvider, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+	}
+
+	if cfg.RoleClaim == "" {
+		cfg.RoleClaim = DefaultOIDCRoleClaim
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = DefaultOIDCGroupsClaim
+	}
+	if cfg.DefaultRole == "" {
+		cfg.DefaultRole = RoleMember
+	}
+	if !ValidRole(cfg.DefaultRole) {
+		return nil, fmt.Errorf("invalid OIDC default role: %s", cfg.DefaultRole)
+	}
+	for idpRole, role := range cfg.RoleMapping {
+		if !ValidRole(role) {
+			return nil, fmt.Errorf("invalid OIDC role mapping %s=%s", idpRole, role)
+		}
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"profile", "email"}
+	}
+
+	return &OIDCProvider{
+		config: cfg,
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       append([]string{oidc.ScopeOpenID}, scopes...),
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		logger:   log,
+	}, nil
+}
+
+// AuthCodeURL возвращает адрес страницы входа IdP
+func (p *OIDCProvider) AuthCodeURL(flow *OIDCFlow) string {
+	return p.oauth.AuthCodeURL(flow.State,
+		oidc.Nonce(flow.Nonce),
+		oauth2.S256ChallengeOption(flow.Verifier),
+	)
+}
+
+// Exchange обменивает код авторизации на токены, проверяет ID токен
+// (подпись по JWKS, issuer, audience, срок действия, nonce) и сопоставляет
+// роли и группы IdP
+func (p *OIDCProvider) Exchange(ctx context.Context, code string, flow *OIDCFlow) (*OIDCIdentity, error) {
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify id token: %w", err)
+	}
+	if idToken.Nonce != flow.Nonce {
+		return nil, ErrOIDCNonceMismatch
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to parse id token claims: %w", err)
+	}
+
+	return p.identity(idToken.Issuer, idToken.Subject, claims), nil
+}
+
+// identity собирает пользователя из claims ID токена
+func (p *OIDCProvider) identity(issuer, subject string, claims map[string]interface{}) *OIDCIdentity {
+	identity := &OIDCIdentity{
+		Issuer:  issuer,
+		Subject: subject,
+		Role:    p.config.DefaultRole,
+		// Роль управляется IdP, только если настроено сопоставление ролей
+		ManagedRole: len(p.config.RoleMapping) > 0,
+	}
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	identity.Name, _ = claims["name"].(string)
+	identity.Username, _ = claims["preferred_username"].(string)
+
+	if identity.Username == "" {
+		if local, _, ok := strings.Cut(identity.Email, "@"); ok && local != "" {
+			identity.Username = local
+		} else {
+			identity.Username = subject
+		}
+	}
+	if identity.Name == "" {
+		identity.Name = identity.Username
+	}
+
+	mapped := make(map[string]bool)
+	for _, idpRole := range claimStrings(claims[p.config.RoleClaim]) {
+		if role, ok := p.config.RoleMapping[idpRole]; ok {
+			mapped[role] = true
+		}
+	}
+	for _, role := range rolePrecedence {
+		if mapped[role] {
+			identity.Role = role
+			break
+		}
+	}
+
+	for _, group := range claimStrings(claims[p.config.GroupsClaim]) {
+		if team, ok := p.config.GroupTeams[group]; ok {
+			identity.Team = team
+			break
+		}
+	}
+
+	return identity
+}
+
+// claimStrings возвращает значение claim (строку или массив строк) как список
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// ParseOIDCMapping разбирает сопоставление вида "idp-admins=admin,leads=team_lead"
+func ParseOIDCMapping(s string) map[string]string {
+	mapping := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if ok && key != "" && value != "" {
+			mapping[key] = value
+		}
+	}
+	return mapping
+}
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random string: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,288 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+const (
+	stubClientID = "pr-reviewer"
+	stubKeyID    = "stub-key"
+)
+
+// stubIdP минимальный OpenID Connect провайдер: discovery, JWKS и token endpoint с PKCE
+type stubIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]stubAuthorization
+
+	// signKey ключ подписи ID токенов, по умолчанию key
+	signKey *rsa.PrivateKey
+	// audience claim aud, по умолчанию stubClientID
+	audience string
+}
+
+type stubAuthorization struct {
+	nonce     string
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	idp := &stubIdP{key: key, signKey: key, audience: stubClientID, codes: make(map[string]stubAuthorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/authorize",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": stubKeyID,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", idp.token)
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// authorize имитирует вход пользователя у IdP и возвращает код авторизации
+func (idp *stubIdP) authorize(t *testing.T, authURL string, claims jwt.MapClaims) (code, state string) {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("invalid auth URL: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("expected PKCE S256 challenge in auth URL, got %s", authURL)
+	}
+	if !strings.Contains(q.Get("scope"), "openid") {
+		t.Fatalf("expected openid scope, got %s", q.Get("scope"))
+	}
+
+	code = "code-" + q.Get("state")
+	idp.mu.Lock()
+	idp.codes[code] = stubAuthorization{nonce: q.Get("nonce"), challenge: q.Get("code_challenge"), claims: claims}
+	idp.mu.Unlock()
+	return code, q.Get("state")
+}
+
+func (idp *stubIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	idp.mu.Lock()
+	authz, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != authz.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   idp.server.URL,
+		"aud":   idp.audience,
+		"sub":   "user-42",
+		"nonce": authz.nonce,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range authz.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = stubKeyID
+	idToken, err := token.SignedString(idp.signKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "idp-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func newTestOIDCProvider(t *testing.T, idp *stubIdP) *OIDCProvider {
+	t.Helper()
+
+	log, _ := logger.New("error", "test")
+	provider, err := NewOIDCProvider(context.Background(), OIDCConfig{
+		IssuerURL:    idp.server.URL,
+		ClientID:     stubClientID,
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/auth/oidc/callback",
+		RoleMapping:  map[string]string{"idp-admins": RoleAdmin, "leads": RoleTeamLead},
+		GroupTeams:   map[string]string{"backend": "Backend"},
+	}, log)
+	if err != nil {
+		t.Fatalf("failed to create OIDC provider: %v", err)
+	}
+	return provider
+}
+
+func TestOIDCProvider_Exchange(t *testing.T) {
+	idp := newStubIdP(t)
+	provider := newTestOIDCProvider(t, idp)
+
+	flow, err := NewOIDCFlow()
+	if err != nil {
+		t.Fatalf("failed to create flow: %v", err)
+	}
+	code, state := idp.authorize(t, provider.AuthCodeURL(flow), jwt.MapClaims{
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"preferred_username": "alice",
+		"name":               "Alice",
+		"roles":              []string{"devs", "leads"},
+		"groups":             []string{"eng", "backend"},
+	})
+	if state != flow.State {
+		t.Fatalf("expected state %s in auth URL, got %s", flow.State, state)
+	}
+
+	identity, err := provider.Exchange(context.Background(), code, flow)
+	if err != nil {
+		t.Fatalf("failed to exchange code: %v", err)
+	}
+
+	if identity.Issuer != idp.server.URL || identity.Subject != "user-42" {
+		t.Errorf("unexpected identity %s/%s", identity.Issuer, identity.Subject)
+	}
+	if identity.Username != "alice" || identity.Email != "alice@example.com" || !identity.EmailVerified {
+		t.Errorf("unexpected profile: %+v", identity)
+	}
+	if identity.Role != RoleTeamLead || !identity.ManagedRole {
+		t.Errorf("expected managed role %s, got %s", RoleTeamLead, identity.Role)
+	}
+	if identity.Team != "Backend" {
+		t.Errorf("expected team Backend, got %q", identity.Team)
+	}
+}
+
+func TestOIDCProvider_ExchangeRejectsInvalidTokens(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		setup func(idp *stubIdP, flow *OIDCFlow)
+	}{
+		{"wrong PKCE verifier", func(_ *stubIdP, flow *OIDCFlow) { flow.Verifier = "wrong-verifier-wrong-verifier-wrong-verifier" }},
+		{"nonce mismatch", func(_ *stubIdP, flow *OIDCFlow) { flow.Nonce = "other" }},
+		{"unknown signing key", func(idp *stubIdP, _ *OIDCFlow) { idp.signKey = otherKey }},
+		{"wrong audience", func(idp *stubIdP, _ *OIDCFlow) { idp.audience = "another-client" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newStubIdP(t)
+			provider := newTestOIDCProvider(t, idp)
+
+			flow, _ := NewOIDCFlow()
+			code, _ := idp.authorize(t, provider.AuthCodeURL(flow), nil)
+			tt.setup(idp, flow)
+
+			if _, err := provider.Exchange(context.Background(), code, flow); err == nil {
+				t.Error("expected exchange to fail")
+			} else if tt.name == "nonce mismatch" && !errors.Is(err, ErrOIDCNonceMismatch) {
+				t.Errorf("expected nonce mismatch, got %v", err)
+			}
+		})
+	}
+}
+
+func TestOIDCProvider_Identity(t *testing.T) {
+	provider := &OIDCProvider{config: OIDCConfig{
+		RoleClaim:   DefaultOIDCRoleClaim,
+		RoleMapping: map[string]string{"idp-admins": RoleAdmin, "leads": RoleTeamLead},
+		DefaultRole: RoleReadonly,
+		GroupsClaim: DefaultOIDCGroupsClaim,
+		GroupTeams:  map[string]string{"backend": "Backend", "frontend": "Frontend"},
+	}}
+
+	tests := []struct {
+		name     string
+		claims   map[string]interface{}
+		username string
+		role     string
+		team     string
+	}{
+		{"defaults", map[string]interface{}{}, "sub-1", RoleReadonly, ""},
+		{"username from email", map[string]interface{}{"email": "bob@example.com"}, "bob", RoleReadonly, ""},
+		{"strongest role wins", map[string]interface{}{"roles": []interface{}{"leads", "idp-admins"}}, "sub-1", RoleAdmin, ""},
+		{"single role string", map[string]interface{}{"roles": "leads"}, "sub-1", RoleTeamLead, ""},
+		{"first mapped group", map[string]interface{}{"groups": []interface{}{"eng", "frontend", "backend"}}, "sub-1", RoleReadonly, "Frontend"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity := provider.identity("https://idp", "sub-1", tt.claims)
+			if identity.Username != tt.username || identity.Role != tt.role || identity.Team != tt.team {
+				t.Errorf("expected %s/%s/%q, got %s/%s/%q", tt.username, tt.role, tt.team,
+					identity.Username, identity.Role, identity.Team)
+			}
+		})
+	}
+}
+
+func TestParseOIDCMapping(t *testing.T) {
+	mapping := ParseOIDCMapping("idp-admins=admin, leads = team_lead,broken,=x")
+	if len(mapping) != 2 || mapping["idp-admins"] != RoleAdmin || mapping["leads"] != RoleTeamLead {
+		t.Errorf("unexpected mapping: %v", mapping)
+	}
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength минимальная длина пароля
+const MinPasswordLength = 8
+
+var ErrPasswordTooShort = fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+
+// Роли пользователей
+const (
+	RoleAdmin    = "admin"
+	RoleTeamLead = "team_lead"
+	RoleMember   = "member"
+	RoleReadonly = "readonly"
+)
+
+// ValidRole проверяет, что роль известна
+func ValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleTeamLead, RoleMember, RoleReadonly:
+		return true
+	}
+	return false
+}
+
+// dummyHash используется для сравнения, когда пользователь не найден,
+// чтобы время ответа не выдавало существование логина
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// HashPassword хеширует пароль bcrypt
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", ErrPasswordTooShort
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword сравнивает пароль с хешем. Пустой хеш (пароль не задан)
+// никогда не совпадает, но сравнение все равно выполняется.
+func CheckPassword(hash, password string) bool {
+	if hash == "" {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"testing"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hash == "correct horse" {
+		t.Fatal("expected password to be hashed")
+	}
+
+	if !CheckPassword(hash, "correct horse") {
+		t.Error("expected password to match")
+	}
+	if CheckPassword(hash, "wrong horse") {
+		t.Error("expected wrong password to be rejected")
+	}
+	if CheckPassword("", "correct horse") {
+		t.Error("expected user without password to be rejected")
+	}
+
+	if _, err := HashPassword("short"); err != ErrPasswordTooShort {
+		t.Errorf("expected ErrPasswordTooShort, got %v", err)
+	}
+}
+
+func TestValidRole(t *testing.T) {
+	for _, role := range []string{RoleAdmin, RoleTeamLead, RoleMember, RoleReadonly} {
+		if !ValidRole(role) {
+			t.Errorf("expected %s to be valid", role)
+		}
+	}
+	if ValidRole("superuser") {
+		t.Error("expected unknown role to be invalid")
+	}
+}
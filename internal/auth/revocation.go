@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/cache"
+)
+
+var ErrRevokedToken = errors.New("token revoked")
+
+// RevocationStore список отозванных access токенов в кеше (Redis). Записи
+// живут не дольше access токена: после истечения токен отклоняется и так.
+type RevocationStore struct {
+	cache    cache.Cache
+	tokenTTL time.Duration
+}
+
+// NewRevocationStore создает список отзыва; tokenTTL - время жизни access токена
+func NewRevocationStore(c cache.Cache, tokenTTL time.Duration) *RevocationStore {
+	return &RevocationStore{cache: c, tokenTTL: tokenTTL}
+}
+
+// RevokeToken отзывает токен по его ID (claim jti)
+func (s *RevocationStore) RevokeToken(ctx context.Context, tokenID string) error {
+	return s.cache.Set(ctx, revokedTokenKey(tokenID), true, s.tokenTTL)
+}
+
+// RevokeTokenUntil отзывает токен по jti до истечения его срока (logout)
+func (s *RevocationStore) RevokeTokenUntil(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.cache.Set(ctx, revokedTokenKey(tokenID), true, ttl)
+}
+
+// RevokeUser отзывает все токены пользователя, выпущенные раньше at.
+// Время хранится в миллисекундах: токен, выпущенный сразу после отзыва
+// (например, при повторном входе), остается действительным.
+func (s *RevocationStore) RevokeUser(ctx context.Context, userID int64, at time.Time) error {
+	return s.cache.Set(ctx, revokedUserKey(userID), at.UnixMilli(), s.tokenTTL)
+}
+
+// IsRevoked проверяет, отозван ли токен по jti или вместе со всеми токенами пользователя
+func (s *RevocationStore) IsRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	if claims.ID != "" {
+		revoked, err := s.cache.Exists(ctx, revokedTokenKey(claims.ID))
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	key := revokedUserKey(claims.UserID)
+	exists, err := s.cache.Exists(ctx, key)
+	if err != nil || !exists {
+		return false, err
+	}
+
+	var revokedAtMs int64
+	if err := s.cache.Get(ctx, key, &revokedAtMs); err != nil {
+		return false, err
+	}
+	if claims.IssuedAtMs > 0 {
+		return claims.IssuedAtMs < revokedAtMs, nil
+	}
+	// Токен без iat_ms (выпущен до его появления) сравнивается по iat с
+	// точностью до секунды: выпущенный в секунду отзыва тоже отозван
+	return claims.IssuedAt == nil || claims.IssuedAt.Unix() <= revokedAtMs/1000, nil
+}
+
+func revokedTokenKey(tokenID string) string {
+	return cache.Key("revoked", "jti", tokenID)
+}
+
+// revokedUserKey ключ времени отзыва всех токенов пользователя (в миллисекундах)
+func revokedUserKey(userID int64) string {
+	return cache.Key("revoked", "user_ms", strconv.FormatInt(userID, 10))
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/user/pr-reviewer/internal/cache"
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+// failingCache кеш, недоступный как Redis во время сбоя
+type failingCache struct {
+	cache.NoOpCache
+}
+
+func (*failingCache) Exists(context.Context, string) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func newRevocationTestAuth(t *testing.T, c cache.Cache) *JWTAuth {
+	t.Helper()
+
+	log, _ := logger.New("error", "test")
+	a := NewJWTAuth("test-secret", time.Hour, log)
+	a.SetRevocationStore(NewRevocationStore(c, time.Hour))
+	return a
+}
+
+func TestJWTAuth_GenerateTokenSetsUniqueID(t *testing.T) {
+	a := NewJWTAuth("test-secret", time.Hour, nil)
+
+	first, _ := a.GenerateToken(1, "", RoleMember, 0)
+	second, _ := a.GenerateToken(1, "", RoleMember, 0)
+
+	c1, err := a.ValidateToken(first)
+	if err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+	c2, _ := a.ValidateToken(second)
+	if c1.ID == "" || c1.ID == c2.ID {
+		t.Errorf("expected unique jti, got %q and %q", c1.ID, c2.ID)
+	}
+}
+
+func TestJWTAuth_RevokedTokenRejected(t *testing.T) {
+	a := newRevocationTestAuth(t, cache.NewMemoryCache())
+	ctx := context.Background()
+
+	revoked, _ := a.GenerateToken(1, "", RoleMember, 0)
+	other, _ := a.GenerateToken(1, "", RoleMember, 0)
+	claims, _ := a.ValidateToken(revoked)
+
+	if err := a.Revocations().RevokeToken(ctx, claims.ID); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+
+	protected := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"revoked token", revoked, http.StatusUnauthorized},
+		{"other token of the same user", other, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/auth/me", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+
+			protected.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, rec.Code)
+			}
+		})
+	}
+
+	// Опциональная аутентификация не заполняет контекст отозванным токеном
+	req := httptest.NewRequest("GET", "/teams", nil)
+	req.Header.Set("Authorization", "Bearer "+revoked)
+	a.OptionalMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := GetUserID(r.Context()); ok {
+			t.Error("expected revoked token to be ignored")
+		}
+	})).ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestJWTAuth_RevokeAllUserTokens(t *testing.T) {
+	a := newRevocationTestAuth(t, cache.NewMemoryCache())
+
+	alice, _ := a.GenerateToken(1, "", RoleMember, 0)
+	bob, _ := a.GenerateToken(2, "", RoleMember, 0)
+	time.Sleep(2 * time.Millisecond)
+
+	if err := a.Revocations().RevokeUser(context.Background(), 1, time.Now()); err != nil {
+		t.Fatalf("failed to revoke user: %v", err)
+	}
+
+	if _, err := a.authenticate(context.Background(), alice); !errors.Is(err, ErrRevokedToken) {
+		t.Errorf("expected alice's token to be revoked, got %v", err)
+	}
+	if _, err := a.authenticate(context.Background(), bob); err != nil {
+		t.Errorf("expected bob's token to stay valid, got %v", err)
+	}
+
+	// Токены, выпущенные после отзыва, действительны
+	later := &Claims{UserID: 1}
+	later.ID = "later"
+	later.IssuedAt = jwt.NewNumericDate(time.Now().Add(2 * time.Second))
+	if revoked, _ := a.Revocations().IsRevoked(context.Background(), later); revoked {
+		t.Error("expected token issued after revocation to be valid")
+	}
+}
+
+func TestJWTAuth_TokenIssuedRightAfterRevokeAllIsValid(t *testing.T) {
+	a := newRevocationTestAuth(t, cache.NewMemoryCache())
+
+	if err := a.Revocations().RevokeUser(context.Background(), 1, time.Now()); err != nil {
+		t.Fatalf("failed to revoke user: %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	// Токен повторного входа выпущен в ту же секунду, что и отзыв
+	token, _ := a.GenerateToken(1, "", RoleMember, 0)
+	if _, err := a.authenticate(context.Background(), token); err != nil {
+		t.Errorf("expected token issued after revocation to be valid, got %v", err)
+	}
+
+	// Токен без iat_ms сравнивается по секундам
+	legacy := &Claims{UserID: 1}
+	legacy.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Second))
+	if revoked, _ := a.Revocations().IsRevoked(context.Background(), legacy); !revoked {
+		t.Error("expected legacy token issued before revocation to be revoked")
+	}
+}
+
+func TestJWTAuth_RevocationCacheFailureAllowsToken(t *testing.T) {
+	a := newRevocationTestAuth(t, &failingCache{})
+
+	token, _ := a.GenerateToken(1, "", RoleMember, 0)
+	if _, err := a.authenticate(context.Background(), token); err != nil {
+		t.Errorf("expected token to be accepted when revocation list is unavailable, got %v", err)
+	}
+}
+
+func TestRevocationStore_RevokeTokenUntil(t *testing.T) {
+	a := newRevocationTestAuth(t, cache.NewMemoryCache())
+	ctx := context.Background()
+
+	token, _ := a.GenerateToken(1, "", RoleMember, 0)
+	claims, _ := a.ValidateToken(token)
+
+	// Logout отзывает access токен на оставшийся срок его жизни
+	if err := a.Revocations().RevokeTokenUntil(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	if _, err := a.authenticate(ctx, token); !errors.Is(err, ErrRevokedToken) {
+		t.Errorf("expected token to be revoked, got %v", err)
+	}
+
+	// Истекший токен в список отзыва не попадает
+	if err := a.Revocations().RevokeTokenUntil(ctx, "expired", time.Now().Add(-time.Minute)); err != nil {
+		t.Errorf("unexpected error for expired token: %v", err)
+	}
+	expired := &Claims{UserID: 2}
+	expired.ID = "expired"
+	if revoked, _ := a.Revocations().IsRevoked(ctx, expired); revoked {
+		t.Error("expected expired token not to be stored")
+	}
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+)
+
+// MemoryCache кеш в памяти процесса. Используется в тестах и при запуске
+// одной реплики без Redis; значения хранятся в JSON, как в RedisCache.
+type MemoryCache struct {
+	mu        sync.RWMutex
+	items     map[string]memoryItem
+	lastEvict time.Time
+}
+
+type memoryItem struct {
+	value     []byte
+	expiresAt time.Time // нулевое значение - без срока
+}
+
+// NewMemoryCache создает кеш в памяти
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{items: make(map[string]memoryItem)}
+}
+
+// Get получает значение из кеша
+func (c *MemoryCache) Get(_ context.Context, key string, dest interface{}) error {
+	c.mu.RLock()
+	item, ok := c.items[key]
+	c.mu.RUnlock()
+
+	if !ok || item.expired(time.Now()) {
+		return fmt.Errorf("cache miss: %s", key)
+	}
+	return json.Unmarshal(item.value, dest)
+}
+
+// Set сохраняет значение в кеш. ttl <= 0 - без срока.
+func (c *MemoryCache) Set(_ context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	item := memoryItem{value: data}
+	if ttl > 0 {
+		item.expiresAt = time.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evictExpired()
+	c.items[key] = item
+	return nil
+}
+
+// Delete удаляет значение из кеша
+func (c *MemoryCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	delete(c.items, key)
+	c.mu.Unlock()
+	return nil
+}
+
+// DeletePattern удаляет все ключи по glob паттерну (как SCAN MATCH в Redis)
+func (c *MemoryCache) DeletePattern(_ context.Context, pattern string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.items {
+		if ok, err := path.Match(pattern, key); err != nil {
+			return err
+		} else if ok {
+			delete(c.items, key)
+		}
+	}
+	return nil
+}
+
+// Exists проверяет существование ключа
+func (c *MemoryCache) Exists(_ context.Context, key string) (bool, error) {
+	c.mu.RLock()
+	item, ok := c.items[key]
+	c.mu.RUnlock()
+
+	return ok && !item.expired(time.Now()), nil
+}
+
+// Close ничего не делает
+func (c *MemoryCache) Close() error {
+	return nil
+}
+
+// evictExpired удаляет истекшие ключи не чаще раза в минуту. Вызывается под блокировкой.
+func (c *MemoryCache) evictExpired() {
+	now := time.Now()
+	if now.Sub(c.lastEvict) < time.Minute {
+		return
+	}
+	c.lastEvict = now
+
+	for key, item := range c.items {
+		if item.expired(now) {
+			delete(c.items, key)
+		}
+	}
+}
+
+func (i memoryItem) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && now.After(i.expiresAt)
+}
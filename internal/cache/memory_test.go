@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	c := NewMemoryCache()
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "user:1", map[string]int{"id": 1}, 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	var got map[string]int
+	if err := c.Get(ctx, "user:1", &got); err != nil || got["id"] != 1 {
+		t.Errorf("expected cached value, got %v (%v)", got, err)
+	}
+
+	if err := c.Get(ctx, "missing", &got); err == nil {
+		t.Error("expected cache miss")
+	}
+
+	_ = c.Set(ctx, "short", true, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if ok, _ := c.Exists(ctx, "short"); ok {
+		t.Error("expected expired key to be missing")
+	}
+
+	_ = c.Set(ctx, "user:2", 2, 0)
+	_ = c.Set(ctx, "team:1", 1, 0)
+	if err := c.DeletePattern(ctx, "user:*"); err != nil {
+		t.Fatalf("failed to delete pattern: %v", err)
+	}
+	if ok, _ := c.Exists(ctx, "user:2"); ok {
+		t.Error("expected user:2 to be deleted by pattern")
+	}
+	if ok, _ := c.Exists(ctx, "team:1"); !ok {
+		t.Error("expected team:1 to remain")
+	}
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ToggleCache переключает запросы между основным и запасным кешем по флагу,
+// который проверяется при каждом обращении (feature flag redis_cache).
+// Данные между кешами не переносятся: после переключения кеш пуст.
+type ToggleCache struct {
+	primary  Cache
+	fallback Cache
+	enabled  func() bool
+}
+
+// NewToggleCache создает переключаемый кеш; enabled == false - используется fallback
+func NewToggleCache(primary, fallback Cache, enabled func() bool) *ToggleCache {
+	return &ToggleCache{
+		primary:  primary,
+		fallback: fallback,
+		enabled:  enabled,
+	}
+}
+
+func (c *ToggleCache) current() Cache {
+	if c.enabled() {
+		return c.primary
+	}
+	return c.fallback
+}
+
+// Get получает значение из текущего кеша
+func (c *ToggleCache) Get(ctx context.Context, key string, dest interface{}) error {
+	return c.current().Get(ctx, key, dest)
+}
+
+// Set сохраняет значение в текущий кеш
+func (c *ToggleCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return c.current().Set(ctx, key, value, ttl)
+}
+
+// Delete удаляет ключ из текущего кеша
+func (c *ToggleCache) Delete(ctx context.Context, key string) error {
+	return c.current().Delete(ctx, key)
+}
+
+// DeletePattern удаляет ключи по шаблону из текущего кеша
+func (c *ToggleCache) DeletePattern(ctx context.Context, pattern string) error {
+	return c.current().DeletePattern(ctx, pattern)
+}
+
+// Exists проверяет наличие ключа в текущем кеше
+func (c *ToggleCache) Exists(ctx context.Context, key string) (bool, error) {
+	return c.current().Exists(ctx, key)
+}
+
+// Close закрывает оба кеша
+func (c *ToggleCache) Close() error {
+	return errors.Join(c.primary.Close(), c.fallback.Close())
+}
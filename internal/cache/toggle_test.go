@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestToggleCache(t *testing.T) {
+	primary := NewMemoryCache()
+	enabled := true
+	c := NewToggleCache(primary, NewNoOpCache(), func() bool { return enabled })
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "key", 1, 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	if ok, _ := primary.Exists(ctx, "key"); !ok {
+		t.Error("expected value to be stored in primary cache")
+	}
+
+	enabled = false
+	var got int
+	if err := c.Get(ctx, "key", &got); err == nil {
+		t.Error("expected cache miss while primary cache is disabled")
+	}
+	_ = c.Set(ctx, "other", 2, 0)
+	if ok, _ := primary.Exists(ctx, "other"); ok {
+		t.Error("expected disabled primary cache not to be written")
+	}
+
+	enabled = true
+	if err := c.Get(ctx, "key", &got); err != nil || got != 1 {
+		t.Errorf("expected primary cache value after re-enabling, got %d (%v)", got, err)
+	}
+}
@@ -9,6 +9,13 @@ import (
 	"github.com/user/pr-reviewer/internal/logger"
 )
 
+// Ошибки отказа без вызова функции: breaker открыт или в half-open
+// исчерпан лимит пробных запросов
+var (
+	ErrOpenState       = gobreaker.ErrOpenState
+	ErrTooManyRequests = gobreaker.ErrTooManyRequests
+)
+
 // CircuitBreaker обертка над gobreaker с логированием
 type CircuitBreaker struct {
 	cb     *gobreaker.CircuitBreaker
@@ -0,0 +1,252 @@
+package featureflags
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Атрибуты контекста для правил таргетинга
+const (
+	AttributeUser  = "user"
+	AttributeTeam  = "team"
+	AttributeRole  = "role"
+	AttributeEmail = "email"
+)
+
+// Операторы правил таргетинга
+const (
+	OperatorEquals = "equals"
+	OperatorIn     = "in"
+	OperatorRegex  = "regex"
+)
+
+// Причины результата вычисления флага
+const (
+	ReasonNotFound = "not_found"
+	ReasonDisabled = "disabled"
+	ReasonDefault  = "default"
+	ReasonRule     = "rule"
+	ReasonRollout  = "rollout"
+	ReasonNoMatch  = "no_match"
+)
+
+// rolloutBuckets точность процентного раската: сотые доли процента
+const rolloutBuckets = 10000
+
+// Rule правило таргетинга: при совпадении атрибута флаг включается с вариантом правила
+type Rule struct {
+	Attribute string   `json:"attribute"`
+	Operator  string   `json:"operator"`
+	Value     string   `json:"value,omitempty"`
+	Values    []string `json:"values,omitempty"`
+	// Variant вариант при совпадении; пустой - вариант по умолчанию флага
+	Variant string `json:"variant,omitempty"`
+
+	compileOnce sync.Once
+	re          *regexp.Regexp
+	reErr       error
+}
+
+// Evaluation результат вычисления флага для контекста
+type Evaluation struct {
+	Key     string          `json:"key"`
+	Enabled bool            `json:"enabled"`
+	Variant string          `json:"variant,omitempty"`
+	Value   json.RawMessage `json:"value,omitempty"`
+	Reason  string          `json:"reason"`
+	// RuleIndex номер совпавшего правила, если Reason == rule
+	RuleIndex int `json:"rule_index,omitempty"`
+}
+
+// Evaluate вычисляет флаг для контекста: правила по порядку, затем rollout.
+// Флаг без правил и rollout включен для всех; без контекста таргетинг не
+// применяется и включенный флаг включен.
+func (m *Manager) Evaluate(key string, ctx *Context) Evaluation {
+	m.mu.RLock()
+	flag, exists := m.flags[key]
+	m.mu.RUnlock()
+
+	var result Evaluation
+	if exists {
+		result = flag.evaluate(ctx)
+	} else {
+		m.logger.Warnw("Feature flag not found", "key", key)
+		result = Evaluation{Key: key, Reason: ReasonNotFound}
+	}
+
+	if m.metrics != nil {
+		m.metrics.RecordFlagEvaluation(key, result.Enabled, result.Reason)
+	}
+	return result
+}
+
+// Variant возвращает вариант флага для контекста; пустая строка - флаг выключен
+// или у него нет вариантов
+func (m *Manager) Variant(key string, ctx *Context) string {
+	return m.Evaluate(key, ctx).Variant
+}
+
+func (f *Flag) evaluate(ctx *Context) Evaluation {
+	if !f.Enabled {
+		return Evaluation{Key: f.Key, Reason: ReasonDisabled}
+	}
+	if ctx == nil || (len(f.Rules) == 0 && f.Rollout == nil) {
+		return f.serve(f.DefaultVariant, ReasonDefault)
+	}
+
+	for i, rule := range f.Rules {
+		if rule.matches(ctx) {
+			variant := rule.Variant
+			if variant == "" {
+				variant = f.DefaultVariant
+			}
+			result := f.serve(variant, ReasonRule)
+			result.RuleIndex = i
+			return result
+		}
+	}
+
+	if f.Rollout != nil && f.Rollout.includes(f.Key, ctx) {
+		return f.serve(f.DefaultVariant, ReasonRollout)
+	}
+	return Evaluation{Key: f.Key, Reason: ReasonNoMatch}
+}
+
+// serve возвращает включенный флаг с вариантом
+func (f *Flag) serve(variant, reason string) Evaluation {
+	return Evaluation{
+		Key:     f.Key,
+		Enabled: true,
+		Variant: variant,
+		Value:   f.Variants[variant],
+		Reason:  reason,
+	}
+}
+
+// includes проверяет списки пользователей и команд, затем процент раската
+func (r *Rollout) includes(flagKey string, ctx *Context) bool {
+	if ctx.UserID > 0 {
+		for _, id := range r.UserIDs {
+			if id == ctx.UserID {
+				return true
+			}
+		}
+	}
+
+	if ctx.TeamID > 0 {
+		for _, id := range r.TeamIDs {
+			if id == ctx.TeamID {
+				return true
+			}
+		}
+	}
+
+	return r.Percentage > 0 && ctx.UserID > 0 &&
+		rolloutBucket(flagKey, ctx.UserID) < r.Percentage*rolloutBuckets/100
+}
+
+// rolloutBucket стабильно распределяет пользователя по корзинам 0..9999.
+// Ключ флага входит в хеш, поэтому разные флаги раскатываются на разных пользователей.
+func rolloutBucket(flagKey string, userID int64) int {
+	sum := sha256.Sum256([]byte(flagKey + ":" + strconv.FormatInt(userID, 10)))
+	return int(binary.BigEndian.Uint64(sum[:8]) % rolloutBuckets)
+}
+
+// matches проверяет правило на контексте
+func (r *Rule) matches(ctx *Context) bool {
+	value, ok := ctx.attribute(r.Attribute)
+	if !ok {
+		return false
+	}
+
+	switch r.Operator {
+	case OperatorEquals:
+		return strings.EqualFold(value, r.Value)
+	case OperatorIn:
+		for _, v := range r.Values {
+			if strings.EqualFold(value, v) {
+				return true
+			}
+		}
+		return false
+	case OperatorRegex:
+		re, err := r.regexp()
+		return err == nil && re.MatchString(value)
+	default:
+		return false
+	}
+}
+
+// regexp компилирует регулярное выражение правила один раз
+func (r *Rule) regexp() (*regexp.Regexp, error) {
+	r.compileOnce.Do(func() {
+		r.re, r.reErr = regexp.Compile(r.Value)
+	})
+	return r.re, r.reErr
+}
+
+// attribute возвращает значение атрибута; false - атрибут не задан в контексте
+func (c *Context) attribute(name string) (string, bool) {
+	switch name {
+	case AttributeUser:
+		return strconv.FormatInt(c.UserID, 10), c.UserID > 0
+	case AttributeTeam:
+		return strconv.FormatInt(c.TeamID, 10), c.TeamID > 0
+	case AttributeRole:
+		return c.Role, c.Role != ""
+	case AttributeEmail:
+		return c.Email, c.Email != ""
+	default:
+		return "", false
+	}
+}
+
+// validateTargeting проверяет правила и варианты флага
+func validateTargeting(flag *Flag) error {
+	if flag.DefaultVariant != "" {
+		if _, ok := flag.Variants[flag.DefaultVariant]; !ok {
+			return fmt.Errorf("default_variant %q is not defined in variants", flag.DefaultVariant)
+		}
+	}
+	for name, value := range flag.Variants {
+		if !json.Valid(value) {
+			return fmt.Errorf("variant %q is not valid JSON", name)
+		}
+	}
+
+	for i, rule := range flag.Rules {
+		if rule == nil {
+			return fmt.Errorf("rule %d is empty", i)
+		}
+		switch rule.Attribute {
+		case AttributeUser, AttributeTeam, AttributeRole, AttributeEmail:
+		default:
+			return fmt.Errorf("rule %d: unknown attribute %q", i, rule.Attribute)
+		}
+		switch rule.Operator {
+		case OperatorEquals:
+		case OperatorIn:
+			if len(rule.Values) == 0 {
+				return fmt.Errorf("rule %d: operator in requires values", i)
+			}
+		case OperatorRegex:
+			if _, err := rule.regexp(); err != nil {
+				return fmt.Errorf("rule %d: invalid regex: %w", i, err)
+			}
+		default:
+			return fmt.Errorf("rule %d: unknown operator %q", i, rule.Operator)
+		}
+		if rule.Variant != "" {
+			if _, ok := flag.Variants[rule.Variant]; !ok {
+				return fmt.Errorf("rule %d: variant %q is not defined in variants", i, rule.Variant)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package featureflags
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/user/pr-reviewer/internal/cache"
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+func TestRolloutBucket_Distribution(t *testing.T) {
+	const users = 10000
+	inA, inBoth := 0, 0
+	for userID := int64(1); userID <= users; userID++ {
+		a := rolloutBucket("flag_a", userID) < 1000 // 10%
+		b := rolloutBucket("flag_b", userID) < 1000
+		if a {
+			inA++
+		}
+		if a && b {
+			inBoth++
+		}
+	}
+
+	if inA < 900 || inA > 1100 {
+		t.Errorf("expected ~10%% of users in rollout, got %d of %d", inA, users)
+	}
+	// С userID % 100 в обоих раскатах были бы одни и те же пользователи (1000)
+	if inBoth > 150 {
+		t.Errorf("expected independent cohorts (~100 users in both), got %d", inBoth)
+	}
+}
+
+func TestRolloutBucket_Stable(t *testing.T) {
+	if rolloutBucket("flag", 42) != rolloutBucket("flag", 42) {
+		t.Error("expected stable bucket for the same flag and user")
+	}
+}
+
+func TestEvaluate_Rules(t *testing.T) {
+	log, _ := logger.New("error", "test")
+	m := NewManager(cache.NewNoOpCache(), log)
+	m.SetFlag(&Flag{
+		Key:     "checkout",
+		Enabled: true,
+		Rules: []*Rule{
+			{Attribute: AttributeEmail, Operator: OperatorRegex, Value: `@example\.com$`, Variant: "internal"},
+			{Attribute: AttributeRole, Operator: OperatorEquals, Value: "admin", Variant: "internal"},
+			{Attribute: AttributeTeam, Operator: OperatorIn, Values: []string{"5", "7"}},
+		},
+		Variants: map[string]json.RawMessage{
+			"internal": json.RawMessage(`{"layout":"v3"}`),
+			"beta":     json.RawMessage(`"v2"`),
+		},
+		DefaultVariant: "beta",
+	})
+
+	tests := []struct {
+		name      string
+		ctx       *Context
+		enabled   bool
+		variant   string
+		reason    string
+		ruleIndex int
+	}{
+		{"email regex", &Context{UserID: 1, Email: "dev@example.com", Role: "admin"}, true, "internal", ReasonRule, 0},
+		{"role equals", &Context{UserID: 2, Email: "dev@other.org", Role: "ADMIN"}, true, "internal", ReasonRule, 1},
+		{"team in with default variant", &Context{UserID: 3, TeamID: 7}, true, "beta", ReasonRule, 2},
+		{"no match", &Context{UserID: 4, TeamID: 9, Role: "member"}, false, "", ReasonNoMatch, 0},
+		{"no context", nil, true, "beta", ReasonDefault, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := m.Evaluate("checkout", tt.ctx)
+			if result.Enabled != tt.enabled || result.Variant != tt.variant ||
+				result.Reason != tt.reason || result.RuleIndex != tt.ruleIndex {
+				t.Errorf("unexpected evaluation: %+v", result)
+			}
+		})
+	}
+
+	if value := m.Evaluate("checkout", &Context{Role: "admin"}).Value; string(value) != `{"layout":"v3"}` {
+		t.Errorf("expected JSON variant value, got %s", value)
+	}
+	if m.Variant("missing", &Context{UserID: 1}) != "" {
+		t.Error("expected empty variant for unknown flag")
+	}
+}
+
+func TestEvaluate_RulesBeforeRollout(t *testing.T) {
+	log, _ := logger.New("error", "test")
+	m := NewManager(cache.NewNoOpCache(), log)
+	m.SetFlag(&Flag{
+		Key:     "search",
+		Enabled: true,
+		Rules:   []*Rule{{Attribute: AttributeUser, Operator: OperatorEquals, Value: "10"}},
+		Rollout: &Rollout{UserIDs: []int64{20}},
+	})
+
+	if r := m.Evaluate("search", &Context{UserID: 10}); r.Reason != ReasonRule {
+		t.Errorf("expected rule match for user 10, got %+v", r)
+	}
+	if r := m.Evaluate("search", &Context{UserID: 20}); r.Reason != ReasonRollout {
+		t.Errorf("expected rollout match for user 20, got %+v", r)
+	}
+	if m.IsEnabledWithContext("search", &Context{UserID: 30}) {
+		t.Error("expected user 30 to be excluded")
+	}
+}
+
+func TestValidateFlag_Targeting(t *testing.T) {
+	variants := map[string]json.RawMessage{"on": json.RawMessage(`"on"`)}
+	tests := []struct {
+		name string
+		flag *Flag
+	}{
+		{"unknown attribute", &Flag{Key: "f", Rules: []*Rule{{Attribute: "country", Operator: OperatorEquals}}}},
+		{"unknown operator", &Flag{Key: "f", Rules: []*Rule{{Attribute: AttributeRole, Operator: "contains"}}}},
+		{"invalid regex", &Flag{Key: "f", Rules: []*Rule{{Attribute: AttributeEmail, Operator: OperatorRegex, Value: "("}}}},
+		{"in without values", &Flag{Key: "f", Rules: []*Rule{{Attribute: AttributeTeam, Operator: OperatorIn}}}},
+		{"undefined rule variant", &Flag{Key: "f", Variants: variants,
+			Rules: []*Rule{{Attribute: AttributeRole, Operator: OperatorEquals, Value: "admin", Variant: "off"}}}},
+		{"undefined default variant", &Flag{Key: "f", Variants: variants, DefaultVariant: "off"}},
+		{"invalid variant JSON", &Flag{Key: "f", Variants: map[string]json.RawMessage{"on": json.RawMessage(`{`)}}},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateFlag(tt.flag); err == nil {
+			t.Errorf("%s: expected validation error", tt.name)
+		}
+	}
+}
@@ -3,11 +3,27 @@ package featureflags
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
 	"sync"
 	"time"
 
 	"github.com/user/pr-reviewer/internal/cache"
 	"github.com/user/pr-reviewer/internal/logger"
+	"github.com/user/pr-reviewer/internal/metrics"
+)
+
+// Ключи встроенных флагов
+const (
+	FlagJWTAuth            = "jwt_auth"
+	FlagRedisCache         = "redis_cache"
+	FlagWebhooks           = "webhooks"
+	FlagAuditLog           = "audit_log"
+	FlagAdvancedMetrics    = "advanced_metrics"
+	FlagRateLimiting       = "rate_limiting"
+	FlagCircuitBreaker     = "circuit_breaker"
+	FlagDistributedTracing = "distributed_tracing"
 )
 
 // Flag представляет feature flag
@@ -18,6 +34,13 @@ type Flag struct {
 	Rollout     *Rollout               `json:"rollout,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 	UpdatedAt   time.Time              `json:"updated_at"`
+
+	// Rules правила таргетинга, проверяются по порядку до первого совпадения
+	Rules []*Rule `json:"rules,omitempty"`
+	// Variants значения флага (строки или JSON) по имени варианта
+	Variants map[string]json.RawMessage `json:"variants,omitempty"`
+	// DefaultVariant вариант для включенного флага без варианта в правиле
+	DefaultVariant string `json:"default_variant,omitempty"`
 }
 
 // Rollout конфигурация постепенного раската
@@ -33,22 +56,63 @@ type Context struct {
 	UserID int64
 	TeamID int64
 	Email  string
+	Role   string
+}
+
+var (
+	ErrFlagNotFound   = errors.New("feature flag not found")
+	ErrInvalidFlagKey = errors.New("invalid feature flag key")
+	ErrProtectedFlag  = errors.New("feature flag can only be set via environment")
+)
+
+// protectedFlags флаги, которые задаются только окружением при запуске:
+// изменение через admin API или файл позволило бы отключить проверку доступа
+var protectedFlags = map[string]bool{
+	FlagJWTAuth: true,
+}
+
+// IsProtected проверяет, что флаг нельзя изменить через admin API или файл
+func IsProtected(key string) bool {
+	return protectedFlags[key]
 }
 
-// Manager управляет feature flags
+// flagKeyPattern допустимые ключи флагов
+var flagKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,99}$`)
+
+// Store хранилище флагов, измененных через admin API. Изменение должно
+// дойти до всех реплик (см. Listener).
+type Store interface {
+	LoadFlags(ctx context.Context) ([]*Flag, error)
+	SaveFlag(ctx context.Context, flag *Flag) error
+	// DeleteFlag удаляет флаг; false - флага в хранилище не было
+	DeleteFlag(ctx context.Context, key string) (bool, error)
+}
+
+// Manager управляет feature flags. Приоритет источников по возрастанию:
+// флаги, заданные в коде и при запуске (defaults), файл флагов (FileProvider)
+// и хранилище (admin API).
+// Значения *Flag не изменяются после публикации: изменение заменяет флаг целиком.
 type Manager struct {
-	flags  map[string]*Flag
-	mu     sync.RWMutex
-	cache  cache.Cache
-	logger *logger.Logger
+	flags     map[string]*Flag
+	defaults  map[string]*Flag
+	fileFlags map[string]*Flag
+	overrides map[string]*Flag
+	mu        sync.RWMutex
+	cache     cache.Cache
+	store     Store
+	metrics   *metrics.Metrics
+	logger    *logger.Logger
 }
 
 // NewManager создает новый feature flags manager
 func NewManager(cacheClient cache.Cache, log *logger.Logger) *Manager {
 	m := &Manager{
-		flags:  make(map[string]*Flag),
-		cache:  cacheClient,
-		logger: log,
+		flags:     make(map[string]*Flag),
+		defaults:  make(map[string]*Flag),
+		fileFlags: make(map[string]*Flag),
+		overrides: make(map[string]*Flag),
+		cache:     cacheClient,
+		logger:    log,
 	}
 
 	// Инициализируем дефолтные флаги
@@ -61,49 +125,49 @@ func NewManager(cacheClient cache.Cache, log *logger.Logger) *Manager {
 func (m *Manager) initDefaultFlags() {
 	defaultFlags := []*Flag{
 		{
-			Key:         "jwt_auth",
+			Key:         FlagJWTAuth,
 			Enabled:     false,
 			Description: "Enable JWT authentication",
 			UpdatedAt:   time.Now(),
 		},
 		{
-			Key:         "redis_cache",
+			Key:         FlagRedisCache,
 			Enabled:     true,
 			Description: "Enable Redis caching",
 			UpdatedAt:   time.Now(),
 		},
 		{
-			Key:         "webhooks",
-			Enabled:     false,
+			Key:         FlagWebhooks,
+			Enabled:     true,
 			Description: "Enable webhook notifications",
 			UpdatedAt:   time.Now(),
 		},
 		{
-			Key:         "audit_log",
+			Key:         FlagAuditLog,
 			Enabled:     true,
 			Description: "Enable audit logging",
 			UpdatedAt:   time.Now(),
 		},
 		{
-			Key:         "advanced_metrics",
+			Key:         FlagAdvancedMetrics,
 			Enabled:     true,
 			Description: "Enable advanced Prometheus metrics",
 			UpdatedAt:   time.Now(),
 		},
 		{
-			Key:         "rate_limiting",
+			Key:         FlagRateLimiting,
 			Enabled:     true,
 			Description: "Enable rate limiting",
 			UpdatedAt:   time.Now(),
 		},
 		{
-			Key:         "circuit_breaker",
+			Key:         FlagCircuitBreaker,
 			Enabled:     false,
 			Description: "Enable circuit breaker for external calls",
 			UpdatedAt:   time.Now(),
 		},
 		{
-			Key:         "distributed_tracing",
+			Key:         FlagDistributedTracing,
 			Enabled:     false,
 			Description: "Enable distributed tracing with Jaeger",
 			UpdatedAt:   time.Now(),
@@ -111,6 +175,7 @@ func (m *Manager) initDefaultFlags() {
 	}
 
 	for _, flag := range defaultFlags {
+		m.defaults[flag.Key] = flag
 		m.flags[flag.Key] = flag
 	}
 
@@ -122,74 +187,90 @@ func (m *Manager) IsEnabled(key string) bool {
 	return m.IsEnabledWithContext(key, nil)
 }
 
-// IsEnabledWithContext проверяет флаг с контекстом для rollout
+// IsEnabledWithContext проверяет флаг с контекстом для правил и rollout
 func (m *Manager) IsEnabledWithContext(key string, ctx *Context) bool {
-	m.mu.RLock()
-	flag, exists := m.flags[key]
-	m.mu.RUnlock()
+	return m.Evaluate(key, ctx).Enabled
+}
 
-	if !exists {
-		m.logger.Warnw("Feature flag not found", "key", key)
-		return false
-	}
+// SetFlag устанавливает значение флага по умолчанию. Флаг из хранилища,
+// если он есть, продолжает действовать.
+func (m *Manager) SetFlag(flag *Flag) {
+	flag.UpdatedAt = time.Now()
 
-	// Если флаг полностью выключен
-	if !flag.Enabled {
-		return false
-	}
+	m.mu.Lock()
+	m.setDefaultLocked(flag)
+	m.mu.Unlock()
 
-	// Если нет rollout конфигурации - флаг включен для всех
-	if flag.Rollout == nil || ctx == nil {
-		return true
+	// Инвалидируем кеш
+	if m.cache != nil {
+		_ = m.cache.Delete(context.Background(), "feature_flags")
 	}
 
-	// Проверяем whitelist по user ID
-	if ctx.UserID > 0 {
-		for _, id := range flag.Rollout.UserIDs {
-			if id == ctx.UserID {
-				return true
-			}
-		}
-	}
+	m.logger.Infow("Feature flag updated",
+		"key", flag.Key,
+		"enabled", flag.Enabled,
+	)
+}
 
-	// Проверяем whitelist по team ID
-	if ctx.TeamID > 0 {
-		for _, id := range flag.Rollout.TeamIDs {
-			if id == ctx.TeamID {
-				return true
-			}
+// setDefaultLocked заменяет флаг по умолчанию. Вызывается под блокировкой.
+func (m *Manager) setDefaultLocked(flag *Flag) {
+	m.defaults[flag.Key] = flag
+	m.refreshLocked(flag.Key)
+}
+
+// refreshLocked выбирает действующий флаг из источника с наибольшим
+// приоритетом. Вызывается под блокировкой.
+func (m *Manager) refreshLocked(key string) {
+	for _, source := range []map[string]*Flag{m.overrides, m.fileFlags, m.defaults} {
+		if flag, exists := source[key]; exists {
+			m.flags[key] = flag
+			return
 		}
 	}
+	delete(m.flags, key)
+}
 
-	// Проверяем percentage rollout
-	if flag.Rollout.Percentage > 0 && ctx.UserID > 0 {
-		// Детерминированный hash для consistency
-		hash := int(ctx.UserID % 100)
-		if hash < flag.Rollout.Percentage {
-			return true
+// mergeLocked пересобирает действующие флаги из всех источников.
+// Вызывается под блокировкой.
+func (m *Manager) mergeLocked() {
+	flags := make(map[string]*Flag, len(m.defaults)+len(m.fileFlags)+len(m.overrides))
+	for _, source := range []map[string]*Flag{m.defaults, m.fileFlags, m.overrides} {
+		for key, flag := range source {
+			flags[key] = flag
 		}
 	}
-
-	return false
+	m.flags = flags
 }
 
-// SetFlag устанавливает значение флага
-func (m *Manager) SetFlag(flag *Flag) {
-	flag.UpdatedAt = time.Now()
+// SetFileFlags атомарно заменяет флаги из файла: флаги, удаленные из файла,
+// возвращаются к значению по умолчанию
+func (m *Manager) SetFileFlags(flags []*Flag) {
+	fileFlags := make(map[string]*Flag, len(flags))
+	for _, flag := range flags {
+		fileFlags[flag.Key] = flag
+	}
 
 	m.mu.Lock()
-	m.flags[flag.Key] = flag
+	m.fileFlags = fileFlags
+	m.mergeLocked()
 	m.mu.Unlock()
+}
 
-	// Инвалидируем кеш
-	if m.cache != nil {
-		_ = m.cache.Delete(context.Background(), "feature_flags")
+// updateDefault изменяет копию флага по умолчанию
+func (m *Manager) updateDefault(key string, update func(flag *Flag)) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, exists := m.defaults[key]
+	if !exists {
+		return false
 	}
 
-	m.logger.Infow("Feature flag updated",
-		"key", flag.Key,
-		"enabled", flag.Enabled,
-	)
+	flag := *current
+	update(&flag)
+	flag.UpdatedAt = time.Now()
+	m.setDefaultLocked(&flag)
+	return true
 }
 
 // GetFlag возвращает флаг
@@ -214,56 +295,35 @@ func (m *Manager) GetAllFlags() map[string]*Flag {
 	return result
 }
 
-// EnableFlag включает флаг
+// EnableFlag включает флаг по умолчанию
 func (m *Manager) EnableFlag(key string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	flag, exists := m.flags[key]
-	if !exists {
+	if !m.updateDefault(key, func(flag *Flag) { flag.Enabled = true }) {
 		m.logger.Warnw("Feature flag not found", "key", key)
 		return nil
 	}
 
-	flag.Enabled = true
-	flag.UpdatedAt = time.Now()
-
 	m.logger.Infow("Feature flag enabled", "key", key)
 	return nil
 }
 
-// DisableFlag выключает флаг
+// DisableFlag выключает флаг по умолчанию
 func (m *Manager) DisableFlag(key string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	flag, exists := m.flags[key]
-	if !exists {
+	if !m.updateDefault(key, func(flag *Flag) { flag.Enabled = false }) {
 		m.logger.Warnw("Feature flag not found", "key", key)
 		return nil
 	}
 
-	flag.Enabled = false
-	flag.UpdatedAt = time.Now()
-
 	m.logger.Infow("Feature flag disabled", "key", key)
 	return nil
 }
 
-// SetRollout устанавливает rollout конфигурацию
+// SetRollout устанавливает rollout конфигурацию флага по умолчанию
 func (m *Manager) SetRollout(key string, rollout *Rollout) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	flag, exists := m.flags[key]
-	if !exists {
+	if !m.updateDefault(key, func(flag *Flag) { flag.Rollout = rollout }) {
 		m.logger.Warnw("Feature flag not found", "key", key)
 		return nil
 	}
 
-	flag.Rollout = rollout
-	flag.UpdatedAt = time.Now()
-
 	m.logger.Infow("Feature flag rollout updated",
 		"key", key,
 		"percentage", rollout.Percentage,
@@ -271,7 +331,117 @@ func (m *Manager) SetRollout(key string, rollout *Rollout) error {
 	return nil
 }
 
-// LoadFromCache загружает флаги из кеша
+// SetMetrics включает метрики вычислений флагов
+func (m *Manager) SetMetrics(met *metrics.Metrics) {
+	m.metrics = met
+}
+
+// SetStore подключает хранилище флагов. Флаги загружаются вызовом Reload.
+func (m *Manager) SetStore(store Store) {
+	m.store = store
+}
+
+// Reload загружает флаги из хранилища. Вызывается при запуске и при
+// уведомлении об изменении флага на другой реплике.
+func (m *Manager) Reload(ctx context.Context) error {
+	if m.store == nil {
+		return nil
+	}
+
+	stored, err := m.store.LoadFlags(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load feature flags: %w", err)
+	}
+
+	overrides := make(map[string]*Flag, len(stored))
+	for _, flag := range stored {
+		if IsProtected(flag.Key) {
+			m.logger.Warnw("Ignoring stored override of protected feature flag", "key", flag.Key)
+			continue
+		}
+		overrides[flag.Key] = flag
+	}
+
+	m.mu.Lock()
+	m.overrides = overrides
+	m.mergeLocked()
+	m.mu.Unlock()
+
+	m.logger.Infow("Feature flags loaded", "stored", len(overrides))
+	return nil
+}
+
+// SaveFlag сохраняет флаг в хранилище (admin API). Без хранилища флаг
+// действует только на этой реплике до рестарта.
+func (m *Manager) SaveFlag(ctx context.Context, flag *Flag) error {
+	if IsProtected(flag.Key) {
+		return ErrProtectedFlag
+	}
+	if err := ValidateFlag(flag); err != nil {
+		return err
+	}
+	flag.UpdatedAt = time.Now()
+
+	if m.store != nil {
+		if err := m.store.SaveFlag(ctx, flag); err != nil {
+			return fmt.Errorf("failed to save feature flag: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	m.overrides[flag.Key] = flag
+	m.flags[flag.Key] = flag
+	m.mu.Unlock()
+
+	m.logger.Infow("Feature flag saved", "key", flag.Key, "enabled", flag.Enabled)
+	return nil
+}
+
+// DeleteFlag удаляет флаг из хранилища: флаг возвращается к значению из
+// файла или по умолчанию, если их нет - удаляется
+func (m *Manager) DeleteFlag(ctx context.Context, key string) error {
+	if IsProtected(key) {
+		return ErrProtectedFlag
+	}
+
+	m.mu.RLock()
+	_, overridden := m.overrides[key]
+	m.mu.RUnlock()
+
+	if m.store != nil {
+		deleted, err := m.store.DeleteFlag(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to delete feature flag: %w", err)
+		}
+		overridden = overridden || deleted
+	}
+	if !overridden {
+		return ErrFlagNotFound
+	}
+
+	m.mu.Lock()
+	delete(m.overrides, key)
+	m.refreshLocked(key)
+	m.mu.Unlock()
+
+	m.logger.Infow("Feature flag deleted", "key", key)
+	return nil
+}
+
+// ValidateFlag проверяет ключ и rollout флага
+func ValidateFlag(flag *Flag) error {
+	if !flagKeyPattern.MatchString(flag.Key) {
+		return ErrInvalidFlagKey
+	}
+	if flag.Rollout != nil && (flag.Rollout.Percentage < 0 || flag.Rollout.Percentage > 100) {
+		return errors.New("rollout percentage must be between 0 and 100")
+	}
+	return validateTargeting(flag)
+}
+
+// LoadFromCache загружает флаги из кеша.
+//
+// Deprecated: флаги хранятся в Store и загружаются Reload.
 func (m *Manager) LoadFromCache(ctx context.Context) error {
 	if m.cache == nil {
 		return nil
@@ -291,7 +461,9 @@ func (m *Manager) LoadFromCache(ctx context.Context) error {
 	return nil
 }
 
-// SaveToCache сохраняет флаги в кеш
+// SaveToCache сохраняет флаги в кеш.
+//
+// Deprecated: флаги сохраняются в Store через SaveFlag.
 func (m *Manager) SaveToCache(ctx context.Context) error {
 	if m.cache == nil {
 		return nil
@@ -0,0 +1,214 @@
+package featureflags
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/health"
+	"github.com/user/pr-reviewer/internal/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// fileDocument схема файла флагов
+type fileDocument struct {
+	Flags []fileFlag `json:"flags"`
+}
+
+// fileFlag флаг в файле; поля совпадают с PUT /admin/flags/{key}
+type fileFlag struct {
+	Key            string                     `json:"key"`
+	Enabled        *bool                      `json:"enabled"`
+	Description    string                     `json:"description"`
+	Rollout        *Rollout                   `json:"rollout"`
+	Metadata       map[string]interface{}     `json:"metadata"`
+	Rules          []*Rule                    `json:"rules"`
+	Variants       map[string]json.RawMessage `json:"variants"`
+	DefaultVariant string                     `json:"default_variant"`
+}
+
+// ParseFlagsFile разбирает и проверяет файл флагов в YAML или JSON (JSON -
+// подмножество YAML). Неизвестные поля считаются ошибкой: опечатка в ключе
+// не должна молча выключать правило.
+func ParseFlagsFile(data []byte) ([]*Flag, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	if raw == nil {
+		return nil, errors.New("flags file is empty")
+	}
+
+	// YAML приводится к JSON, чтобы использовать схему и валидацию admin API
+	converted, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported YAML value: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(converted))
+	dec.DisallowUnknownFields()
+	var doc fileDocument
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid flags file: %w", err)
+	}
+
+	now := time.Now()
+	flags := make([]*Flag, 0, len(doc.Flags))
+	seen := make(map[string]bool, len(doc.Flags))
+	for i, ff := range doc.Flags {
+		if ff.Enabled == nil {
+			return nil, fmt.Errorf("flag %d (%s): enabled is required", i, ff.Key)
+		}
+		if seen[ff.Key] {
+			return nil, fmt.Errorf("flag %d: duplicate key %s", i, ff.Key)
+		}
+		seen[ff.Key] = true
+		if IsProtected(ff.Key) {
+			return nil, fmt.Errorf("flag %d (%s): %w", i, ff.Key, ErrProtectedFlag)
+		}
+
+		flag := &Flag{
+			Key:            ff.Key,
+			Enabled:        *ff.Enabled,
+			Description:    ff.Description,
+			Rollout:        ff.Rollout,
+			Metadata:       ff.Metadata,
+			Rules:          ff.Rules,
+			Variants:       ff.Variants,
+			DefaultVariant: ff.DefaultVariant,
+			UpdatedAt:      now,
+		}
+		if err := ValidateFlag(flag); err != nil {
+			return nil, fmt.Errorf("flag %d (%s): %w", i, ff.Key, err)
+		}
+		flags = append(flags, flag)
+	}
+	return flags, nil
+}
+
+// FileProvider загружает флаги из файла (например, ConfigMap) и перечитывает
+// его при изменении содержимого. Флаги из файла переопределяют значения по
+// умолчанию, флаги из admin API - флаги из файла. При ошибке загрузки
+// продолжают действовать флаги последней успешной загрузки, ошибка видна
+// в health check.
+type FileProvider struct {
+	path    string
+	manager *Manager
+	logger  *logger.Logger
+
+	mu       sync.RWMutex
+	hash     [sha256.Size]byte
+	loaded   bool
+	loadedAt time.Time
+	count    int
+	lastErr  error
+}
+
+// NewFileProvider создает провайдер флагов из файла
+func NewFileProvider(path string, manager *Manager, log *logger.Logger) *FileProvider {
+	return &FileProvider{
+		path:    path,
+		manager: manager,
+		logger:  log,
+	}
+}
+
+// Load читает файл и применяет флаги, если содержимое изменилось.
+// Файл применяется целиком или не применяется вовсе.
+func (p *FileProvider) Load() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Содержимое сравнивается по хешу: ConfigMap обновляется заменой
+	// symlink, и время изменения файла ненадежно
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return p.failLocked(fmt.Errorf("failed to read flags file: %w", err))
+	}
+	hash := sha256.Sum256(data)
+	if p.loaded && hash == p.hash {
+		p.lastErr = nil
+		return nil
+	}
+
+	flags, err := ParseFlagsFile(data)
+	if err != nil {
+		return p.failLocked(err)
+	}
+
+	p.manager.SetFileFlags(flags)
+	p.hash = hash
+	p.loaded = true
+	p.loadedAt = time.Now()
+	p.count = len(flags)
+	p.lastErr = nil
+
+	p.logger.Infow("Feature flags file loaded", "path", p.path, "flags", len(flags))
+	return nil
+}
+
+// failLocked запоминает ошибку загрузки; повторная ошибка не логируется
+func (p *FileProvider) failLocked(err error) error {
+	if p.lastErr == nil || p.lastErr.Error() != err.Error() {
+		p.logger.Errorw("Failed to load feature flags file", "path", p.path, "error", err)
+	}
+	p.lastErr = err
+	return err
+}
+
+// Run перечитывает файл с интервалом до отмены контекста
+func (p *FileProvider) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = p.Load()
+		}
+	}
+}
+
+// Name имя health check
+func (p *FileProvider) Name() string {
+	return "feature_flags_file"
+}
+
+// Check сообщает об ошибке последней загрузки файла: degraded, если
+// действуют флаги предыдущей загрузки, unhealthy - если файл не загружался
+func (p *FileProvider) Check(_ context.Context) health.CheckResult {
+	start := time.Now()
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	result := health.CheckResult{
+		Status:    health.StatusHealthy,
+		Timestamp: start,
+		Details: map[string]interface{}{
+			"path":  p.path,
+			"flags": p.count,
+		},
+	}
+	if p.loaded {
+		result.Details["loaded_at"] = p.loadedAt
+	}
+
+	if p.lastErr != nil {
+		result.Error = p.lastErr.Error()
+		if p.loaded {
+			result.Status = health.StatusDegraded
+		} else {
+			result.Status = health.StatusUnhealthy
+		}
+	}
+
+	result.Duration = time.Since(start).String()
+	return result
+}
@@ -0,0 +1,126 @@
+package featureflags
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/user/pr-reviewer/internal/cache"
+	"github.com/user/pr-reviewer/internal/health"
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+const testFlagsYAML = `
+flags:
+  - key: redis_cache
+    enabled: false
+  - key: new_dashboard
+    enabled: true
+    rollout:
+      percentage: 25
+    rules:
+      - attribute: role
+        operator: equals
+        value: admin
+        variant: v3
+    variants:
+      v3: {layout: grid}
+`
+
+func TestParseFlagsFile(t *testing.T) {
+	flags, err := ParseFlagsFile([]byte(testFlagsYAML))
+	if err != nil {
+		t.Fatalf("failed to parse YAML: %v", err)
+	}
+	if len(flags) != 2 || flags[1].Rollout.Percentage != 25 || string(flags[1].Variants["v3"]) != `{"layout":"grid"}` {
+		t.Errorf("unexpected flags: %+v", flags[1])
+	}
+
+	if _, err := ParseFlagsFile([]byte(`{"flags": [{"key": "beta", "enabled": true}]}`)); err != nil {
+		t.Errorf("failed to parse JSON: %v", err)
+	}
+
+	invalid := map[string]string{
+		"empty":           ``,
+		"unknown field":   "flags:\n  - key: beta\n    enabled: true\n    rollot: {percentage: 10}",
+		"missing enabled": "flags:\n  - key: beta",
+		"duplicate key":   "flags:\n  - {key: beta, enabled: true}\n  - {key: beta, enabled: false}",
+		"invalid key":     "flags:\n  - {key: Beta Flag, enabled: true}",
+		"protected flag":  "flags:\n  - {key: jwt_auth, enabled: false}",
+		"invalid rule":    "flags:\n  - key: beta\n    enabled: true\n    rules: [{attribute: team, operator: like}]",
+	}
+	for name, data := range invalid {
+		if _, err := ParseFlagsFile([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	log, _ := logger.New("error", "test")
+	m := NewManager(cache.NewNoOpCache(), log)
+	m.SetStore(&memoryStore{flags: map[string]*Flag{}})
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "feature-flags.yaml")
+	p := NewFileProvider(path, m, log)
+	if err := p.Load(); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+	if result := p.Check(ctx); result.Status != health.StatusUnhealthy {
+		t.Errorf("expected unhealthy before first load, got %s", result.Status)
+	}
+
+	writeFile(t, path, testFlagsYAML)
+	if err := p.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if m.IsEnabled(FlagRedisCache) || !m.IsEnabled("new_dashboard") {
+		t.Error("expected file flags to override defaults")
+	}
+
+	// Невалидный файл не применяется, действуют флаги прошлой загрузки
+	writeFile(t, path, strings.Replace(testFlagsYAML, "percentage: 25", "percentage: 250", 1))
+	if err := p.Load(); err == nil {
+		t.Fatal("expected validation error")
+	}
+	if !m.IsEnabled("new_dashboard") {
+		t.Error("expected previous file flags to remain")
+	}
+	if result := p.Check(ctx); result.Status != health.StatusDegraded || result.Error == "" {
+		t.Errorf("expected degraded with error, got %s (%q)", result.Status, result.Error)
+	}
+
+	// Флаг из admin API переопределяет файл; после удаления действует файл
+	if err := m.SaveFlag(ctx, &Flag{Key: FlagRedisCache, Enabled: true}); err != nil {
+		t.Fatalf("failed to save flag: %v", err)
+	}
+	writeFile(t, path, "flags:\n  - {key: redis_cache, enabled: false}")
+	if err := p.Load(); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	if !m.IsEnabled(FlagRedisCache) {
+		t.Error("expected API override to take precedence over file")
+	}
+	if _, exists := m.GetFlag("new_dashboard"); exists {
+		t.Error("expected flag removed from file to be removed")
+	}
+	if err := m.DeleteFlag(ctx, FlagRedisCache); err != nil {
+		t.Fatalf("failed to delete flag: %v", err)
+	}
+	if m.IsEnabled(FlagRedisCache) {
+		t.Error("expected file flag after deleting API override")
+	}
+	if result := p.Check(ctx); result.Status != health.StatusHealthy {
+		t.Errorf("expected healthy after successful reload, got %s", result.Status)
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}
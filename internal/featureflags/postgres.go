@@ -0,0 +1,195 @@
+package featureflags
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+// Channel канал Postgres LISTEN/NOTIFY об изменении флагов; payload - ключ флага
+const Channel = "feature_flags"
+
+// PostgresStore хранит флаги в таблице feature_flags и уведомляет реплики
+// об изменениях через pg_notify в той же транзакции
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore создает хранилище флагов
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// LoadFlags возвращает все сохраненные флаги
+func (s *PostgresStore) LoadFlags(ctx context.Context) ([]*Flag, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT key, enabled, description, rollout, metadata, rules, variants, default_variant, updated_at
+		FROM feature_flags`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []*Flag
+	for rows.Next() {
+		flag := &Flag{}
+		var rollout, metadata, rules, variants []byte
+		err := rows.Scan(&flag.Key, &flag.Enabled, &flag.Description, &rollout, &metadata,
+			&rules, &variants, &flag.DefaultVariant, &flag.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		columns := []struct {
+			name string
+			data []byte
+			dest interface{}
+		}{
+			{"rollout", rollout, &flag.Rollout},
+			{"metadata", metadata, &flag.Metadata},
+			{"rules", rules, &flag.Rules},
+			{"variants", variants, &flag.Variants},
+		}
+		for _, column := range columns {
+			if len(column.data) == 0 {
+				continue
+			}
+			if err := json.Unmarshal(column.data, column.dest); err != nil {
+				return nil, fmt.Errorf("invalid %s of flag %s: %w", column.name, flag.Key, err)
+			}
+		}
+		flags = append(flags, flag)
+	}
+	return flags, rows.Err()
+}
+
+// SaveFlag создает или обновляет флаг
+func (s *PostgresStore) SaveFlag(ctx context.Context, flag *Flag) error {
+	rollout, err := json.Marshal(flag.Rollout)
+	if err != nil {
+		return err
+	}
+	metadata, err := json.Marshal(flag.Metadata)
+	if err != nil {
+		return err
+	}
+	rules, err := json.Marshal(flag.Rules)
+	if err != nil {
+		return err
+	}
+	variants, err := json.Marshal(flag.Variants)
+	if err != nil {
+		return err
+	}
+
+	return s.notifyInTx(ctx, flag.Key, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO feature_flags (
+				key, enabled, description, rollout, metadata, rules, variants, default_variant, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (key) DO UPDATE SET
+				enabled = EXCLUDED.enabled,
+				description = EXCLUDED.description,
+				rollout = EXCLUDED.rollout,
+				metadata = EXCLUDED.metadata,
+				rules = EXCLUDED.rules,
+				variants = EXCLUDED.variants,
+				default_variant = EXCLUDED.default_variant,
+				updated_at = EXCLUDED.updated_at`,
+			flag.Key, flag.Enabled, flag.Description, rollout, metadata, rules, variants,
+			flag.DefaultVariant, flag.UpdatedAt)
+		return err
+	})
+}
+
+// DeleteFlag удаляет флаг
+func (s *PostgresStore) DeleteFlag(ctx context.Context, key string) (bool, error) {
+	var deleted bool
+	err := s.notifyInTx(ctx, key, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `DELETE FROM feature_flags WHERE key = $1`, key)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		deleted = affected > 0
+		return err
+	})
+	return deleted, err
+}
+
+// notifyInTx выполняет изменение и NOTIFY в одной транзакции: уведомление
+// уходит только после commit
+func (s *PostgresStore) notifyInTx(ctx context.Context, key string, change func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := change(tx); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT pg_notify($1, $2)`, Channel, key); err != nil {
+		return fmt.Errorf("failed to notify flag change: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Listener перезагружает флаги при изменении на любой реплике
+type Listener struct {
+	dsn     string
+	manager *Manager
+	logger  *logger.Logger
+}
+
+// NewListener создает listener изменений флагов
+func NewListener(dsn string, manager *Manager, log *logger.Logger) *Listener {
+	return &Listener{
+		dsn:     dsn,
+		manager: manager,
+		logger:  log,
+	}
+}
+
+// Run слушает канал до отмены контекста
+func (l *Listener) Run(ctx context.Context) error {
+	listener := pq.NewListener(l.dsn, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			l.logger.Warnw("Feature flags listener connection event", "event", ev, "error", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(Channel); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", Channel, err)
+	}
+	l.logger.Infow("Feature flags listener started", "channel", Channel)
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			// n == nil - соединение восстановлено и уведомления могли потеряться:
+			// в обоих случаях флаги перечитываются целиком
+			if n != nil {
+				l.logger.Debugw("Feature flag changed", "key", n.Extra)
+			}
+			if err := l.manager.Reload(ctx); err != nil {
+				l.logger.Errorw("Failed to reload feature flags", "error", err)
+			}
+		case <-ping.C:
+			go func() {
+				_ = listener.Ping()
+			}()
+		}
+	}
+}
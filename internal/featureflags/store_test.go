@@ -0,0 +1,145 @@
+package featureflags
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/user/pr-reviewer/internal/cache"
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+// memoryStore хранилище флагов для тестов; общее у нескольких Manager,
+// как таблица feature_flags у реплик
+type memoryStore struct {
+	flags map[string]*Flag
+}
+
+func (s *memoryStore) LoadFlags(context.Context) ([]*Flag, error) {
+	flags := make([]*Flag, 0, len(s.flags))
+	for _, flag := range s.flags {
+		flags = append(flags, flag)
+	}
+	return flags, nil
+}
+
+func (s *memoryStore) SaveFlag(_ context.Context, flag *Flag) error {
+	s.flags[flag.Key] = flag
+	return nil
+}
+
+func (s *memoryStore) DeleteFlag(_ context.Context, key string) (bool, error) {
+	_, exists := s.flags[key]
+	delete(s.flags, key)
+	return exists, nil
+}
+
+func newStoredManager(t *testing.T, store Store) *Manager {
+	t.Helper()
+	log, _ := logger.New("error", "test")
+	m := NewManager(cache.NewNoOpCache(), log)
+	m.SetStore(store)
+	if err := m.Reload(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return m
+}
+
+func TestManager_StoredFlagsOverrideDefaults(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryStore{flags: map[string]*Flag{}}
+	replica1 := newStoredManager(t, store)
+	replica2 := newStoredManager(t, store)
+
+	if err := replica1.SaveFlag(ctx, &Flag{Key: FlagAuditLog, Enabled: false}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replica1.IsEnabled(FlagAuditLog) {
+		t.Error("expected audit_log to be disabled on replica 1")
+	}
+
+	// Другая реплика получает изменение после уведомления
+	if err := replica2.Reload(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replica2.IsEnabled(FlagAuditLog) {
+		t.Error("expected audit_log to be disabled on replica 2 after reload")
+	}
+
+	// Изменение значения по умолчанию не отменяет сохраненный флаг
+	_ = replica2.EnableFlag(FlagAuditLog)
+	if replica2.IsEnabled(FlagAuditLog) {
+		t.Error("expected stored flag to take precedence over default")
+	}
+
+	// Удаление возвращает значение по умолчанию
+	if err := replica1.DeleteFlag(ctx, FlagAuditLog); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !replica1.IsEnabled(FlagAuditLog) {
+		t.Error("expected audit_log to return to default after delete")
+	}
+	_ = replica2.Reload(ctx)
+	if !replica2.IsEnabled(FlagAuditLog) {
+		t.Error("expected audit_log default on replica 2 after reload")
+	}
+}
+
+func TestManager_DeleteFlag(t *testing.T) {
+	ctx := context.Background()
+	m := newStoredManager(t, &memoryStore{flags: map[string]*Flag{}})
+
+	if err := m.DeleteFlag(ctx, FlagAuditLog); !errors.Is(err, ErrFlagNotFound) {
+		t.Errorf("expected ErrFlagNotFound for flag without override, got %v", err)
+	}
+
+	_ = m.SaveFlag(ctx, &Flag{Key: "new_dashboard", Enabled: true})
+	if err := m.DeleteFlag(ctx, "new_dashboard"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, exists := m.GetFlag("new_dashboard"); exists {
+		t.Error("expected custom flag to be removed")
+	}
+}
+
+func TestManager_ProtectedFlag(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryStore{flags: map[string]*Flag{
+		FlagJWTAuth: {Key: FlagJWTAuth, Enabled: false},
+	}}
+	m := newStoredManager(t, store)
+	_ = m.EnableFlag(FlagJWTAuth)
+
+	if !m.IsEnabled(FlagJWTAuth) {
+		t.Error("expected stored override of jwt_auth to be ignored")
+	}
+	if err := m.SaveFlag(ctx, &Flag{Key: FlagJWTAuth, Enabled: false}); !errors.Is(err, ErrProtectedFlag) {
+		t.Errorf("expected ErrProtectedFlag on save, got %v", err)
+	}
+	if err := m.DeleteFlag(ctx, FlagJWTAuth); !errors.Is(err, ErrProtectedFlag) {
+		t.Errorf("expected ErrProtectedFlag on delete, got %v", err)
+	}
+	if !m.IsEnabled(FlagJWTAuth) {
+		t.Error("expected jwt_auth to remain enabled")
+	}
+}
+
+func TestValidateFlag(t *testing.T) {
+	tests := []struct {
+		flag  *Flag
+		valid bool
+	}{
+		{&Flag{Key: "new_dashboard"}, true},
+		{&Flag{Key: "ui.v2-beta"}, true},
+		{&Flag{Key: ""}, false},
+		{&Flag{Key: "Bad Key"}, false},
+		{&Flag{Key: "ok", Rollout: &Rollout{Percentage: 100}}, true},
+		{&Flag{Key: "ok", Rollout: &Rollout{Percentage: 101}}, false},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateFlag(tt.flag); (err == nil) != tt.valid {
+			t.Errorf("key %q: expected valid=%v, got %v", tt.flag.Key, tt.valid, err)
+		}
+	}
+}
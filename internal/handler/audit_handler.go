@@ -0,0 +1,275 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/audit"
+	"github.com/user/pr-reviewer/internal/auth"
+	"github.com/user/pr-reviewer/internal/featureflags"
+	"github.com/user/pr-reviewer/internal/middleware"
+	"github.com/user/pr-reviewer/internal/models"
+)
+
+// auditEnabled проверяет, что audit log подключен и включен флагом audit_log.
+// Флаг проверяется на каждом запросе, чтобы его можно было переключить без рестарта.
+func (h *Handler) auditEnabled() bool {
+	return h.audit != nil && (h.flags == nil || h.flags.IsEnabled(featureflags.FlagAuditLog))
+}
+
+// auditSnapshot загружает состояние сущности до изменения. Если audit log
+// выключен, запрос в БД не выполняется.
+func (h *Handler) auditSnapshot(load func() (interface{}, error)) interface{} {
+	if !h.auditEnabled() {
+		return nil
+	}
+	entity, err := load()
+	if err != nil {
+		return nil
+	}
+	return entity
+}
+
+// recordAudit записывает изменение сущности: автор из контекста аутентификации
+// (пользователь или сервисный аккаунт), IP, request ID и изменения до/после
+func (h *Handler) recordAudit(r *http.Request, action audit.Action, entity audit.Entity, entityID int, changes map[string]interface{}, description string) {
+	if !h.auditEnabled() {
+		return
+	}
+	h.writeAudit(r, action, entity, entityID, changes, description)
+}
+
+// writeAudit записывает изменение независимо от флага audit_log
+func (h *Handler) writeAudit(r *http.Request, action audit.Action, entity audit.Entity, entityID int, changes map[string]interface{}, description string) {
+	ctx := r.Context()
+	entry := &audit.Entry{
+		Action:      action,
+		Entity:      entity,
+		EntityID:    int64(entityID),
+		IP:          h.clientIP(r),
+		UserAgent:   r.UserAgent(),
+		Changes:     changes,
+		Description: description,
+	}
+	entry.UserID, _ = auth.GetUserID(ctx)
+	entry.UserEmail, _ = auth.GetUserEmail(ctx)
+	entry.ServiceAccountID, _ = auth.GetServiceAccountID(ctx)
+	entry.RequestID, _ = ctx.Value(middleware.RequestIDKey).(string)
+
+	h.logAudit(func(ctx context.Context) error {
+		return h.audit.Log(ctx, entry)
+	})
+}
+
+// recordUserAudit записывает изменение пользователя, загружая его текущее состояние
+func (h *Handler) recordUserAudit(r *http.Request, userID int, before interface{}, description string) {
+	if !h.auditEnabled() {
+		return
+	}
+	after := h.auditSnapshot(func() (interface{}, error) { return h.service.GetUser(userID) })
+	changes := audit.Diff(before, after)
+	if len(changes) == 0 {
+		// Например, деактивация уже неактивного пользователя
+		return
+	}
+	h.recordAudit(r, audit.ActionUpdate, audit.EntityUser, userID, changes, description)
+}
+
+// recordReviewerAudit записывает изменение рецензентов PR. Сущность - рецензент,
+// изменения - разница PR до/после.
+func (h *Handler) recordReviewerAudit(r *http.Request, action audit.Action, reviewerID int, before interface{}, after *models.PullRequest, description string) {
+	if !h.auditEnabled() {
+		return
+	}
+	changes := audit.Diff(before, after)
+	changes["pullRequestId"] = after.ID
+	h.recordAudit(r, action, audit.EntityReviewer, reviewerID, changes, description)
+}
+
+// auditPullRequest выполняет изменение статуса PR и записывает его в audit log
+func (h *Handler) auditPullRequest(r *http.Request, prID int, description string, update func(int) (*models.PullRequest, error)) (*models.PullRequest, error) {
+	before := h.auditSnapshot(func() (interface{}, error) { return h.service.GetPullRequest(prID) })
+
+	pr, err := update(prID)
+	if err != nil {
+		return nil, err
+	}
+
+	h.recordAudit(r, audit.ActionUpdate, audit.EntityPullRequest, prID, audit.Diff(before, pr), description)
+	return pr, nil
+}
+
+// auditFlushEvery через сколько записей выгрузки сбрасывать ответ клиенту
+const auditFlushEvery = 500
+
+// GetAuditLog возвращает страницу audit log или выгружает все подходящие
+// записи в CSV/NDJSON (параметр format или заголовок Accept)
+func (h *Handler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, err := h.auditFilter(r)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format := auditExportFormat(r)
+	if format == "" {
+		page, err := h.audit.Query(r.Context(), filter)
+		if err != nil {
+			if errors.Is(err, audit.ErrInvalidCursor) {
+				h.sendError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			h.sendError(w, http.StatusInternalServerError, "Failed to query audit log")
+			return
+		}
+		h.sendJSON(w, http.StatusOK, page)
+		return
+	}
+
+	h.exportAuditLog(w, r, filter, format)
+}
+
+// exportAuditLog потоково выгружает записи. После начала ответа ошибку уже
+// нельзя вернуть статусом, поэтому выгрузка обрывается и ошибка логируется.
+func (h *Handler) exportAuditLog(w http.ResponseWriter, r *http.Request, filter audit.Filter, format string) {
+	if filter.Cursor != "" {
+		h.sendError(w, http.StatusBadRequest, "cursor is not supported for export")
+		return
+	}
+
+	writer, err := audit.NewEntryWriter(format, w)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	contentType := "application/x-ndjson"
+	if format == audit.FormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	filename := fmt.Sprintf("audit-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	// Выгрузка живет дольше WriteTimeout сервера. ResponseController находит
+	// Flusher через Unwrap оберток middleware и tracing.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.WriteHeader(http.StatusOK)
+
+	written := 0
+	err = h.audit.Export(r.Context(), filter, func(entry *audit.Entry) error {
+		if err := writer.Write(entry); err != nil {
+			return err
+		}
+		written++
+		if written%auditFlushEvery == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		h.logf("Audit log export interrupted after %d entries: %v", written, err)
+	}
+}
+
+// VerifyAuditLog проверяет цепочку хешей audit log. Нарушение цепочки -
+// результат проверки, а не ошибка запроса, поэтому ответ всегда 200.
+func (h *Handler) VerifyAuditLog(w http.ResponseWriter, r *http.Request) {
+	result, err := h.audit.Verify(r.Context())
+	if err != nil {
+		h.logf("Failed to verify audit log: %v", err)
+		h.sendError(w, http.StatusInternalServerError, "Failed to verify audit log")
+		return
+	}
+
+	if !result.Valid {
+		h.logf("Audit log chain is broken at entry %d: %s", result.Broken.EntryID, result.Broken.Reason)
+	}
+	h.sendJSON(w, http.StatusOK, result)
+}
+
+// auditExportFormat определяет формат выгрузки; пустая строка - JSON страница
+func auditExportFormat(r *http.Request) string {
+	// Явный параметр format важнее заголовка Accept
+	if format := r.URL.Query().Get("format"); format != "" {
+		if format == "json" {
+			return ""
+		}
+		return format
+	}
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "text/csv"):
+		return audit.FormatCSV
+	case strings.Contains(accept, "application/x-ndjson"):
+		return audit.FormatNDJSON
+	}
+	return ""
+}
+
+// auditFilter разбирает параметры запроса audit log
+func (h *Handler) auditFilter(r *http.Request) (audit.Filter, error) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Entity:    audit.Entity(query.Get("entity")),
+		Action:    audit.Action(query.Get("action")),
+		RequestID: query.Get("requestId"),
+		Cursor:    query.Get("cursor"),
+	}
+
+	ids := map[string]*int64{
+		"userId":           &filter.UserID,
+		"serviceAccountId": &filter.ServiceAccountID,
+		"entityId":         &filter.EntityID,
+	}
+	for name, dest := range ids {
+		if v := query.Get(name); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || id <= 0 {
+				return filter, errors.New("invalid " + name)
+			}
+			*dest = id
+		}
+	}
+
+	// created_at хранится в UTC без зоны (TIMESTAMP): смещение из запроса
+	// Postgres отбросил бы, поэтому время переводится в UTC
+	if v := query.Get("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, errors.New("invalid from, expected RFC3339")
+		}
+		filter.From = from.UTC()
+	}
+
+	if v := query.Get("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, errors.New("invalid to, expected RFC3339")
+		}
+		filter.To = to.UTC()
+	}
+
+	if limit, err := h.getIntQuery(r, "limit"); err == nil {
+		if limit <= 0 || limit > audit.MaxLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", audit.MaxLimit)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
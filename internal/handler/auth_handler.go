@@ -0,0 +1,318 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/audit"
+	"github.com/user/pr-reviewer/internal/auth"
+	"github.com/user/pr-reviewer/internal/models"
+	"github.com/user/pr-reviewer/internal/service"
+)
+
+// SetJWTAuth включает эндпоинты аутентификации и WebSocket API
+func (h *Handler) SetJWTAuth(jwtAuth *auth.JWTAuth) {
+	h.jwtAuth = jwtAuth
+}
+
+// SetTrustedProxies задает адреса или CIDR прокси, от которых принимаются
+// X-Forwarded-For и X-Real-IP. Без них IP клиента берется из соединения.
+func (h *Handler) SetTrustedProxies(proxies []string) error {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		cidr := proxy
+		if !strings.Contains(cidr, "/") {
+			// Отдельный адрес - сеть из одного адреса
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		nets = append(nets, network)
+	}
+	h.trustedProxies = nets
+	return nil
+}
+
+// SetAuditLogger подключает audit log
+func (h *Handler) SetAuditLogger(logger *audit.Logger) {
+	h.audit = logger
+}
+
+// Login проверяет логин и пароль и выдает access и refresh токены
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Username == "" || req.Password == "" {
+		h.sendError(w, http.StatusBadRequest, "Username and password are required")
+		return
+	}
+
+	ip, userAgent := h.clientIP(r), r.UserAgent()
+
+	user, err := h.service.Authenticate(req.Username, req.Password)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			var userID int64
+			if user != nil {
+				userID = int64(user.ID)
+			}
+			h.logAudit(func(ctx context.Context) error {
+				return h.audit.LogLogin(ctx, userID, req.Username, false, ip, userAgent)
+			})
+			h.sendError(w, http.StatusUnauthorized, "Invalid username or password")
+		} else {
+			h.sendError(w, http.StatusInternalServerError, "Failed to authenticate")
+		}
+		return
+	}
+
+	refreshToken, err := h.service.IssueRefreshToken(user.ID, userAgent, ip)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to issue refresh token")
+		return
+	}
+
+	h.logAudit(func(ctx context.Context) error {
+		return h.audit.LogLogin(ctx, int64(user.ID), user.Username, true, ip, userAgent)
+	})
+	h.sendTokens(w, user, refreshToken)
+}
+
+// RefreshToken обменивает refresh токен на новую пару токенов
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, refreshToken, err := h.service.RotateRefreshToken(req.RefreshToken, r.UserAgent(), h.clientIP(r))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			h.sendError(w, http.StatusUnauthorized, "Invalid refresh token")
+		} else {
+			h.sendError(w, http.StatusInternalServerError, "Failed to refresh token")
+		}
+		return
+	}
+
+	h.sendTokens(w, user, refreshToken)
+}
+
+// Logout завершает сессию: отзывает refresh токен и access токен запроса
+// (по jti до истечения его срока)
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, err := h.jwtAuth.AuthenticateRequest(r)
+	if err != nil {
+		h.sendError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	var req models.RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Неизвестный refresh токен не считается ошибкой: сессия и так не активна
+	if _, err := h.service.RevokeRefreshToken(req.RefreshToken); err != nil && !errors.Is(err, service.ErrInvalidRefreshToken) {
+		h.sendError(w, http.StatusInternalServerError, "Failed to logout")
+		return
+	}
+
+	if revocations := h.jwtAuth.Revocations(); revocations != nil && claims.ID != "" && claims.ExpiresAt != nil {
+		if err := revocations.RevokeTokenUntil(r.Context(), claims.ID, claims.ExpiresAt.Time); err != nil {
+			h.sendError(w, http.StatusInternalServerError, "Failed to logout")
+			return
+		}
+	}
+
+	ip, userAgent := h.clientIP(r), r.UserAgent()
+	h.logAudit(func(ctx context.Context) error {
+		return h.audit.LogLogout(ctx, claims.UserID, ip, userAgent)
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeTokens отзывает access токены до истечения срока: один токен по ID
+// (claim jti) или все токены пользователя вместе с его refresh токенами
+func (h *Handler) RevokeTokens(w http.ResponseWriter, r *http.Request) {
+	var req models.RevokeTokensRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if (req.TokenID == "") == (req.UserID == nil) {
+		h.sendError(w, http.StatusBadRequest, "Exactly one of tokenId or userId is required")
+		return
+	}
+
+	revocations := h.jwtAuth.Revocations()
+	if req.TokenID != "" {
+		if err := revocations.RevokeToken(r.Context(), req.TokenID); err != nil {
+			h.sendError(w, http.StatusInternalServerError, "Failed to revoke token")
+			return
+		}
+		h.recordAudit(r, audit.ActionUpdate, audit.EntityUser, 0,
+			map[string]interface{}{"tokenId": req.TokenID}, "Access token revoked")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Сначала отзываются refresh токены, иначе пользователь сразу получит новый access токен
+	sessions, err := h.service.RevokeUserSessions(*req.UserID)
+	if err != nil {
+		if err.Error() == errUserNotFound {
+			h.sendError(w, http.StatusNotFound, "User not found")
+		} else {
+			h.sendError(w, http.StatusInternalServerError, "Failed to revoke sessions")
+		}
+		return
+	}
+	if err := revocations.RevokeUser(r.Context(), int64(*req.UserID), time.Now()); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to revoke tokens")
+		return
+	}
+	h.recordAudit(r, audit.ActionUpdate, audit.EntityUser, *req.UserID,
+		map[string]interface{}{"refreshTokensRevoked": sessions}, "All user tokens revoked")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// JWKS отдает публичные ключи подписи access токенов (RFC 7517), в том числе
+// выведенные из работы ключи, токены которых еще действительны
+func (h *Handler) JWKS(w http.ResponseWriter, _ *http.Request) {
+	// Короткий кеш: новый ключ после ротации сразу подписывает токены
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	h.sendJSON(w, http.StatusOK, h.jwtAuth.KeyRing().JWKS())
+}
+
+// GetCurrentUser возвращает пользователя текущего access токена
+func (h *Handler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.service.GetUser(userID)
+	if err != nil {
+		if err.Error() == errUserNotFound {
+			h.sendError(w, http.StatusNotFound, "User not found")
+		} else {
+			h.sendError(w, http.StatusInternalServerError, "Failed to get user")
+		}
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, user)
+}
+
+// sendTokens выпускает access токен и отправляет пару токенов клиенту
+func (h *Handler) sendTokens(w http.ResponseWriter, user *models.User, refreshToken string) {
+	response, err := h.issueTokens(user, refreshToken)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to issue access token")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.sendJSON(w, http.StatusOK, response)
+}
+
+// issueTokens выпускает access токен для пользователя
+func (h *Handler) issueTokens(user *models.User, refreshToken string) (*models.AuthResponse, error) {
+	var teamID int64
+	if user.TeamID != nil {
+		teamID = int64(*user.TeamID)
+	}
+
+	accessToken, err := h.jwtAuth.GenerateToken(int64(user.ID), user.Email, user.Role, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(h.jwtAuth.TokenExpiration().Seconds()),
+		User:         user,
+	}, nil
+}
+
+// auditWriteTimeout ограничивает запись в audit log: запись ждет блокировку
+// головы цепочки, общую для всех реплик, и не должна задерживать запрос
+const auditWriteTimeout = 2 * time.Second
+
+// logAudit записывает действие в audit log, если он подключен и включен
+// флагом audit_log. Ошибка или таймаут записи не влияют на ответ пользователю.
+func (h *Handler) logAudit(write func(ctx context.Context) error) {
+	if !h.auditEnabled() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), auditWriteTimeout)
+	defer cancel()
+	if err := write(ctx); err != nil {
+		h.logf("Failed to write audit log: %v", err)
+	}
+}
+
+// clientIP возвращает IP клиента. Заголовки прокси учитываются, только если
+// соединение пришло от доверенного прокси: иначе клиент мог бы подставить
+// любой адрес. X-Forwarded-For разбирается справа налево до первого адреса,
+// не принадлежащего доверенным прокси.
+func (h *Handler) clientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if !h.trustedProxy(net.ParseIP(remote)) {
+		return remote
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			if i == 0 || !h.trustedProxy(ip) {
+				return ip.String()
+			}
+		}
+	}
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return remote
+}
+
+// trustedProxy проверяет, что адрес принадлежит доверенному прокси
+func (h *Handler) trustedProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range h.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/auth"
+	"github.com/user/pr-reviewer/internal/stream"
+)
+
+// streamHeartbeat интервал комментариев-пингов, чтобы прокси не закрывали соединение
+const streamHeartbeat = 15 * time.Second
+
+// SetEventBroker включает live stream событий PR
+func (h *Handler) SetEventBroker(broker *stream.Broker) {
+	h.events = broker
+}
+
+// SetWebSocketServer включает WebSocket API событий PR
+func (h *Handler) SetWebSocketServer(ws *stream.WebSocketServer) {
+	h.ws = ws
+}
+
+// StreamEvents отдает события PR и рецензентов через Server-Sent Events.
+// Поддерживает фильтры teamId и userId и возобновление по Last-Event-ID.
+// Не admin получает события только своей команды и свои (см. authorizeStream).
+func (h *Handler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	var filter stream.Filter
+	if teamID, err := h.getIntQuery(r, "teamId"); err == nil {
+		filter.TeamID = int64(teamID)
+	} else if err != http.ErrNotSupported {
+		h.sendError(w, http.StatusBadRequest, "Invalid teamId")
+		return
+	}
+	if userID, err := h.getIntQuery(r, "userId"); err == nil {
+		filter.UserID = int64(userID)
+	} else if err != http.ErrNotSupported {
+		h.sendError(w, http.StatusBadRequest, "Invalid userId")
+		return
+	}
+
+	if !h.authorizeStream(w, r, &filter) {
+		return
+	}
+
+	lastID, err := lastEventID(r)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid Last-Event-ID")
+		return
+	}
+
+	// Стрим живет дольше WriteTimeout сервера
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	sub, replay, resumed := h.events.Subscribe(filter, lastID)
+	defer h.events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Событие вытеснено из буфера: клиент должен заново загрузить состояние
+	if !resumed {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range replay {
+		if err := writeStreamEvent(w, event); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		h.logf("Streaming not supported: %v", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := writeStreamEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// StreamWebSocket открывает WebSocket канал, в котором клиент подписывается
+// на темы team:N, pr:N и user:me. Токен передается в заголовке Authorization
+// или подпротоколом (см. auth.WebSocketProtocol). Не admin получает события
+// только своей команды и свои, как и в StreamEvents.
+func (h *Handler) StreamWebSocket(w http.ResponseWriter, r *http.Request) {
+	claims, err := h.jwtAuth.AuthenticateRequest(r)
+	if err != nil {
+		h.sendError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+	access := stream.Access{
+		UserID: claims.UserID,
+		TeamID: claims.TeamID,
+		Admin:  claims.Role == auth.RoleAdmin,
+	}
+
+	lastID, err := lastEventID(r)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid lastEventId")
+		return
+	}
+
+	if err := h.ws.Serve(w, r, access, lastID); err != nil {
+		h.logf("WebSocket connection failed: %v", err)
+	}
+}
+
+// authorizeStream ограничивает фильтр live stream правами вызывающего.
+// Аутентификация требуется независимо от флага jwt_auth: стрим отдает события
+// всех команд. Admin видит любые события, остальные - только своей команды
+// (claim team_id) и события со своим участием; без фильтров подставляется
+// своя команда. При отказе отправляет 401/403 и возвращает false.
+func (h *Handler) authorizeStream(w http.ResponseWriter, r *http.Request, filter *stream.Filter) bool {
+	if h.jwtAuth == nil {
+		return true
+	}
+	role, ok := auth.GetUserRole(r.Context())
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "Authentication required")
+		return false
+	}
+	if role == auth.RoleAdmin {
+		return true
+	}
+
+	callerID, _ := auth.GetUserID(r.Context())
+	callerTeamID, _ := auth.GetTeamID(r.Context())
+	if filter.UserID != 0 && filter.UserID != callerID {
+		h.sendError(w, http.StatusForbidden, "Forbidden: only your own events can be streamed")
+		return false
+	}
+	if filter.TeamID != 0 && filter.TeamID != callerTeamID {
+		h.sendError(w, http.StatusForbidden,
+			fmt.Sprintf("Forbidden: team %d is not your team", filter.TeamID))
+		return false
+	}
+
+	if filter.TeamID == 0 && filter.UserID == 0 {
+		switch {
+		case callerTeamID != 0:
+			filter.TeamID = callerTeamID
+		case callerID != 0:
+			filter.UserID = callerID
+		default:
+			h.sendError(w, http.StatusForbidden, "Forbidden: you are not a member of any team")
+			return false
+		}
+	}
+	return true
+}
+
+// lastEventID возвращает ID последнего полученного клиентом события
+// из заголовка Last-Event-ID или параметра lastEventId
+func lastEventID(r *http.Request) (int64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("lastEventId")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// writeStreamEvent записывает событие в формате text/event-stream
+func writeStreamEvent(w http.ResponseWriter, event *stream.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+	"github.com/user/pr-reviewer/internal/audit"
+	"github.com/user/pr-reviewer/internal/featureflags"
+)
+
+// PutFeatureFlagRequest запрос на установку флага. Флаг заменяется целиком.
+type PutFeatureFlagRequest struct {
+	Enabled     *bool                  `json:"enabled"`
+	Description string                 `json:"description"`
+	Rollout     *featureflags.Rollout  `json:"rollout,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+
+	Rules          []*featureflags.Rule       `json:"rules,omitempty"`
+	Variants       map[string]json.RawMessage `json:"variants,omitempty"`
+	DefaultVariant string                     `json:"default_variant,omitempty"`
+}
+
+// GetFeatureFlags возвращает все флаги, отсортированные по ключу
+func (h *Handler) GetFeatureFlags(w http.ResponseWriter, _ *http.Request) {
+	all := h.flags.GetAllFlags()
+	flags := make([]*featureflags.Flag, 0, len(all))
+	for _, flag := range all {
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Key < flags[j].Key })
+
+	h.sendJSON(w, http.StatusOK, flags)
+}
+
+// GetFeatureFlag возвращает флаг по ключу
+func (h *Handler) GetFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	flag, exists := h.flags.GetFlag(mux.Vars(r)["key"])
+	if !exists {
+		h.sendError(w, http.StatusNotFound, "Feature flag not found")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, flag)
+}
+
+// PutFeatureFlag создает или заменяет флаг. Изменение сохраняется в БД и
+// доходит до всех реплик.
+func (h *Handler) PutFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	var req PutFeatureFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Enabled == nil {
+		h.sendError(w, http.StatusBadRequest, "enabled is required")
+		return
+	}
+
+	key := mux.Vars(r)["key"]
+	if featureflags.IsProtected(key) {
+		h.sendError(w, http.StatusForbidden, featureflags.ErrProtectedFlag.Error())
+		return
+	}
+	before, existed := h.flags.GetFlag(key)
+	flag := &featureflags.Flag{
+		Key:         key,
+		Enabled:     *req.Enabled,
+		Description: req.Description,
+		Rollout:     req.Rollout,
+		Metadata:    req.Metadata,
+
+		Rules:          req.Rules,
+		Variants:       req.Variants,
+		DefaultVariant: req.DefaultVariant,
+	}
+	if err := featureflags.ValidateFlag(flag); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.flags.SaveFlag(r.Context(), flag); err != nil {
+		h.logf("Failed to save feature flag %s: %v", key, err)
+		h.sendError(w, http.StatusInternalServerError, "Failed to save feature flag")
+		return
+	}
+
+	action := audit.ActionUpdate
+	if !existed {
+		action = audit.ActionCreate
+	}
+	h.recordFlagAudit(r, action, key, before, flag, "Feature flag "+key+" set")
+	h.sendJSON(w, http.StatusOK, flag)
+}
+
+// DeleteFeatureFlag удаляет флаг, заданный через API. Встроенный флаг
+// возвращается к значению по умолчанию.
+func (h *Handler) DeleteFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	if featureflags.IsProtected(key) {
+		h.sendError(w, http.StatusForbidden, featureflags.ErrProtectedFlag.Error())
+		return
+	}
+	before, _ := h.flags.GetFlag(key)
+
+	if err := h.flags.DeleteFlag(r.Context(), key); err != nil {
+		if errors.Is(err, featureflags.ErrFlagNotFound) {
+			h.sendError(w, http.StatusNotFound, "Feature flag is not set via API")
+			return
+		}
+		h.logf("Failed to delete feature flag %s: %v", key, err)
+		h.sendError(w, http.StatusInternalServerError, "Failed to delete feature flag")
+		return
+	}
+
+	after, _ := h.flags.GetFlag(key)
+	h.recordFlagAudit(r, audit.ActionDelete, key, before, after, "Feature flag "+key+" deleted")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// recordFlagAudit записывает изменение флага. Изменения флагов пишутся
+// и при выключенном audit_log, иначе его выключение не оставило бы следа.
+// У флага нет числового ID, поэтому ключ передается в changes.
+func (h *Handler) recordFlagAudit(r *http.Request, action audit.Action, key string, before, after *featureflags.Flag, description string) {
+	if h.audit == nil {
+		return
+	}
+	changes := audit.Diff(before, after)
+	changes["key"] = key
+	h.writeAudit(r, action, audit.EntityFeatureFlag, 0, changes, description)
+}
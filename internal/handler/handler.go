@@ -3,14 +3,20 @@ package handler
 import (
 	"encoding/json"
 	"log"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/user/pr-reviewer/internal/audit"
+	"github.com/user/pr-reviewer/internal/auth"
+	"github.com/user/pr-reviewer/internal/featureflags"
 	"github.com/user/pr-reviewer/internal/logger"
 	"github.com/user/pr-reviewer/internal/models"
 	"github.com/user/pr-reviewer/internal/service"
+	"github.com/user/pr-reviewer/internal/stream"
+	"github.com/user/pr-reviewer/internal/webhook"
 )
 
 const (
@@ -21,8 +27,21 @@ const (
 
 // Handler обрабатывает HTTP запросы
 type Handler struct {
-	service *service.Service
-	logger  interface{} // Can be either *log.Logger or *logger.Logger
+	service  *service.Service
+	webhooks *webhook.Manager
+	events   *stream.Broker
+	ws       *stream.WebSocketServer
+	jwtAuth  *auth.JWTAuth
+	audit    *audit.Logger
+	flags    *featureflags.Manager
+	oidc     *auth.OIDCProvider
+	logger   interface{} // Can be either *log.Logger or *logger.Logger
+
+	// oidcRedirect адрес фронтенда для возврата после входа через IdP
+	oidcRedirect string
+
+	// trustedProxies сети прокси, которым доверяются X-Forwarded-For и X-Real-IP
+	trustedProxies []*net.IPNet
 }
 
 // New создаёт новый HTTP handler
@@ -49,35 +68,9 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
 	// Middleware
 	router.Use(h.loggingMiddleware)
 
-	// Health check
-	router.HandleFunc("/health", h.HealthCheck).Methods("GET")
-
-	// Teams
-	router.HandleFunc("/teams", h.GetTeams).Methods("GET")
-	router.HandleFunc("/teams", h.CreateTeam).Methods("POST")
-	router.HandleFunc("/teams/{teamId}", h.GetTeam).Methods("GET")
-	router.HandleFunc("/teams/{teamId}", h.DeleteTeam).Methods("DELETE")
-	router.HandleFunc("/teams/{teamId}/users", h.AddUserToTeam).Methods("POST")
-	router.HandleFunc("/teams/{teamId}/users", h.RemoveUserFromTeam).Methods("DELETE")
-	router.HandleFunc("/teams/{teamId}/users/deactivate", h.BulkDeactivateUsers).Methods("POST")
-
-	// Users
-	router.HandleFunc("/users", h.GetUsers).Methods("GET")
-	router.HandleFunc("/users", h.CreateUser).Methods("POST")
-	router.HandleFunc("/users/{userId}", h.GetUser).Methods("GET")
-	router.HandleFunc("/users/{userId}", h.UpdateUser).Methods("PATCH")
-
-	// Pull Requests
-	router.HandleFunc("/pull-requests", h.GetPullRequests).Methods("GET")
-	router.HandleFunc("/pull-requests", h.CreatePullRequest).Methods("POST")
-	router.HandleFunc("/pull-requests/{prId}", h.GetPullRequest).Methods("GET")
-	router.HandleFunc("/pull-requests/{prId}/reviewers", h.AddReviewer).Methods("POST")
-	router.HandleFunc("/pull-requests/{prId}/reviewers", h.ReassignReviewer).Methods("PUT")
-	router.HandleFunc("/pull-requests/{prId}/merge", h.MergePullRequest).Methods("POST")
-	router.HandleFunc("/pull-requests/{prId}/close", h.ClosePullRequest).Methods("POST")
-
-	// Statistics
-	router.HandleFunc("/statistics", h.GetStatistics).Methods("GET")
+	for _, rt := range h.routes() {
+		router.Handle(rt.path, h.authorize(rt)).Methods(rt.method)
+	}
 }
 
 // loggingMiddleware логирует все запросы
@@ -124,6 +117,7 @@ func (h *Handler) CreateTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.recordAudit(r, audit.ActionCreate, audit.EntityTeam, team.ID, audit.Diff(nil, team), "Team created")
 	h.sendJSON(w, http.StatusCreated, team)
 }
 
@@ -142,6 +136,8 @@ func (h *Handler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := h.auditSnapshot(func() (interface{}, error) { return h.service.GetTeam(teamID) })
+
 	if err := h.service.DeleteTeam(teamID); err != nil {
 		if err.Error() == errTeamNotFound {
 			h.sendError(w, http.StatusNotFound, "Team not found")
@@ -151,6 +147,8 @@ func (h *Handler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.recordAudit(r, audit.ActionDelete, audit.EntityTeam, teamID, audit.Diff(before, nil), "Team deleted")
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -170,6 +168,12 @@ func (h *Handler) AddUserToTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.authorizeTeam(w, r, teamID) || !h.authorizeTeamMember(w, r, req.UserID) {
+		return
+	}
+
+	before := h.auditSnapshot(func() (interface{}, error) { return h.service.GetUser(req.UserID) })
+
 	if err := h.service.AddUserToTeam(teamID, req.UserID); err != nil {
 		if err.Error() == "user already in team" {
 			h.sendError(w, http.StatusConflict, err.Error())
@@ -181,6 +185,8 @@ func (h *Handler) AddUserToTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.recordUserAudit(r, req.UserID, before, "User added to team")
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -198,6 +204,12 @@ func (h *Handler) RemoveUserFromTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.authorizeTeam(w, r, teamID) {
+		return
+	}
+
+	before := h.auditSnapshot(func() (interface{}, error) { return h.service.GetUser(userID) })
+
 	if err := h.service.RemoveUserFromTeam(teamID, userID); err != nil {
 		if err.Error() == "user not found in team" {
 			h.sendError(w, http.StatusNotFound, err.Error())
@@ -207,6 +219,8 @@ func (h *Handler) RemoveUserFromTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.recordUserAudit(r, userID, before, "User removed from team")
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -224,12 +238,27 @@ func (h *Handler) BulkDeactivateUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.authorizeTeam(w, r, teamID) {
+		return
+	}
+
+	before := make(map[int]interface{}, len(req.UserIDs))
+	if h.auditEnabled() {
+		for _, userID := range req.UserIDs {
+			before[userID] = h.auditSnapshot(func() (interface{}, error) { return h.service.GetUser(userID) })
+		}
+	}
+
 	response, err := h.service.BulkDeactivateUsers(teamID, &req)
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, "Failed to deactivate users")
 		return
 	}
 
+	for userID, snapshot := range before {
+		h.recordUserAudit(r, userID, snapshot, "User deactivated")
+	}
+
 	h.sendJSON(w, http.StatusOK, response)
 }
 
@@ -259,8 +288,17 @@ func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateUserRequest
 	h.handleCreateEntity(w, r, &req, func() (interface{}, error) {
-		return h.service.CreateUser(&req)
-	}, map[string]int{"not found": http.StatusNotFound})
+		user, err := h.service.CreateUser(&req)
+		if err != nil {
+			return nil, err
+		}
+		h.recordAudit(r, audit.ActionCreate, audit.EntityUser, user.ID, audit.Diff(nil, user), "User created")
+		return user, nil
+	}, map[string]int{
+		"not found":     http.StatusNotFound,
+		"invalid role":  http.StatusBadRequest,
+		"password must": http.StatusBadRequest,
+	})
 }
 
 // GetUser возвращает пользователя по ID
@@ -284,19 +322,75 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := h.auditSnapshot(func() (interface{}, error) { return h.service.GetUser(userID) })
+
 	user, err := h.service.UpdateUser(userID, &req)
 	if err != nil {
 		if err.Error() == errUserNotFound {
 			h.sendError(w, http.StatusNotFound, "User not found")
+		} else if strings.HasPrefix(err.Error(), "invalid role") || err == auth.ErrPasswordTooShort {
+			h.sendError(w, http.StatusBadRequest, err.Error())
 		} else {
 			h.sendError(w, http.StatusInternalServerError, "Failed to update user")
 		}
 		return
 	}
 
+	changes := audit.Diff(before, user)
+	if req.Password != nil {
+		// Хеш пароля не попадает в JSON пользователя, отмечаем только факт смены
+		changes["password"] = map[string]interface{}{"changed": true}
+	}
+	h.recordAudit(r, audit.ActionUpdate, audit.EntityUser, userID, changes, "User updated")
 	h.sendJSON(w, http.StatusOK, user)
 }
 
+// GetNotificationPreferences возвращает настройки уведомлений пользователя
+func (h *Handler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	// Некорректный ID обработает handleGetByID
+	if userID, err := h.getIntParam(r, "userId"); err == nil && !h.authorizeSelf(w, r, userID) {
+		return
+	}
+
+	h.handleGetByID(w, r, "userId", func(id int) (interface{}, error) {
+		return h.service.GetNotificationPreferences(id)
+	}, "User not found")
+}
+
+// UpdateNotificationPreferences обновляет настройки уведомлений пользователя
+func (h *Handler) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getIntParam(r, "userId")
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if !h.authorizeSelf(w, r, userID) {
+		return
+	}
+
+	var req models.UpdateNotificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	before := h.auditSnapshot(func() (interface{}, error) { return h.service.GetNotificationPreferences(userID) })
+
+	prefs, err := h.service.UpdateNotificationPreferences(userID, &req)
+	if err != nil {
+		if err.Error() == errUserNotFound {
+			h.sendError(w, http.StatusNotFound, "User not found")
+		} else {
+			h.sendError(w, http.StatusInternalServerError, "Failed to update notification preferences")
+		}
+		return
+	}
+
+	h.recordAudit(r, audit.ActionUpdate, audit.EntityUser, userID, audit.Diff(before, prefs),
+		"Notification preferences updated")
+	h.sendJSON(w, http.StatusOK, prefs)
+}
+
 // GetPullRequests возвращает все PR
 func (h *Handler) GetPullRequests(w http.ResponseWriter, r *http.Request) {
 	var userID *int
@@ -330,7 +424,12 @@ func (h *Handler) GetPullRequests(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) CreatePullRequest(w http.ResponseWriter, r *http.Request) {
 	var req models.CreatePullRequestRequest
 	h.handleCreateEntity(w, r, &req, func() (interface{}, error) {
-		return h.service.CreatePullRequest(&req)
+		pr, err := h.service.CreatePullRequest(&req)
+		if err != nil {
+			return nil, err
+		}
+		h.recordAudit(r, audit.ActionCreate, audit.EntityPullRequest, pr.ID, audit.Diff(nil, pr), "Pull request created")
+		return pr, nil
 	}, map[string]int{"not found": http.StatusNotFound})
 }
 
@@ -357,6 +456,12 @@ func (h *Handler) AddReviewer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.authorizePullRequest(w, r, prID) {
+		return
+	}
+
+	before := h.auditSnapshot(func() (interface{}, error) { return h.service.GetPullRequest(prID) })
+
 	pr, err := h.service.AddReviewer(prID, req.ReviewerID)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
@@ -369,6 +474,8 @@ func (h *Handler) AddReviewer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.recordReviewerAudit(r, audit.ActionCreate, req.ReviewerID, before, pr, "Reviewer assigned")
+
 	h.sendJSON(w, http.StatusOK, pr)
 }
 
@@ -386,6 +493,12 @@ func (h *Handler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.authorizePullRequest(w, r, prID) {
+		return
+	}
+
+	before := h.auditSnapshot(func() (interface{}, error) { return h.service.GetPullRequest(prID) })
+
 	pr, err := h.service.ReassignReviewer(prID, &req)
 	if err != nil {
 		if err.Error() == errPRNotFound || err.Error() == "reviewer not found in PR" {
@@ -398,20 +511,28 @@ func (h *Handler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.recordReviewerAudit(r, audit.ActionUpdate, req.OldReviewerID, before, pr, "Reviewer reassigned")
+
 	h.sendJSON(w, http.StatusOK, pr)
 }
 
 // MergePullRequest переводит PR в состояние MERGED
 func (h *Handler) MergePullRequest(w http.ResponseWriter, r *http.Request) {
+	if prID, err := h.getIntParam(r, "prId"); err == nil && !h.authorizePullRequest(w, r, prID) {
+		return
+	}
 	h.handleUpdateEntity(w, r, "prId", func(id int) (interface{}, error) {
-		return h.service.MergePullRequest(id)
+		return h.auditPullRequest(r, id, "Pull request merged", h.service.MergePullRequest)
 	}, "Pull request not found", "Failed to merge pull request")
 }
 
 // ClosePullRequest переводит PR в состояние CLOSED (закрыт без мерджа)
 func (h *Handler) ClosePullRequest(w http.ResponseWriter, r *http.Request) {
+	if prID, err := h.getIntParam(r, "prId"); err == nil && !h.authorizePullRequest(w, r, prID) {
+		return
+	}
 	h.handleUpdateEntity(w, r, "prId", func(id int) (interface{}, error) {
-		return h.service.ClosePullRequest(id)
+		return h.auditPullRequest(r, id, "Pull request closed", h.service.ClosePullRequest)
 	}, "Pull request not found or already closed/merged", "Failed to close pull request")
 }
 
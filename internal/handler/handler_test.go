@@ -1,9 +1,18 @@
 package handler
 
 import (
+	"bufio"
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/user/pr-reviewer/internal/audit"
+	"github.com/user/pr-reviewer/internal/featureflags"
+	"github.com/user/pr-reviewer/internal/stream"
 )
 
 // mockLogger для тестирования
@@ -155,3 +164,261 @@ func TestLoggingMiddleware(t *testing.T) {
 		t.Errorf("expected status 200, got %d", w.Code)
 	}
 }
+
+func TestMyNotifications_RequiresAuthentication(t *testing.T) {
+	h := &Handler{
+		service: nil,
+		logger:  &mockLogger{},
+	}
+
+	handlers := map[string]http.HandlerFunc{
+		"list":     h.GetMyNotifications,
+		"read":     h.MarkNotificationRead,
+		"read-all": h.MarkAllNotificationsRead,
+	}
+
+	for name, fn := range handlers {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/me/notifications", nil)
+			w := httptest.NewRecorder()
+
+			fn(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("expected status 401, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	broker := stream.NewBroker(10)
+	broker.Publish(&stream.Event{ID: 1, Type: "pr.created", Data: map[string]interface{}{"pr_id": 1, "team_id": 2}})
+	broker.Publish(&stream.Event{ID: 2, Type: "pr.created", Data: map[string]interface{}{"pr_id": 2, "team_id": 3}})
+	broker.Publish(&stream.Event{ID: 3, Type: "pr.merged", Data: map[string]interface{}{"pr_id": 1, "team_id": 2}})
+
+	h := &Handler{
+		service: nil,
+		events:  broker,
+		logger:  &mockLogger{},
+	}
+	server := httptest.NewServer(http.HandlerFunc(h.StreamEvents))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"?teamId=2", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected text/event-stream, got %s", ct)
+	}
+
+	// Сначала приходит событие 3 из буфера, затем новое событие 5 (4 другой команды)
+	go func() {
+		broker.Publish(&stream.Event{ID: 4, Type: "pr.created", Data: map[string]interface{}{"pr_id": 4, "team_id": 3}})
+		broker.Publish(&stream.Event{ID: 5, Type: "reviewer.assigned", Data: map[string]interface{}{"pr_id": 1, "team_id": 2}})
+	}()
+
+	reader := bufio.NewReader(resp.Body)
+	var ids []string
+	for len(ids) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read stream: %v", err)
+		}
+		if strings.HasPrefix(line, "id: ") {
+			ids = append(ids, strings.TrimSpace(strings.TrimPrefix(line, "id: ")))
+		}
+	}
+	if ids[0] != "3" || ids[1] != "5" {
+		t.Errorf("expected events [3 5], got %v", ids)
+	}
+}
+
+func TestLogin_ValidatesRequest(t *testing.T) {
+	h := &Handler{
+		service: nil,
+		logger:  &mockLogger{},
+	}
+
+	for _, body := range []string{`{invalid`, `{"username":"alice"}`, `{"password":"secret"}`} {
+		req := httptest.NewRequest("POST", "/auth/login", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		h.Login(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("body %s: expected status 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestGetCurrentUser_RequiresAuthentication(t *testing.T) {
+	h := &Handler{
+		service: nil,
+		logger:  &mockLogger{},
+	}
+
+	req := httptest.NewRequest("GET", "/auth/me", nil)
+	w := httptest.NewRecorder()
+
+	h.GetCurrentUser(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", w.Code)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	h := &Handler{logger: &mockLogger{}}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:5555"
+	if ip := h.clientIP(req); ip != "10.0.0.1" {
+		t.Errorf("expected remote address, got %s", ip)
+	}
+
+	// Без доверенных прокси заголовки клиента игнорируются
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.2")
+	if ip := h.clientIP(req); ip != "10.0.0.1" {
+		t.Errorf("expected forwarded header to be ignored, got %s", ip)
+	}
+
+	if err := h.SetTrustedProxies([]string{"10.0.0.0/8", "192.0.2.10"}); err != nil {
+		t.Fatalf("failed to set trusted proxies: %v", err)
+	}
+	if ip := h.clientIP(req); ip != "203.0.113.7" {
+		t.Errorf("expected client address behind proxies, got %s", ip)
+	}
+
+	// Адрес, подставленный клиентом левее, не принимается
+	req.Header.Set("X-Forwarded-For", "1.1.1.1, 203.0.113.7, 10.0.0.2")
+	if ip := h.clientIP(req); ip != "203.0.113.7" {
+		t.Errorf("expected rightmost untrusted address, got %s", ip)
+	}
+
+	// Невалидный адрес не попадает в refresh токены и audit log
+	req.Header.Set("X-Forwarded-For", strings.Repeat("a", 100))
+	req.Header.Set("X-Real-IP", "not-an-ip")
+	if ip := h.clientIP(req); ip != "10.0.0.1" {
+		t.Errorf("expected fallback to remote address, got %s", ip)
+	}
+
+	if err := h.SetTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("expected invalid CIDR to be rejected")
+	}
+}
+
+func TestLogAudit_BoundedByTimeout(t *testing.T) {
+	h := &Handler{audit: &audit.Logger{}, logger: &mockLogger{}}
+
+	start := time.Now()
+	h.logAudit(func(ctx context.Context) error {
+		// Запись, ждущая блокировку цепочки, прерывается по таймауту
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if elapsed := time.Since(start); elapsed > auditWriteTimeout+time.Second {
+		t.Errorf("expected audit write to be cancelled after %v, took %v", auditWriteTimeout, elapsed)
+	}
+}
+
+func TestAuditFilter(t *testing.T) {
+	h := &Handler{logger: &mockLogger{}}
+
+	req := httptest.NewRequest("GET", "/admin/audit?userId=5&entity=team&entityId=3&action=update&requestId=req-1&from=2026-01-01T00:00:00Z&limit=50", nil)
+	filter, err := h.auditFilter(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filter.UserID != 5 || filter.Entity != "team" || filter.EntityID != 3 || filter.Action != "update" ||
+		filter.RequestID != "req-1" || filter.From.IsZero() || filter.Limit != 50 {
+		t.Errorf("unexpected filter: %+v", filter)
+	}
+
+	for _, query := range []string{"userId=abc", "entityId=-1", "from=yesterday", "limit=5000"} {
+		req := httptest.NewRequest("GET", "/admin/audit?"+query, nil)
+		if _, err := h.auditFilter(req); err == nil {
+			t.Errorf("%s: expected error", query)
+		}
+	}
+}
+
+func TestAuditExportFormat(t *testing.T) {
+	tests := []struct {
+		query  string
+		accept string
+		want   string
+	}{
+		{"", "", ""},
+		{"format=json", "text/csv", ""},
+		{"format=csv", "", "csv"},
+		{"", "application/x-ndjson", "ndjson"},
+		{"", "text/csv", "csv"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/admin/audit?"+tt.query, nil)
+		req.Header.Set("Accept", tt.accept)
+		if got := auditExportFormat(req); got != tt.want {
+			t.Errorf("query %q, accept %q: expected %q, got %q", tt.query, tt.accept, tt.want, got)
+		}
+	}
+}
+
+func TestFeatureFlagsAdmin(t *testing.T) {
+	h, flags := newAuthorizedHandler(t)
+	router := mux.NewRouter()
+	router.HandleFunc("/admin/flags/{key}", h.PutFeatureFlag).Methods("PUT")
+	router.HandleFunc("/admin/flags/{key}", h.DeleteFeatureFlag).Methods("DELETE")
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("PUT", "/admin/flags/audit_log", `{"description": "no enabled"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without enabled, got %d", w.Code)
+	}
+	if w := do("PUT", "/admin/flags/Bad%20Key", `{"enabled": true}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid key, got %d", w.Code)
+	}
+
+	if w := do("PUT", "/admin/flags/audit_log", `{"enabled": false}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if flags.IsEnabled(featureflags.FlagAuditLog) {
+		t.Error("expected audit_log to be disabled")
+	}
+
+	if w := do("DELETE", "/admin/flags/audit_log", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if !flags.IsEnabled(featureflags.FlagAuditLog) {
+		t.Error("expected audit_log to return to default")
+	}
+	if w := do("DELETE", "/admin/flags/audit_log", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for flag without override, got %d", w.Code)
+	}
+}
+
+func TestAuditFilter_ConvertsTimeToUTC(t *testing.T) {
+	h := &Handler{logger: &mockLogger{}}
+
+	req := httptest.NewRequest("GET", "/audit-logs?from=2026-03-01T10:00:00%2B03:00&to=2026-03-01T12:00:00-02:00", nil)
+	filter, err := h.auditFilter(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantFrom := time.Date(2026, 3, 1, 7, 0, 0, 0, time.UTC)
+	wantTo := time.Date(2026, 3, 1, 14, 0, 0, 0, time.UTC)
+	if filter.From != wantFrom || filter.To != wantTo {
+		t.Errorf("expected %v - %v in UTC, got %v - %v", wantFrom, wantTo, filter.From, filter.To)
+	}
+}
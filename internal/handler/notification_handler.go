@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/user/pr-reviewer/internal/auth"
+)
+
+// GetMyNotifications возвращает уведомления текущего пользователя
+func (h *Handler) GetMyNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	unreadOnly, _ := h.getBoolQuery(r, "unread")
+	limit, _ := h.getIntQuery(r, "limit")
+	offset, _ := h.getIntQuery(r, "offset")
+
+	list, err := h.service.GetNotifications(userID, unreadOnly, limit, offset)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to get notifications")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, list)
+}
+
+// MarkNotificationRead отмечает уведомление прочитанным
+func (h *Handler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	if err := h.service.MarkNotificationRead(userID, id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.sendError(w, http.StatusNotFound, "Notification not found")
+		} else {
+			h.sendError(w, http.StatusInternalServerError, "Failed to mark notification read")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MarkAllNotificationsRead отмечает все уведомления текущего пользователя прочитанными
+func (h *Handler) MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := h.service.MarkAllNotificationsRead(userID)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to mark notifications read")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]int{"markedRead": count})
+}
+
+// currentUserID возвращает ID аутентифицированного пользователя или отвечает 401
+func (h *Handler) currentUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok || userID <= 0 {
+		h.sendError(w, http.StatusUnauthorized, "Authentication required")
+		return 0, false
+	}
+	return int(userID), true
+}
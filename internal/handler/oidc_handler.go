@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/user/pr-reviewer/internal/auth"
+	"github.com/user/pr-reviewer/internal/service"
+)
+
+const (
+	// oidcFlowCookie cookie с state, nonce и PKCE verifier текущего входа
+	oidcFlowCookie = "oidc_flow"
+	// oidcFlowTTL время на прохождение входа у IdP
+	oidcFlowTTL = 600
+)
+
+// SetOIDCProvider включает вход через корпоративный IdP. Если postLoginRedirect
+// задан, после входа браузер перенаправляется на него с токенами во фрагменте URL,
+// иначе токены возвращаются в JSON.
+func (h *Handler) SetOIDCProvider(provider *auth.OIDCProvider, postLoginRedirect string) {
+	h.oidc = provider
+	h.oidcRedirect = postLoginRedirect
+}
+
+// OIDCLogin начинает authorization code flow: сохраняет параметры входа
+// в cookie и перенаправляет на страницу входа IdP
+func (h *Handler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	flow, err := auth.NewOIDCFlow()
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to start login")
+		return
+	}
+
+	value, err := json.Marshal(flow)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to start login")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(value),
+		Path:     "/auth/oidc",
+		MaxAge:   oidcFlowTTL,
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, h.oidc.AuthCodeURL(flow), http.StatusFound)
+}
+
+// OIDCCallback завершает вход: проверяет state, обменивает код на ID токен,
+// находит или создает пользователя и выдает токены сервиса
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if idpError := query.Get("error"); idpError != "" {
+		h.sendError(w, http.StatusUnauthorized, "Identity provider error: "+idpError)
+		return
+	}
+
+	flow, err := readOIDCFlow(r)
+	// Параметры входа одноразовые
+	http.SetCookie(w, &http.Cookie{Name: oidcFlowCookie, Path: "/auth/oidc", MaxAge: -1})
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Login session expired, start again")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(flow.State)) != 1 {
+		h.sendError(w, http.StatusBadRequest, "Invalid state")
+		return
+	}
+	code := query.Get("code")
+	if code == "" {
+		h.sendError(w, http.StatusBadRequest, "Missing authorization code")
+		return
+	}
+
+	identity, err := h.oidc.Exchange(r.Context(), code, flow)
+	if err != nil {
+		h.logf("OIDC authentication failed: %v", err)
+		h.sendError(w, http.StatusUnauthorized, "OIDC authentication failed")
+		return
+	}
+
+	ip, userAgent := h.clientIP(r), r.UserAgent()
+
+	user, err := h.service.LoginWithOIDC(identity)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			var userID int64
+			if user != nil {
+				userID = int64(user.ID)
+			}
+			h.logAudit(func(ctx context.Context) error {
+				return h.audit.LogLogin(ctx, userID, identity.Username, false, ip, userAgent)
+			})
+			h.sendError(w, http.StatusUnauthorized, "User is deactivated")
+		} else {
+			h.sendError(w, http.StatusInternalServerError, "Failed to authenticate")
+		}
+		return
+	}
+
+	refreshToken, err := h.service.IssueRefreshToken(user.ID, userAgent, ip)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to issue refresh token")
+		return
+	}
+
+	h.logAudit(func(ctx context.Context) error {
+		return h.audit.LogLogin(ctx, int64(user.ID), user.Username, true, ip, userAgent)
+	})
+
+	if h.oidcRedirect == "" {
+		h.sendTokens(w, user, refreshToken)
+		return
+	}
+
+	tokens, err := h.issueTokens(user, refreshToken)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to issue access token")
+		return
+	}
+
+	// Фрагмент URL не отправляется на сервер и не попадает в логи прокси
+	fragment := url.Values{}
+	fragment.Set("access_token", tokens.AccessToken)
+	fragment.Set("refresh_token", tokens.RefreshToken)
+	fragment.Set("token_type", tokens.TokenType)
+	fragment.Set("expires_in", strconv.Itoa(tokens.ExpiresIn))
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, h.oidcRedirect+"#"+fragment.Encode(), http.StatusFound)
+}
+
+// readOIDCFlow читает параметры входа из cookie
+func readOIDCFlow(r *http.Request) (*auth.OIDCFlow, error) {
+	cookie, err := r.Cookie(oidcFlowCookie)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	flow := &auth.OIDCFlow{}
+	if err := json.Unmarshal(value, flow); err != nil {
+		return nil, err
+	}
+	if flow.State == "" || flow.Nonce == "" || flow.Verifier == "" {
+		return nil, errors.New("incomplete login session")
+	}
+	return flow, nil
+}
+
+// isSecureRequest проверяет, пришел ли запрос по HTTPS (напрямую или через прокси)
+func isSecureRequest(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
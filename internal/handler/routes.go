@@ -0,0 +1,293 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/user/pr-reviewer/internal/auth"
+	"github.com/user/pr-reviewer/internal/featureflags"
+)
+
+// Группы ролей матрицы доступа
+var (
+	allRoles   = []string{auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember, auth.RoleReadonly}
+	writeRoles = []string{auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember}
+	leadRoles  = []string{auth.RoleAdmin, auth.RoleTeamLead}
+	adminRoles = []string{auth.RoleAdmin}
+)
+
+// route маршрут API, роли, которым он доступен, и область доступа для
+// API ключей. Пустой roles означает публичный маршрут (или маршрут, который
+// сам проверяет токен); пустой scope — маршрут недоступен по API ключу.
+// Маршруты только для admin проверяются всегда, когда настроен JWT.
+type route struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+	roles   []string
+	scope   string
+}
+
+// SetFeatureFlags подключает feature flags. Проверка ролей на маршрутах,
+// кроме маршрутов только для admin, включается флагом jwt_auth.
+func (h *Handler) SetFeatureFlags(flags *featureflags.Manager) {
+	h.flags = flags
+}
+
+// routes возвращает матрицу доступа ко всем маршрутам API
+func (h *Handler) routes() []route {
+	routes := []route{
+		// Health check
+		{"GET", "/health", h.HealthCheck, nil, ""},
+	}
+
+	// Authentication
+	if h.jwtAuth != nil {
+		routes = append(routes,
+			route{"POST", "/auth/login", h.Login, nil, ""},
+			route{"POST", "/auth/refresh", h.RefreshToken, nil, ""},
+			route{"POST", "/auth/logout", h.Logout, nil, ""},
+			route{"GET", "/auth/me", h.GetCurrentUser, allRoles, ""},
+		)
+	}
+	if h.jwtAuth != nil && h.jwtAuth.Revocations() != nil {
+		routes = append(routes, route{"POST", "/admin/tokens/revoke", h.RevokeTokens, adminRoles, ""})
+	}
+	if h.jwtAuth != nil && h.jwtAuth.KeyRing() != nil {
+		routes = append(routes, route{"GET", "/.well-known/jwks.json", h.JWKS, nil, ""})
+	}
+	if h.oidc != nil && h.jwtAuth != nil {
+		routes = append(routes,
+			route{"GET", "/auth/oidc/login", h.OIDCLogin, nil, ""},
+			route{"GET", "/auth/oidc/callback", h.OIDCCallback, nil, ""},
+		)
+	}
+
+	routes = append(routes,
+		// Teams
+		route{"GET", "/teams", h.GetTeams, allRoles, auth.ScopeTeamsRead},
+		route{"POST", "/teams", h.CreateTeam, adminRoles, auth.ScopeTeamsWrite},
+		route{"GET", "/teams/{teamId}", h.GetTeam, allRoles, auth.ScopeTeamsRead},
+		route{"DELETE", "/teams/{teamId}", h.DeleteTeam, adminRoles, auth.ScopeTeamsWrite},
+		route{"POST", "/teams/{teamId}/users", h.AddUserToTeam, leadRoles, auth.ScopeTeamsWrite},
+		route{"DELETE", "/teams/{teamId}/users", h.RemoveUserFromTeam, leadRoles, auth.ScopeTeamsWrite},
+		route{"POST", "/teams/{teamId}/users/deactivate", h.BulkDeactivateUsers, leadRoles, auth.ScopeTeamsWrite},
+
+		// Users
+		route{"GET", "/users", h.GetUsers, allRoles, auth.ScopeUsersRead},
+		route{"POST", "/users", h.CreateUser, adminRoles, auth.ScopeUsersWrite},
+		route{"GET", "/users/{userId}", h.GetUser, allRoles, auth.ScopeUsersRead},
+		route{"PATCH", "/users/{userId}", h.UpdateUser, adminRoles, auth.ScopeUsersWrite},
+		route{"GET", "/users/{userId}/notification-preferences", h.GetNotificationPreferences, allRoles, ""},
+		route{"PUT", "/users/{userId}/notification-preferences", h.UpdateNotificationPreferences, allRoles, ""},
+
+		// Notifications текущего пользователя
+		route{"GET", "/me/notifications", h.GetMyNotifications, allRoles, ""},
+		route{"POST", "/me/notifications/read-all", h.MarkAllNotificationsRead, allRoles, ""},
+		route{"POST", "/me/notifications/{id}/read", h.MarkNotificationRead, allRoles, ""},
+
+		// Pull Requests
+		route{"GET", "/pull-requests", h.GetPullRequests, allRoles, auth.ScopePRRead},
+		route{"POST", "/pull-requests", h.CreatePullRequest, writeRoles, auth.ScopePRWrite},
+		route{"GET", "/pull-requests/{prId}", h.GetPullRequest, allRoles, auth.ScopePRRead},
+		route{"POST", "/pull-requests/{prId}/reviewers", h.AddReviewer, writeRoles, auth.ScopePRWrite},
+		route{"PUT", "/pull-requests/{prId}/reviewers", h.ReassignReviewer, writeRoles, auth.ScopePRWrite},
+		route{"POST", "/pull-requests/{prId}/merge", h.MergePullRequest, writeRoles, auth.ScopePRWrite},
+		route{"POST", "/pull-requests/{prId}/close", h.ClosePullRequest, writeRoles, auth.ScopePRWrite},
+
+		// Statistics
+		route{"GET", "/statistics", h.GetStatistics, allRoles, auth.ScopeStatsRead},
+	)
+
+	// Live stream событий PR. WebSocket проверяет токен сам: браузер
+	// передает его подпротоколом Sec-WebSocket-Protocol.
+	if h.events != nil {
+		routes = append(routes, route{"GET", "/events/stream", h.StreamEvents, allRoles, auth.ScopePRRead})
+	}
+	if h.ws != nil && h.jwtAuth != nil {
+		routes = append(routes, route{"GET", "/events/ws", h.StreamWebSocket, nil, ""})
+	}
+
+	// Администрирование доступно только аутентифицированному admin:
+	// без JWT маршруты не регистрируются
+	if h.jwtAuth == nil {
+		return routes
+	}
+
+	// Service accounts и API ключи
+	routes = append(routes,
+		route{"GET", "/service-accounts", h.GetServiceAccounts, adminRoles, ""},
+		route{"POST", "/service-accounts", h.CreateServiceAccount, adminRoles, ""},
+		route{"GET", "/service-accounts/{accountId}/api-keys", h.GetAPIKeys, adminRoles, ""},
+		route{"POST", "/service-accounts/{accountId}/api-keys", h.CreateAPIKey, adminRoles, ""},
+		route{"DELETE", "/service-accounts/{accountId}/api-keys/{keyId}", h.RevokeAPIKey, adminRoles, ""},
+	)
+
+	// Audit log
+	if h.audit != nil {
+		routes = append(routes,
+			route{"GET", "/admin/audit", h.GetAuditLog, adminRoles, auth.ScopeAuditRead},
+			route{"GET", "/admin/audit/verify", h.VerifyAuditLog, adminRoles, auth.ScopeAuditRead},
+		)
+	}
+
+	// Feature flags
+	if h.flags != nil {
+		routes = append(routes,
+			route{"GET", "/admin/flags", h.GetFeatureFlags, adminRoles, ""},
+			route{"GET", "/admin/flags/{key}", h.GetFeatureFlag, adminRoles, ""},
+			route{"PUT", "/admin/flags/{key}", h.PutFeatureFlag, adminRoles, ""},
+			route{"DELETE", "/admin/flags/{key}", h.DeleteFeatureFlag, adminRoles, ""},
+		)
+	}
+
+	// Webhooks
+	if h.webhooks != nil {
+		routes = append(routes,
+			route{"GET", "/webhooks/dead-letters", h.GetDeadLetters, adminRoles, ""},
+			route{"POST", "/webhooks/dead-letters/replay", h.ReplayDeadLetters, adminRoles, ""},
+			route{"POST", "/webhooks/dead-letters/{id}/replay", h.ReplayDeadLetter, adminRoles, ""},
+			route{"POST", "/webhooks/filters/test", h.TestWebhookFilter, adminRoles, ""},
+			route{"GET", "/webhooks/subscriptions", h.ListWebhookSubscriptions, adminRoles, ""},
+			route{"PATCH", "/webhooks/subscriptions/{id}", h.UpdateWebhookSubscription, adminRoles, ""},
+			route{"POST", "/webhooks/subscriptions/{id}/enable", h.EnableWebhookSubscription, adminRoles, ""},
+			route{"POST", "/webhooks/subscriptions/{id}/rotate-secret", h.RotateWebhookSecret, adminRoles, ""},
+		)
+	}
+
+	return routes
+}
+
+// authorize проверяет роль пользователя, если включен флаг jwt_auth.
+// Флаг проверяется на каждом запросе, чтобы его можно было переключить без рестарта.
+// Маршруты только для admin проверяются независимо от флага.
+// Запросы по API ключу дополнительно проверяются по области доступа маршрута.
+func (h *Handler) authorize(rt route) http.Handler {
+	var next http.Handler = rt.handler
+	if rt.roles == nil {
+		return next
+	}
+
+	protected := next
+	if h.jwtAuth != nil {
+		protected = h.jwtAuth.RequireRole(rt.roles...)(next)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.GetScopes(r.Context()); ok {
+			if rt.scope == "" {
+				h.sendError(w, http.StatusForbidden, "Forbidden: endpoint is not available for API keys")
+				return
+			}
+			if !auth.HasScope(r.Context(), rt.scope) {
+				h.sendError(w, http.StatusForbidden, "Forbidden: API key lacks scope "+rt.scope)
+				return
+			}
+		}
+
+		if h.authEnforced() || (h.jwtAuth != nil && adminOnly(rt.roles)) {
+			protected.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authEnforced проверяет, включена ли проверка прав доступа
+func (h *Handler) authEnforced() bool {
+	return h.jwtAuth != nil && h.flags != nil && h.flags.IsEnabled(featureflags.FlagJWTAuth)
+}
+
+// adminOnly проверяет, что маршрут доступен только admin
+func adminOnly(roles []string) bool {
+	return len(roles) == 1 && roles[0] == auth.RoleAdmin
+}
+
+// authorizeTeam проверяет, что вызывающий может изменять команду teamID.
+// Admin может изменять любую команду, остальные роли — только свою (claim team_id).
+// При отказе отправляет 403 с причиной и возвращает false.
+func (h *Handler) authorizeTeam(w http.ResponseWriter, r *http.Request, teamID int) bool {
+	if !h.authEnforced() {
+		return true
+	}
+	if role, _ := auth.GetUserRole(r.Context()); role == auth.RoleAdmin {
+		return true
+	}
+
+	callerTeamID, _ := auth.GetTeamID(r.Context())
+	if callerTeamID == 0 {
+		h.sendError(w, http.StatusForbidden, "Forbidden: you are not a member of any team")
+		return false
+	}
+	if callerTeamID != int64(teamID) {
+		h.sendError(w, http.StatusForbidden,
+			fmt.Sprintf("Forbidden: team %d is not your team, only team %d can be modified", teamID, callerTeamID))
+		return false
+	}
+	return true
+}
+
+// authorizeTeamMember проверяет, что пользователь не состоит в чужой команде:
+// перевод пользователя из другой команды меняет и ее состав.
+func (h *Handler) authorizeTeamMember(w http.ResponseWriter, r *http.Request, userID int) bool {
+	if !h.authEnforced() {
+		return true
+	}
+	if role, _ := auth.GetUserRole(r.Context()); role == auth.RoleAdmin {
+		return true
+	}
+
+	user, err := h.service.GetUser(userID)
+	if err != nil {
+		// Несуществующего пользователя обработает сам handler
+		return true
+	}
+	if user.TeamID == nil {
+		return true
+	}
+	return h.authorizeTeam(w, r, *user.TeamID)
+}
+
+// authorizeSelf проверяет, что вызывающий действует от имени пользователя
+// userID. Admin может действовать от имени любого пользователя.
+// При отказе отправляет 403 и возвращает false.
+func (h *Handler) authorizeSelf(w http.ResponseWriter, r *http.Request, userID int) bool {
+	if !h.authEnforced() {
+		return true
+	}
+	if role, _ := auth.GetUserRole(r.Context()); role == auth.RoleAdmin {
+		return true
+	}
+
+	callerID, _ := auth.GetUserID(r.Context())
+	if callerID != int64(userID) {
+		h.sendError(w, http.StatusForbidden, "Forbidden: only your own settings can be accessed")
+		return false
+	}
+	return true
+}
+
+// authorizePullRequest проверяет, что PR принадлежит команде вызывающего.
+// Команда PR — команда его автора.
+func (h *Handler) authorizePullRequest(w http.ResponseWriter, r *http.Request, prID int) bool {
+	if !h.authEnforced() {
+		return true
+	}
+	if role, _ := auth.GetUserRole(r.Context()); role == auth.RoleAdmin {
+		return true
+	}
+
+	pr, err := h.service.GetPullRequest(prID)
+	if err != nil {
+		if err.Error() == errPRNotFound {
+			// 404 вернет сам handler
+			return true
+		}
+		h.sendError(w, http.StatusInternalServerError, "Failed to check pull request access")
+		return false
+	}
+	if pr.Team == nil {
+		h.sendError(w, http.StatusForbidden, "Forbidden: pull request author is not a member of any team")
+		return false
+	}
+	return h.authorizeTeam(w, r, pr.Team.ID)
+}
@@ -0,0 +1,484 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/user/pr-reviewer/internal/auth"
+	"github.com/user/pr-reviewer/internal/cache"
+	"github.com/user/pr-reviewer/internal/featureflags"
+	"github.com/user/pr-reviewer/internal/logger"
+	"github.com/user/pr-reviewer/internal/stream"
+	"github.com/user/pr-reviewer/internal/webhook"
+)
+
+// routePermissions ожидаемая матрица доступа: маршрут -> роли с доступом.
+// nil означает публичный маршрут.
+var routePermissions = map[string][]string{
+	"GET /health":        nil,
+	"POST /auth/login":   nil,
+	"POST /auth/refresh": nil,
+	"POST /auth/logout":  nil,
+	"GET /auth/me":       {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember, auth.RoleReadonly},
+
+	"POST /admin/tokens/revoke": {auth.RoleAdmin},
+
+	"GET /teams":                            {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember, auth.RoleReadonly},
+	"POST /teams":                           {auth.RoleAdmin},
+	"GET /teams/{teamId}":                   {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember, auth.RoleReadonly},
+	"DELETE /teams/{teamId}":                {auth.RoleAdmin},
+	"POST /teams/{teamId}/users":            {auth.RoleAdmin, auth.RoleTeamLead},
+	"DELETE /teams/{teamId}/users":          {auth.RoleAdmin, auth.RoleTeamLead},
+	"POST /teams/{teamId}/users/deactivate": {auth.RoleAdmin, auth.RoleTeamLead},
+
+	"GET /users":            {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember, auth.RoleReadonly},
+	"POST /users":           {auth.RoleAdmin},
+	"GET /users/{userId}":   {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember, auth.RoleReadonly},
+	"PATCH /users/{userId}": {auth.RoleAdmin},
+	"GET /users/{userId}/notification-preferences": {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember, auth.RoleReadonly},
+	"PUT /users/{userId}/notification-preferences": {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember, auth.RoleReadonly},
+
+	"GET /me/notifications":            {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember, auth.RoleReadonly},
+	"POST /me/notifications/read-all":  {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember, auth.RoleReadonly},
+	"POST /me/notifications/{id}/read": {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember, auth.RoleReadonly},
+
+	"GET /pull-requests":                   {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember, auth.RoleReadonly},
+	"POST /pull-requests":                  {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember},
+	"GET /pull-requests/{prId}":            {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember, auth.RoleReadonly},
+	"POST /pull-requests/{prId}/reviewers": {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember},
+	"PUT /pull-requests/{prId}/reviewers":  {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember},
+	"POST /pull-requests/{prId}/merge":     {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember},
+	"POST /pull-requests/{prId}/close":     {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember},
+
+	"GET /statistics": {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember, auth.RoleReadonly},
+
+	"GET /service-accounts":                                 {auth.RoleAdmin},
+	"POST /service-accounts":                                {auth.RoleAdmin},
+	"GET /service-accounts/{accountId}/api-keys":            {auth.RoleAdmin},
+	"POST /service-accounts/{accountId}/api-keys":           {auth.RoleAdmin},
+	"DELETE /service-accounts/{accountId}/api-keys/{keyId}": {auth.RoleAdmin},
+
+	"GET /admin/flags":          {auth.RoleAdmin},
+	"GET /admin/flags/{key}":    {auth.RoleAdmin},
+	"PUT /admin/flags/{key}":    {auth.RoleAdmin},
+	"DELETE /admin/flags/{key}": {auth.RoleAdmin},
+
+	"GET /webhooks/dead-letters":                      {auth.RoleAdmin},
+	"POST /webhooks/dead-letters/replay":              {auth.RoleAdmin},
+	"POST /webhooks/dead-letters/{id}/replay":         {auth.RoleAdmin},
+	"POST /webhooks/filters/test":                     {auth.RoleAdmin},
+	"GET /webhooks/subscriptions":                     {auth.RoleAdmin},
+	"PATCH /webhooks/subscriptions/{id}":              {auth.RoleAdmin},
+	"POST /webhooks/subscriptions/{id}/enable":        {auth.RoleAdmin},
+	"POST /webhooks/subscriptions/{id}/rotate-secret": {auth.RoleAdmin},
+
+	"GET /events/stream": {auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember, auth.RoleReadonly},
+	"GET /events/ws":     nil,
+}
+
+// newAuthorizedHandler создает handler со всеми опциональными маршрутами
+func newAuthorizedHandler(t *testing.T) (*Handler, *featureflags.Manager) {
+	t.Helper()
+
+	log, err := logger.New("error", "test")
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	flags := featureflags.NewManager(cache.NewNoOpCache(), log)
+	jwtAuth := auth.NewJWTAuth("test-secret", time.Hour, log)
+	jwtAuth.SetRevocationStore(auth.NewRevocationStore(cache.NewMemoryCache(), time.Hour))
+	h := &Handler{
+		logger:   &mockLogger{},
+		jwtAuth:  jwtAuth,
+		webhooks: &webhook.Manager{},
+		events:   stream.NewBroker(10),
+		ws:       &stream.WebSocketServer{},
+		flags:    flags,
+	}
+	return h, flags
+}
+
+func TestRoutes_PermissionMatrixIsComplete(t *testing.T) {
+	h, _ := newAuthorizedHandler(t)
+
+	registered := make(map[string]bool)
+	for _, rt := range h.routes() {
+		key := rt.method + " " + rt.path
+		if registered[key] {
+			t.Errorf("route %s registered twice", key)
+		}
+		registered[key] = true
+
+		if _, ok := routePermissions[key]; !ok {
+			t.Errorf("route %s is missing from the permission matrix", key)
+		}
+	}
+	for key := range routePermissions {
+		if !registered[key] {
+			t.Errorf("route %s is in the permission matrix but not registered", key)
+		}
+	}
+}
+
+func TestRoutes_EnforcePermissionMatrix(t *testing.T) {
+	h, flags := newAuthorizedHandler(t)
+	_ = flags.EnableFlag(featureflags.FlagJWTAuth)
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	callers := []string{"", auth.RoleAdmin, auth.RoleTeamLead, auth.RoleMember, auth.RoleReadonly}
+
+	for _, rt := range h.routes() {
+		allowed := routePermissions[rt.method+" "+rt.path]
+		rt.handler = ok
+		handler := h.jwtAuth.OptionalMiddleware(h.authorize(rt))
+
+		for _, role := range callers {
+			name := rt.method + " " + rt.path + " as " + role
+			if role == "" {
+				name = rt.method + " " + rt.path + " anonymous"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				req := httptest.NewRequest(rt.method, rt.path, nil)
+				if role != "" {
+					token, err := h.jwtAuth.GenerateToken(1, "user@example.com", role, 0)
+					if err != nil {
+						t.Fatalf("failed to generate token: %v", err)
+					}
+					req.Header.Set("Authorization", "Bearer "+token)
+				}
+				w := httptest.NewRecorder()
+
+				handler.ServeHTTP(w, req)
+
+				want := http.StatusOK
+				switch {
+				case allowed == nil:
+				case role == "":
+					want = http.StatusUnauthorized
+				case !containsRole(allowed, role):
+					want = http.StatusForbidden
+				}
+				if w.Code != want {
+					t.Errorf("expected status %d, got %d", want, w.Code)
+				}
+			})
+		}
+	}
+}
+
+func TestRoutes_NotEnforcedWhenFlagDisabled(t *testing.T) {
+	h, _ := newAuthorizedHandler(t)
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	member, err := h.jwtAuth.GenerateToken(1, "user@example.com", auth.RoleMember, 0)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	for _, rt := range h.routes() {
+		rt.handler = ok
+		handler := h.jwtAuth.OptionalMiddleware(h.authorize(rt))
+
+		req := httptest.NewRequest(rt.method, rt.path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		// Маршруты только для admin проверяются и при выключенном jwt_auth
+		if adminOnly(rt.roles) {
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s: expected status 401 for anonymous caller, got %d", rt.method, rt.path, w.Code)
+			}
+
+			req = httptest.NewRequest(rt.method, rt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+member)
+			w = httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != http.StatusForbidden {
+				t.Errorf("%s %s: expected status 403 for member, got %d", rt.method, rt.path, w.Code)
+			}
+			continue
+		}
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s %s: expected status 200 with jwt_auth disabled, got %d", rt.method, rt.path, w.Code)
+		}
+	}
+}
+
+func TestRoutes_AdminRoutesRequireJWT(t *testing.T) {
+	h, _ := newAuthorizedHandler(t)
+	h.jwtAuth = nil
+
+	for _, rt := range h.routes() {
+		if strings.HasPrefix(rt.path, "/admin/") || strings.HasPrefix(rt.path, "/service-accounts") || strings.HasPrefix(rt.path, "/webhooks/") {
+			t.Errorf("%s %s: expected admin route not to be registered without JWT", rt.method, rt.path)
+		}
+	}
+}
+
+func TestPutFeatureFlag_ProtectedFlag(t *testing.T) {
+	h, flags := newAuthorizedHandler(t)
+	_ = flags.EnableFlag(featureflags.FlagJWTAuth)
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		req := httptest.NewRequest(method, "/admin/flags/jwt_auth", strings.NewReader(`{"enabled": false}`))
+		req = mux.SetURLVars(req, map[string]string{"key": featureflags.FlagJWTAuth})
+		w := httptest.NewRecorder()
+
+		if method == http.MethodPut {
+			h.PutFeatureFlag(w, req)
+		} else {
+			h.DeleteFeatureFlag(w, req)
+		}
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s: expected status 403, got %d", method, w.Code)
+		}
+	}
+	if !flags.IsEnabled(featureflags.FlagJWTAuth) {
+		t.Error("expected jwt_auth to remain enabled")
+	}
+}
+
+func containsRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAuthorizeTeam(t *testing.T) {
+	h, flags := newAuthorizedHandler(t)
+	_ = flags.EnableFlag(featureflags.FlagJWTAuth)
+
+	tests := []struct {
+		name       string
+		role       string
+		callerTeam int64
+		teamID     int
+		want       int
+	}{
+		{"admin of another team", auth.RoleAdmin, 1, 2, http.StatusOK},
+		{"admin without team", auth.RoleAdmin, 0, 2, http.StatusOK},
+		{"team lead of own team", auth.RoleTeamLead, 1, 1, http.StatusOK},
+		{"team lead of another team", auth.RoleTeamLead, 1, 2, http.StatusForbidden},
+		{"member of another team", auth.RoleMember, 1, 2, http.StatusForbidden},
+		{"team lead without team", auth.RoleTeamLead, 0, 1, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := h.jwtAuth.GenerateToken(1, "user@example.com", tt.role, tt.callerTeam)
+			if err != nil {
+				t.Fatalf("failed to generate token: %v", err)
+			}
+
+			handler := h.jwtAuth.OptionalMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if h.authorizeTeam(w, r, tt.teamID) {
+					w.WriteHeader(http.StatusOK)
+				}
+			}))
+
+			req := httptest.NewRequest("POST", "/teams/1/users/deactivate", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, w.Code)
+			}
+			if tt.want == http.StatusForbidden && !strings.Contains(w.Body.String(), "Forbidden: ") {
+				t.Errorf("expected reason in response, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthorizeTeam_NotEnforcedWhenFlagDisabled(t *testing.T) {
+	h, _ := newAuthorizedHandler(t)
+
+	req := httptest.NewRequest("POST", "/teams/2/users/deactivate", nil)
+	w := httptest.NewRecorder()
+
+	if !h.authorizeTeam(w, req, 2) {
+		t.Errorf("expected team scope to be skipped with jwt_auth disabled, got %d", w.Code)
+	}
+}
+
+func TestAuthorizeSelf(t *testing.T) {
+	h, flags := newAuthorizedHandler(t)
+	_ = flags.EnableFlag(featureflags.FlagJWTAuth)
+
+	tests := []struct {
+		name   string
+		role   string
+		userID int
+		want   int
+	}{
+		{"own settings", auth.RoleMember, 1, http.StatusOK},
+		{"another user", auth.RoleMember, 2, http.StatusForbidden},
+		{"team lead for another user", auth.RoleTeamLead, 2, http.StatusForbidden},
+		{"admin for another user", auth.RoleAdmin, 2, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := h.jwtAuth.GenerateToken(1, "user@example.com", tt.role, 0)
+			if err != nil {
+				t.Fatalf("failed to generate token: %v", err)
+			}
+
+			handler := h.jwtAuth.OptionalMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if h.authorizeSelf(w, r, tt.userID) {
+					w.WriteHeader(http.StatusOK)
+				}
+			}))
+
+			req := httptest.NewRequest("GET", "/users/2/notification-preferences", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
+
+// stubAPIKeys проверяет ключи по заранее заданной таблице
+type stubAPIKeys map[string]*auth.APIKeyPrincipal
+
+func (s stubAPIKeys) ValidateAPIKey(_ context.Context, key string) (*auth.APIKeyPrincipal, error) {
+	if principal, ok := s[key]; ok {
+		return principal, nil
+	}
+	return nil, auth.ErrInvalidAPIKey
+}
+
+func TestRoutes_APIKeyScopes(t *testing.T) {
+	h, flags := newAuthorizedHandler(t)
+	_ = flags.EnableFlag(featureflags.FlagJWTAuth)
+
+	log, _ := logger.New("error", "test")
+	apiKeys := auth.NewAPIKeyAuth(stubAPIKeys{
+		"prr_ci": {ServiceAccountID: 1, Name: "ci", Role: auth.RoleMember, TeamID: 1,
+			Scopes: []string{auth.ScopePRWrite, auth.ScopeStatsRead}},
+	}, log)
+
+	routesByKey := make(map[string]route)
+	for _, rt := range h.routes() {
+		rt.handler = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}
+		routesByKey[rt.method+" "+rt.path] = rt
+	}
+
+	tests := []struct {
+		route string
+		key   string
+		want  int
+	}{
+		{"POST /pull-requests", "prr_ci", http.StatusOK},
+		{"POST /pull-requests/{prId}/merge", "prr_ci", http.StatusOK},
+		{"GET /statistics", "prr_ci", http.StatusOK},
+		{"GET /pull-requests", "prr_ci", http.StatusForbidden},
+		{"POST /teams/{teamId}/users/deactivate", "prr_ci", http.StatusForbidden},
+		{"GET /me/notifications", "prr_ci", http.StatusForbidden},
+		{"GET /service-accounts", "prr_ci", http.StatusForbidden},
+		{"GET /health", "prr_ci", http.StatusOK},
+		{"POST /pull-requests", "prr_unknown", http.StatusUnauthorized},
+		{"POST /pull-requests", "not-a-key", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.route+" "+tt.key, func(t *testing.T) {
+			rt := routesByKey[tt.route]
+			handler := apiKeys.Middleware(h.authorize(rt))
+
+			req := httptest.NewRequest(rt.method, rt.path, nil)
+			req.Header.Set(auth.APIKeyHeader, tt.key)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthorizeStream(t *testing.T) {
+	h, _ := newAuthorizedHandler(t)
+
+	tests := []struct {
+		name   string
+		role   string
+		teamID int64
+		query  string
+		want   int
+		filter stream.Filter
+	}{
+		{"anonymous", "", 0, "", http.StatusUnauthorized, stream.Filter{}},
+		{"admin any team", auth.RoleAdmin, 0, "?teamId=7", http.StatusOK, stream.Filter{TeamID: 7}},
+		{"member defaults to own team", auth.RoleMember, 2, "", http.StatusOK, stream.Filter{TeamID: 2}},
+		{"member own team", auth.RoleMember, 2, "?teamId=2", http.StatusOK, stream.Filter{TeamID: 2}},
+		{"member other team", auth.RoleMember, 2, "?teamId=3", http.StatusForbidden, stream.Filter{}},
+		{"member self", auth.RoleMember, 2, "?userId=1", http.StatusOK, stream.Filter{UserID: 1}},
+		{"member other user", auth.RoleMember, 2, "?userId=5", http.StatusForbidden, stream.Filter{}},
+		{"member without team", auth.RoleMember, 0, "", http.StatusOK, stream.Filter{UserID: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got stream.Filter
+			handler := h.jwtAuth.OptionalMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if teamID, err := h.getIntQuery(r, "teamId"); err == nil {
+					got.TeamID = int64(teamID)
+				}
+				if userID, err := h.getIntQuery(r, "userId"); err == nil {
+					got.UserID = int64(userID)
+				}
+				if h.authorizeStream(w, r, &got) {
+					w.WriteHeader(http.StatusOK)
+				}
+			}))
+
+			req := httptest.NewRequest("GET", "/events/stream"+tt.query, nil)
+			if tt.role != "" {
+				token, err := h.jwtAuth.GenerateToken(1, "user@example.com", tt.role, tt.teamID)
+				if err != nil {
+					t.Fatalf("failed to generate token: %v", err)
+				}
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, w.Code)
+			}
+			if tt.want == http.StatusOK && got != tt.filter {
+				t.Errorf("expected filter %+v, got %+v", tt.filter, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/user/pr-reviewer/internal/audit"
+	"github.com/user/pr-reviewer/internal/models"
+	"github.com/user/pr-reviewer/internal/repository"
+	"github.com/user/pr-reviewer/internal/service"
+)
+
+const errServiceAccountNotFound = "service account not found"
+
+// GetServiceAccounts возвращает все сервисные аккаунты
+func (h *Handler) GetServiceAccounts(w http.ResponseWriter, _ *http.Request) {
+	accounts, err := h.service.GetServiceAccounts()
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to get service accounts")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, accounts)
+}
+
+// CreateServiceAccount создаёт сервисный аккаунт
+func (h *Handler) CreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateServiceAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	account, err := h.service.CreateServiceAccount(&req)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrServiceAccountExists):
+			h.sendError(w, http.StatusConflict, err.Error())
+		case err.Error() == errTeamNotFound:
+			h.sendError(w, http.StatusNotFound, "Team not found")
+		case strings.HasPrefix(err.Error(), "invalid role"):
+			h.sendError(w, http.StatusBadRequest, err.Error())
+		default:
+			h.sendError(w, http.StatusInternalServerError, "Failed to create service account")
+		}
+		return
+	}
+
+	h.recordAudit(r, audit.ActionCreate, audit.EntityServiceAccount, account.ID, audit.Diff(nil, account),
+		"Service account created")
+	h.sendJSON(w, http.StatusCreated, account)
+}
+
+// GetAPIKeys возвращает API ключи сервисного аккаунта
+func (h *Handler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	accountID, err := h.getIntParam(r, "accountId")
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid service account ID")
+		return
+	}
+
+	keys, err := h.service.GetAPIKeys(accountID)
+	if err != nil {
+		if err.Error() == errServiceAccountNotFound {
+			h.sendError(w, http.StatusNotFound, "Service account not found")
+		} else {
+			h.sendError(w, http.StatusInternalServerError, "Failed to get API keys")
+		}
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, keys)
+}
+
+// CreateAPIKey выпускает API ключ. Ключ показывается только в этом ответе.
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	accountID, err := h.getIntParam(r, "accountId")
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid service account ID")
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(accountID, &req)
+	if err != nil {
+		switch {
+		case err.Error() == errServiceAccountNotFound:
+			h.sendError(w, http.StatusNotFound, "Service account not found")
+		case errors.Is(err, service.ErrInvalidScope), strings.HasPrefix(err.Error(), "expiresAt"):
+			h.sendError(w, http.StatusBadRequest, err.Error())
+		default:
+			h.sendError(w, http.StatusInternalServerError, "Failed to create API key")
+		}
+		return
+	}
+
+	// В audit log попадают только метаданные ключа, без самого ключа
+	h.recordAudit(r, audit.ActionCreate, audit.EntityServiceAccount, accountID, audit.Diff(nil, key.APIKey),
+		"API key created")
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.sendJSON(w, http.StatusCreated, key)
+}
+
+// RevokeAPIKey отзывает API ключ
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	accountID, err := h.getIntParam(r, "accountId")
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid service account ID")
+		return
+	}
+	keyID, err := strconv.ParseInt(mux.Vars(r)["keyId"], 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(accountID, keyID); err != nil {
+		if err.Error() == "API key not found" {
+			h.sendError(w, http.StatusNotFound, "API key not found")
+		} else {
+			h.sendError(w, http.StatusInternalServerError, "Failed to revoke API key")
+		}
+		return
+	}
+
+	h.recordAudit(r, audit.ActionDelete, audit.EntityServiceAccount, accountID,
+		map[string]interface{}{"apiKeyId": keyID}, "API key revoked")
+
+	w.WriteHeader(http.StatusNoContent)
+}
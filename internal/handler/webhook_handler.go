@@ -0,0 +1,335 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/audit"
+	"github.com/user/pr-reviewer/internal/webhook"
+)
+
+// ReplayDeadLettersRequest запрос на массовый replay dead letters
+type ReplayDeadLettersRequest struct {
+	SubscriptionID int64      `json:"subscriptionId"`
+	From           *time.Time `json:"from,omitempty"`
+	To             *time.Time `json:"to,omitempty"`
+}
+
+// TestWebhookFilterRequest запрос на проверку фильтра подписки
+type TestWebhookFilterRequest struct {
+	Filter *webhook.Filter        `json:"filter"`
+	Data   map[string]interface{} `json:"data"`
+}
+
+// TestWebhookFilterResponse результат проверки фильтра
+type TestWebhookFilterResponse struct {
+	Matches    bool     `json:"matches"`
+	Mismatches []string `json:"mismatches,omitempty"`
+}
+
+// RotateWebhookSecretRequest запрос на ротацию секрета подписки.
+// Без secret генерируется случайный секрет.
+type RotateWebhookSecretRequest struct {
+	Secret      string `json:"secret,omitempty"`
+	GracePeriod string `json:"gracePeriod,omitempty"`
+}
+
+// RotateWebhookSecretResponse новый секрет подписки; возвращается один раз
+type RotateWebhookSecretResponse struct {
+	SubscriptionID          int64     `json:"subscriptionId"`
+	Secret                  string    `json:"secret"`
+	PreviousSecretExpiresAt time.Time `json:"previousSecretExpiresAt"`
+}
+
+// UpdateWebhookSubscriptionRequest запрос на изменение подписки
+type UpdateWebhookSubscriptionRequest struct {
+	Format *webhook.Format `json:"format,omitempty"`
+}
+
+// defaultSecretGracePeriod сколько старый секрет продолжает подписывать доставки
+const defaultSecretGracePeriod = 24 * time.Hour
+
+// maxSecretGracePeriod ограничение grace периода ротации
+const maxSecretGracePeriod = 30 * 24 * time.Hour
+
+// SetWebhookManager подключает webhook manager для управления dead letters
+func (h *Handler) SetWebhookManager(m *webhook.Manager) {
+	h.webhooks = m
+}
+
+// GetDeadLetters возвращает dead letters с фильтрацией по подписке и периоду
+func (h *Handler) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	filter, err := h.deadLetterFilter(r)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	letters, err := h.webhooks.DeadLetters(r.Context(), filter)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to get dead letters")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, letters)
+}
+
+// ReplayDeadLetter повторно доставляет один dead letter
+func (h *Handler) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := h.getIntParam(r, "id")
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid dead letter ID")
+		return
+	}
+
+	dl, err := h.webhooks.Replay(r.Context(), int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, webhook.ErrDeadLetterNotFound):
+			h.sendError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, webhook.ErrSubscriptionNotFound):
+			h.sendError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, webhook.ErrWebhooksDisabled):
+			h.sendError(w, http.StatusServiceUnavailable, err.Error())
+		case dl != nil:
+			h.sendError(w, http.StatusBadGateway, "Replay failed: "+err.Error())
+		default:
+			h.sendError(w, http.StatusInternalServerError, "Failed to replay dead letter")
+		}
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, dl)
+}
+
+// ListWebhookSubscriptions возвращает подписки без секретов
+func (h *Handler) ListWebhookSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.webhooks.Subscriptions(r.Context())
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to get subscriptions")
+		return
+	}
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	h.sendJSON(w, http.StatusOK, subs)
+}
+
+// UpdateWebhookSubscription меняет формат доставок подписки
+func (h *Handler) UpdateWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := h.getIntParam(r, "id")
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	var req UpdateWebhookSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Format == nil {
+		h.sendError(w, http.StatusBadRequest, "format is required")
+		return
+	}
+
+	sub, err := h.webhooks.SetFormat(r.Context(), int64(id), *req.Format)
+	if err != nil {
+		switch {
+		case errors.Is(err, webhook.ErrInvalidFormat):
+			h.sendError(w, http.StatusBadRequest, "format must be one of: payload, cloudevents, cloudevents-binary")
+		case errors.Is(err, webhook.ErrSubscriptionNotFound):
+			h.sendError(w, http.StatusNotFound, err.Error())
+		default:
+			h.sendError(w, http.StatusInternalServerError, "Failed to update subscription")
+		}
+		return
+	}
+
+	h.recordAudit(r, audit.ActionUpdate, audit.EntityWebhookSubscription, id,
+		map[string]interface{}{"format": sub.Format}, "Webhook subscription format changed")
+	sub.Secret = ""
+	h.sendJSON(w, http.StatusOK, sub)
+}
+
+// EnableWebhookSubscription включает подписку, отключенную после серии ошибок
+func (h *Handler) EnableWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := h.getIntParam(r, "id")
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	sub, err := h.webhooks.EnableSubscription(r.Context(), int64(id))
+	if err != nil {
+		if errors.Is(err, webhook.ErrSubscriptionNotFound) {
+			h.sendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.sendError(w, http.StatusInternalServerError, "Failed to enable subscription")
+		return
+	}
+
+	h.recordAudit(r, audit.ActionUpdate, audit.EntityWebhookSubscription, id,
+		map[string]interface{}{"active": true}, "Webhook subscription enabled")
+	sub.Secret = ""
+	h.sendJSON(w, http.StatusOK, sub)
+}
+
+// RotateWebhookSecret заменяет секрет подписки. Старый секрет продолжает
+// подписывать доставки до окончания grace периода.
+func (h *Handler) RotateWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	id, err := h.getIntParam(r, "id")
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	var req RotateWebhookSecretRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	gracePeriod := defaultSecretGracePeriod
+	if req.GracePeriod != "" {
+		gracePeriod, err = time.ParseDuration(req.GracePeriod)
+		if err != nil || gracePeriod < 0 || gracePeriod > maxSecretGracePeriod {
+			h.sendError(w, http.StatusBadRequest, "gracePeriod must be a duration between 0 and 720h")
+			return
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = webhook.NewSecret(); err != nil {
+			h.sendError(w, http.StatusInternalServerError, "Failed to generate secret")
+			return
+		}
+	}
+
+	expiresAt, err := h.webhooks.RotateSecret(r.Context(), int64(id), secret, gracePeriod)
+	if err != nil {
+		if errors.Is(err, webhook.ErrSubscriptionNotFound) {
+			h.sendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.sendError(w, http.StatusInternalServerError, "Failed to rotate secret")
+		return
+	}
+
+	h.recordAudit(r, audit.ActionUpdate, audit.EntityWebhookSubscription, id,
+		map[string]interface{}{"previous_secret_expires_at": expiresAt}, "Webhook secret rotated")
+	h.sendJSON(w, http.StatusOK, RotateWebhookSecretResponse{
+		SubscriptionID:          int64(id),
+		Secret:                  secret,
+		PreviousSecretExpiresAt: expiresAt,
+	})
+}
+
+// ReplayDeadLetters повторно доставляет dead letters подписки за период
+func (h *Handler) ReplayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	var req ReplayDeadLettersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.SubscriptionID <= 0 {
+		h.sendError(w, http.StatusBadRequest, "subscriptionId is required")
+		return
+	}
+
+	filter := webhook.DeadLetterFilter{SubscriptionID: req.SubscriptionID}
+	if req.From != nil {
+		filter.From = *req.From
+	}
+	if req.To != nil {
+		filter.To = *req.To
+	}
+
+	result, err := h.webhooks.ReplayBulk(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, webhook.ErrWebhooksDisabled) {
+			h.sendError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		h.sendError(w, http.StatusInternalServerError, "Failed to replay dead letters")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, result)
+}
+
+// deadLetterFilter разбирает параметры запроса списка dead letters
+func (h *Handler) deadLetterFilter(r *http.Request) (webhook.DeadLetterFilter, error) {
+	var filter webhook.DeadLetterFilter
+	query := r.URL.Query()
+
+	if v := query.Get("subscriptionId"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return filter, errors.New("invalid subscriptionId")
+		}
+		filter.SubscriptionID = id
+	}
+
+	if v := query.Get("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, errors.New("invalid from, expected RFC3339")
+		}
+		filter.From = from
+	}
+
+	if v := query.Get("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, errors.New("invalid to, expected RFC3339")
+		}
+		filter.To = to
+	}
+
+	if includeReplayed, err := h.getBoolQuery(r, "includeReplayed"); err == nil {
+		filter.IncludeReplayed = includeReplayed
+	}
+
+	if limit, err := h.getIntQuery(r, "limit"); err == nil {
+		filter.Limit = limit
+	}
+
+	if offset, err := h.getIntQuery(r, "offset"); err == nil {
+		filter.Offset = offset
+	}
+
+	return filter, nil
+}
+
+// TestWebhookFilter проверяет фильтр подписки на примере события
+func (h *Handler) TestWebhookFilter(w http.ResponseWriter, r *http.Request) {
+	var req TestWebhookFilterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Data == nil {
+		h.sendError(w, http.StatusBadRequest, "data is required")
+		return
+	}
+	if err := req.Filter.Validate(); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	mismatches := req.Filter.Mismatches(req.Data)
+	h.sendJSON(w, http.StatusOK, TestWebhookFilterResponse{
+		Matches:    len(mismatches) == 0,
+		Mismatches: mismatches,
+	})
+}
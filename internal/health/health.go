@@ -50,17 +50,20 @@ type Health struct {
 	version   string
 	startTime time.Time
 	checkers  []Checker
-	logger    *logger.Logger
-	mu        sync.RWMutex
+	// nonCritical проверки, не влияющие на readiness
+	nonCritical map[string]bool
+	logger      *logger.Logger
+	mu          sync.RWMutex
 }
 
 // New создает новый health checker
 func New(version string, log *logger.Logger) *Health {
 	return &Health{
-		version:   version,
-		startTime: time.Now(),
-		checkers:  make([]Checker, 0),
-		logger:    log,
+		version:     version,
+		startTime:   time.Now(),
+		checkers:    make([]Checker, 0),
+		nonCritical: make(map[string]bool),
+		logger:      log,
 	}
 }
 
@@ -71,6 +74,15 @@ func (h *Health) RegisterChecker(checker Checker) {
 	h.checkers = append(h.checkers, checker)
 }
 
+// RegisterNonCritical регистрирует проверку, которая отражается в /health,
+// но не снимает реплику с балансировки (readiness)
+func (h *Health) RegisterNonCritical(checker Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checkers = append(h.checkers, checker)
+	h.nonCritical[checker.Name()] = true
+}
+
 // Check выполняет все проверки здоровья
 func (h *Health) Check(ctx context.Context) HealthResponse {
 	checks := make(map[string]CheckResult)
@@ -147,8 +159,8 @@ func (h *Health) ReadinessHandler() http.HandlerFunc {
 		ctx := r.Context()
 		response := h.Check(ctx)
 
-		// Readiness probe должен возвращать 200 только если все healthy
-		if response.Status == StatusHealthy {
+		// Readiness probe должен возвращать 200 только если все критичные проверки healthy
+		if h.ready(response) {
 			w.WriteHeader(http.StatusOK)
 			_, _ = w.Write([]byte("ready"))
 		} else {
@@ -158,6 +170,19 @@ func (h *Health) ReadinessHandler() http.HandlerFunc {
 	}
 }
 
+// ready проверяет, что все критичные проверки healthy
+func (h *Health) ready(response HealthResponse) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for name, result := range response.Checks {
+		if result.Status != StatusHealthy && !h.nonCritical[name] {
+			return false
+		}
+	}
+	return true
+}
+
 // LivenessHandler HTTP handler для Kubernetes liveness probe
 func (h *Health) LivenessHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
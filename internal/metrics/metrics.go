@@ -26,6 +26,9 @@ type Metrics struct {
 	ReviewersAssignedTotal prometheus.Counter
 	UsersDeactivatedTotal  prometheus.Counter
 
+	// Feature flags метрики
+	FeatureFlagEvaluationsTotal *prometheus.CounterVec
+
 	// Application метрики
 	AppUptime prometheus.Gauge
 	AppInfo   *prometheus.GaugeVec
@@ -117,6 +120,16 @@ func Init(namespace string) *Metrics {
 			},
 		),
 
+		// Feature flags метрики
+		FeatureFlagEvaluationsTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "feature_flag_evaluations_total",
+				Help:      "Total number of feature flag evaluations",
+			},
+			[]string{"flag", "enabled", "reason"},
+		),
+
 		// Application метрики
 		AppUptime: promauto.NewGauge(
 			prometheus.GaugeOpts{
@@ -154,6 +167,11 @@ func (m *Metrics) RecordDBQuery(queryType string, duration time.Duration) {
 	m.DBQueryDuration.WithLabelValues(queryType).Observe(duration.Seconds())
 }
 
+// RecordFlagEvaluation записывает вычисление feature flag
+func (m *Metrics) RecordFlagEvaluation(flag string, enabled bool, reason string) {
+	m.FeatureFlagEvaluationsTotal.WithLabelValues(flag, strconv.FormatBool(enabled), reason).Inc()
+}
+
 // IncrementInFlightRequests увеличивает счетчик активных запросов
 func (m *Metrics) IncrementInFlightRequests() {
 	m.HTTPRequestsInFlight.Inc()
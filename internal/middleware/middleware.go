@@ -1,14 +1,17 @@
 package middleware
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/user/pr-reviewer/internal/featureflags"
 	"github.com/user/pr-reviewer/internal/logger"
 	"github.com/user/pr-reviewer/internal/metrics"
 	"golang.org/x/time/rate"
@@ -39,6 +42,7 @@ type Middleware struct {
 	logger  *logger.Logger
 	metrics *metrics.Metrics
 	limiter *RateLimiter
+	flags   *featureflags.Manager
 }
 
 // New создает новый экземпляр middleware
@@ -50,6 +54,12 @@ func New(log *logger.Logger, met *metrics.Metrics) *Middleware {
 	}
 }
 
+// SetFeatureFlags подключает feature flags: флаг rate_limiting проверяется
+// на каждом запросе
+func (m *Middleware) SetFeatureFlags(flags *featureflags.Manager) {
+	m.flags = flags
+}
+
 // RequestID добавляет уникальный ID к каждому запросу
 func (m *Middleware) RequestID(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -133,6 +143,11 @@ func (m *Middleware) Metrics(next http.Handler) http.Handler {
 // RateLimit ограничивает количество запросов
 func (m *Middleware) RateLimit(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.flags != nil && !m.flags.IsEnabled(featureflags.FlagRateLimiting) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		// Используем IP адрес как ключ
 		ip := getIP(r)
 
@@ -254,6 +269,16 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap дает http.ResponseController доступ к Flush и дедлайнам
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Hijack нужен для перехода соединения на WebSocket
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(rw.ResponseWriter).Hijack()
+}
+
 // RateLimiter реализует rate limiting per IP
 type RateLimiter struct {
 	visitors map[string]*rate.Limiter
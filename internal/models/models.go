@@ -8,14 +8,19 @@ import (
 
 // User представляет пользователя системы
 type User struct {
-	ID        int       `json:"id" db:"id"`
-	Username  string    `json:"username" db:"username"`
-	Name      string    `json:"name" db:"name"`
-	IsActive  bool      `json:"isActive" db:"is_active"`
-	TeamID    *int      `json:"teamId,omitempty" db:"team_id"`
-	Teams     []Team    `json:"teams,omitempty"`
-	CreatedAt time.Time `json:"createdAt" db:"created_at"`
-	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+	ID         int    `json:"id" db:"id"`
+	Username   string `json:"username" db:"username"`
+	Name       string `json:"name" db:"name"`
+	IsActive   bool   `json:"isActive" db:"is_active"`
+	TeamID     *int   `json:"teamId,omitempty" db:"team_id"`
+	ChatHandle string `json:"chatHandle,omitempty" db:"chat_handle"`
+	Email      string `json:"email,omitempty" db:"email"`
+	Role       string `json:"role" db:"role"`
+	// PasswordHash загружается только для проверки пароля
+	PasswordHash string    `json:"-" db:"password_hash"`
+	Teams        []Team    `json:"teams,omitempty"`
+	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time `json:"updatedAt" db:"updated_at"`
 }
 
 // Team представляет команду
@@ -70,16 +75,18 @@ func (s *PRStatus) UnmarshalJSON(data []byte) error {
 
 // PullRequest представляет Pull Request
 type PullRequest struct {
-	ID        int        `json:"id" db:"id"`
-	Title     string     `json:"title" db:"title"`
-	AuthorID  int        `json:"authorId" db:"author_id"`
-	Author    *User      `json:"author,omitempty"`
-	Team      *Team      `json:"team,omitempty"`
-	Status    PRStatus   `json:"status" db:"status"`
-	Reviewers []User     `json:"reviewers"`
-	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
-	MergedAt  *time.Time `json:"mergedAt,omitempty" db:"merged_at"`
-	UpdatedAt time.Time  `json:"updatedAt" db:"updated_at"`
+	ID         int        `json:"id" db:"id"`
+	Title      string     `json:"title" db:"title"`
+	Repository string     `json:"repository,omitempty" db:"repository"`
+	Labels     []string   `json:"labels" db:"labels"`
+	AuthorID   int        `json:"authorId" db:"author_id"`
+	Author     *User      `json:"author,omitempty"`
+	Team       *Team      `json:"team,omitempty"`
+	Status     PRStatus   `json:"status" db:"status"`
+	Reviewers  []User     `json:"reviewers"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	MergedAt   *time.Time `json:"mergedAt,omitempty" db:"merged_at"`
+	UpdatedAt  time.Time  `json:"updatedAt" db:"updated_at"`
 }
 
 // PRReviewer представляет связь между PR и рецензентом
@@ -95,21 +102,181 @@ type CreateTeamRequest struct {
 
 // CreateUserRequest запрос на создание пользователя
 type CreateUserRequest struct {
-	Username string `json:"username" validate:"required,min=1,max=100"`
-	Name     string `json:"name" validate:"required,min=1,max=100"`
-	TeamID   *int   `json:"teamId,omitempty"`
+	Username   string `json:"username" validate:"required,min=1,max=100"`
+	Name       string `json:"name" validate:"required,min=1,max=100"`
+	TeamID     *int   `json:"teamId,omitempty"`
+	ChatHandle string `json:"chatHandle,omitempty" validate:"omitempty,max=100"`
+	Email      string `json:"email,omitempty" validate:"omitempty,email,max=255"`
+	Role       string `json:"role,omitempty" validate:"omitempty,oneof=admin team_lead member readonly"`
+	Password   string `json:"password,omitempty" validate:"omitempty,min=8,max=72"`
 }
 
 // UpdateUserRequest запрос на обновление пользователя
 type UpdateUserRequest struct {
-	Name     *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
-	IsActive *bool   `json:"isActive,omitempty"`
+	Name       *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	IsActive   *bool   `json:"isActive,omitempty"`
+	ChatHandle *string `json:"chatHandle,omitempty" validate:"omitempty,max=100"`
+	Email      *string `json:"email,omitempty" validate:"omitempty,email,max=255"`
+	Role       *string `json:"role,omitempty" validate:"omitempty,oneof=admin team_lead member readonly"`
+	Password   *string `json:"password,omitempty" validate:"omitempty,min=8,max=72"`
+}
+
+// LoginRequest запрос на вход по логину и паролю
+type LoginRequest struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// RefreshTokenRequest запрос на обновление или отзыв refresh токена
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// AuthResponse выданные токены и текущий пользователь
+type AuthResponse struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int    `json:"expiresIn"`
+	User         *User  `json:"user"`
+}
+
+// RefreshToken сохраненный refresh токен (только хеш)
+type RefreshToken struct {
+	ID         int64      `db:"id"`
+	UserID     int        `db:"user_id"`
+	TokenHash  string     `db:"token_hash"`
+	FamilyID   string     `db:"family_id"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+	ReplacedBy *int64     `db:"replaced_by"`
+	UserAgent  string     `db:"user_agent"`
+	IP         string     `db:"ip"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+// ServiceAccount сервисный аккаунт (CI бот, интеграция) для доступа по API ключу
+type ServiceAccount struct {
+	ID          int       `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description,omitempty" db:"description"`
+	Role        string    `json:"role" db:"role"`
+	TeamID      *int      `json:"teamId,omitempty" db:"team_id"`
+	IsActive    bool      `json:"isActive" db:"is_active"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// CreateServiceAccountRequest запрос на создание сервисного аккаунта
+type CreateServiceAccountRequest struct {
+	Name        string `json:"name" validate:"required,min=1,max=100"`
+	Description string `json:"description,omitempty"`
+	Role        string `json:"role,omitempty" validate:"omitempty,oneof=admin team_lead member readonly"`
+	TeamID      *int   `json:"teamId,omitempty"`
+}
+
+// APIKey API ключ сервисного аккаунта. Сам ключ не хранится, только хеш.
+type APIKey struct {
+	ID               int64      `json:"id" db:"id"`
+	ServiceAccountID int        `json:"serviceAccountId" db:"service_account_id"`
+	Name             string     `json:"name" db:"name"`
+	Prefix           string     `json:"prefix" db:"prefix"`
+	KeyHash          string     `json:"-" db:"key_hash"`
+	Scopes           []string   `json:"scopes" db:"scopes"`
+	ExpiresAt        *time.Time `json:"expiresAt,omitempty" db:"expires_at"`
+	LastUsedAt       *time.Time `json:"lastUsedAt,omitempty" db:"last_used_at"`
+	RevokedAt        *time.Time `json:"revokedAt,omitempty" db:"revoked_at"`
+	CreatedAt        time.Time  `json:"createdAt" db:"created_at"`
+}
+
+// CreateAPIKeyRequest запрос на выпуск API ключа
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,min=1,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// CreateAPIKeyResponse выпущенный ключ. Key возвращается только один раз.
+type CreateAPIKeyResponse struct {
+	*APIKey
+	Key string `json:"key"`
+}
+
+// RevokeTokensRequest запрос на отзыв access токенов: один токен по ID (jti)
+// или все токены пользователя
+type RevokeTokensRequest struct {
+	TokenID string `json:"tokenId,omitempty"`
+	UserID  *int   `json:"userId,omitempty"`
+}
+
+// SigningKey ключ подписи access токенов (приватный ключ в PKCS#8 PEM,
+// зашифрованный при заданном KEK, см. auth.KeyEncryptor)
+type SigningKey struct {
+	ID         string     `json:"id" db:"id"`
+	Algorithm  string     `json:"algorithm" db:"algorithm"`
+	PrivateKey []byte     `json:"-" db:"private_key"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	RetiredAt  *time.Time `json:"retiredAt,omitempty" db:"retired_at"`
+}
+
+// NotificationPreferences настройки уведомлений пользователя (opt-in)
+type NotificationPreferences struct {
+	UserID           int        `json:"userId" db:"user_id"`
+	EmailAssignments bool       `json:"emailAssignments" db:"email_assignments"`
+	EmailDigest      bool       `json:"emailDigest" db:"email_digest"`
+	InboxEvents      []string   `json:"inboxEvents" db:"inbox_events"`
+	LastDigestOn     *time.Time `json:"lastDigestOn,omitempty" db:"last_digest_on"`
+	UpdatedAt        time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+// UpdateNotificationPreferencesRequest запрос на изменение настроек уведомлений
+type UpdateNotificationPreferencesRequest struct {
+	EmailAssignments *bool `json:"emailAssignments,omitempty"`
+	EmailDigest      *bool `json:"emailDigest,omitempty"`
+	// InboxEvents события для inbox уведомлений; пустой список отключает уведомления
+	InboxEvents *[]string `json:"inboxEvents,omitempty"`
+}
+
+// WantsInboxEvent проверяет, нужно ли создавать уведомление для события
+func (p *NotificationPreferences) WantsInboxEvent(event string) bool {
+	if p.InboxEvents == nil {
+		return true
+	}
+	for _, e := range p.InboxEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Notification уведомление пользователя в приложении
+type Notification struct {
+	ID        int64      `json:"id" db:"id"`
+	UserID    int        `json:"userId" db:"user_id"`
+	Event     string     `json:"event" db:"event"`
+	PRID      *int       `json:"prId,omitempty" db:"pr_id"`
+	Title     string     `json:"title" db:"title"`
+	Message   string     `json:"message" db:"message"`
+	OutboxID  *int64     `json:"-" db:"outbox_id"`
+	ReadAt    *time.Time `json:"readAt,omitempty" db:"read_at"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+}
+
+// NotificationList страница уведомлений
+type NotificationList struct {
+	Notifications []*Notification `json:"notifications"`
+	UnreadCount   int             `json:"unreadCount"`
+	Limit         int             `json:"limit"`
+	Offset        int             `json:"offset"`
 }
 
 // CreatePullRequestRequest запрос на создание PR
 type CreatePullRequestRequest struct {
-	Title    string `json:"title" validate:"required,min=1,max=255"`
-	AuthorID int    `json:"authorId" validate:"required,min=1"`
+	Title      string   `json:"title" validate:"required,min=1,max=255"`
+	AuthorID   int      `json:"authorId" validate:"required,min=1"`
+	Repository string   `json:"repository,omitempty" validate:"omitempty,max=255"`
+	Labels     []string `json:"labels,omitempty" validate:"omitempty,max=20,dive,min=1,max=50"`
 }
 
 // ReassignReviewerRequest запрос на переназначение рецензента
@@ -0,0 +1,220 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/logger"
+	"github.com/user/pr-reviewer/internal/models"
+)
+
+// DigestSource источник данных для дайджеста
+type DigestSource interface {
+	GetDigestRecipients() ([]*models.User, error)
+	GetPendingReviews(userID int) ([]*models.PullRequest, error)
+	ClaimDigest(userID int, day time.Time) (bool, error)
+	ReleaseDigest(userID int, day time.Time) error
+}
+
+// digestRetryDelay через сколько повторить дайджесты, которые не удалось отправить
+const digestRetryDelay = 15 * time.Minute
+
+// errDigestsNotSent часть дайджестов не отправлена; резерв снят, и они
+// будут отправлены при повторе
+var errDigestsNotSent = errors.New("some digests were not sent")
+
+// digestTemplate письмо с ожидающими ревью
+var digestTemplate = template.Must(template.New("digest").Parse(
+	`Good morning {{.Name}},
+
+You have {{len .Items}} pull request(s) waiting for your review:
+{{range .Items}}
+  - {{.Title}} (open for {{.Age}}){{if .URL}}
+    {{.URL}}{{end}}
+{{end}}
+--
+PR Reviewer
+`))
+
+type digestItem struct {
+	Title string
+	Age   string
+	URL   string
+}
+
+// DigestScheduler раз в день отправляет пользователям список ожидающих ревью
+type DigestScheduler struct {
+	source   DigestSource
+	mailer   Mailer
+	hour     int
+	location *time.Location
+	baseURL  string
+	now      func() time.Time
+	logger   *logger.Logger
+}
+
+// NewDigestScheduler создает планировщик дайджеста, отправляющий письма в hour часов
+func NewDigestScheduler(source DigestSource, mailer Mailer, hour int, loc *time.Location, baseURL string, log *logger.Logger) *DigestScheduler {
+	if loc == nil {
+		loc = time.Local
+	}
+	return &DigestScheduler{
+		source:   source,
+		mailer:   mailer,
+		hour:     hour,
+		location: loc,
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		now:      time.Now,
+		logger:   log,
+	}
+}
+
+// Run отправляет дайджест каждый день до отмены контекста
+func (s *DigestScheduler) Run(ctx context.Context) {
+	s.logger.Infow("Digest scheduler started", "hour", s.hour, "location", s.location.String())
+
+	retryAt := time.Time{}
+	for {
+		next := s.nextRun()
+		if !retryAt.IsZero() && retryAt.Before(next) {
+			next = retryAt
+		}
+		retryAt = time.Time{}
+		timer := time.NewTimer(next.Sub(s.now()))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.logger.Info("Digest scheduler stopped")
+			return
+		case <-timer.C:
+			sent, err := s.SendDigests(ctx)
+			if err != nil {
+				s.logger.Errorw("Failed to send digests", "sent", sent, "error", err)
+				// Неотправленные дайджесты повторяются до следующей плановой отправки
+				if errors.Is(err, errDigestsNotSent) {
+					retryAt = s.now().Add(digestRetryDelay)
+				}
+			} else {
+				s.logger.Infow("Digests sent", "count", sent)
+			}
+		}
+	}
+}
+
+// nextRun возвращает ближайшее время отправки
+func (s *DigestScheduler) nextRun() time.Time {
+	now := s.now().In(s.location)
+	next := time.Date(now.Year(), now.Month(), now.Day(), s.hour, 0, 0, 0, s.location)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// SendDigests отправляет дайджест всем подписанным пользователям и возвращает
+// количество отправленных писем. Пользователи без ожидающих ревью пропускаются.
+// Если письмо не отправлено, резерв снимается и возвращается errDigestsNotSent.
+func (s *DigestScheduler) SendDigests(ctx context.Context) (int, error) {
+	recipients, err := s.source.GetDigestRecipients()
+	if err != nil {
+		return 0, err
+	}
+
+	now := s.now().In(s.location)
+	sent, failed := 0, 0
+	for _, user := range recipients {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+
+		prs, err := s.source.GetPendingReviews(user.ID)
+		if err != nil {
+			s.logger.Warnw("Failed to get pending reviews", "user_id", user.ID, "error", err)
+			continue
+		}
+		if len(prs) == 0 {
+			continue
+		}
+
+		// Резервируем отправку, чтобы несколько реплик не отправили дайджест дважды
+		claimed, err := s.source.ClaimDigest(user.ID, now)
+		if err != nil {
+			s.logger.Warnw("Failed to claim digest", "user_id", user.ID, "error", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		msg, err := s.buildDigest(user, prs, now)
+		if err != nil {
+			s.release(user.ID, now)
+			return sent, err
+		}
+
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			s.logger.Errorw("Failed to send digest", "user_id", user.ID, "error", err)
+			s.release(user.ID, now)
+			failed++
+			continue
+		}
+		sent++
+	}
+
+	if failed > 0 {
+		return sent, fmt.Errorf("%w: %d failed", errDigestsNotSent, failed)
+	}
+	return sent, nil
+}
+
+// release снимает резерв дайджеста, чтобы он отправился при повторе
+func (s *DigestScheduler) release(userID int, day time.Time) {
+	if err := s.source.ReleaseDigest(userID, day); err != nil {
+		s.logger.Errorw("Failed to release digest", "user_id", userID, "error", err)
+	}
+}
+
+// buildDigest формирует письмо дайджеста
+func (s *DigestScheduler) buildDigest(user *models.User, prs []*models.PullRequest, now time.Time) (*Message, error) {
+	items := make([]digestItem, 0, len(prs))
+	for _, pr := range prs {
+		items = append(items, digestItem{
+			Title: pr.Title,
+			Age:   formatAge(now.Sub(pr.CreatedAt)),
+			URL:   prURL(s.baseURL, pr.ID),
+		})
+	}
+
+	var body bytes.Buffer
+	err := digestTemplate.Execute(&body, map[string]interface{}{
+		"Name":  user.Name,
+		"Items": items,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render digest: %w", err)
+	}
+
+	return &Message{
+		To:      []string{user.Email},
+		Subject: fmt.Sprintf("%d pull request(s) waiting for your review", len(prs)),
+		Body:    body.String(),
+	}, nil
+}
+
+// formatAge форматирует возраст PR для письма
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return "less than an hour"
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
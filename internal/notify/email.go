@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/user/pr-reviewer/internal/logger"
+	"github.com/user/pr-reviewer/internal/models"
+	"github.com/user/pr-reviewer/internal/webhook"
+)
+
+// EmailHandlerName имя EmailNotifier в outbox relay
+const EmailHandlerName = "email"
+
+// Recipients источник данных о получателях уведомлений
+type Recipients interface {
+	GetUser(id int) (*models.User, error)
+	GetNotificationPreferences(userID int) (*models.NotificationPreferences, error)
+	ClaimAssignmentEmail(outboxID int64, userID int) (bool, error)
+	ReleaseAssignmentEmail(outboxID int64, userID int) error
+}
+
+// assignmentTemplate письмо о назначении рецензентом
+var assignmentTemplate = template.Must(template.New("assignment").Parse(
+	`Hi {{.Name}},
+
+You have been assigned to review "{{.Title}}".
+{{if .URL}}
+Open it here: {{.URL}}
+{{end}}
+--
+PR Reviewer
+`))
+
+// EmailNotifier отправляет письма о назначении и переназначении рецензентов.
+// Реализует webhook.EventHandler и подключается к outbox relay.
+type EmailNotifier struct {
+	mailer     Mailer
+	recipients Recipients
+	baseURL    string
+	logger     *logger.Logger
+}
+
+// NewEmailNotifier создает новый email notifier
+func NewEmailNotifier(mailer Mailer, recipients Recipients, baseURL string, log *logger.Logger) *EmailNotifier {
+	return &EmailNotifier{
+		mailer:     mailer,
+		recipients: recipients,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		logger:     log,
+	}
+}
+
+// HandleEvent отправляет письмо новому рецензенту, если он включил такие
+// уведомления. Relay доставляет события at-least-once, поэтому отправка
+// резервируется по (outbox_id, user_id) и не повторяется.
+func (n *EmailNotifier) HandleEvent(ctx context.Context, event *webhook.OutboxEvent) error {
+	var field string
+	switch event.Event {
+	case webhook.EventReviewerAssigned:
+		field = "reviewer_id"
+	case webhook.EventReviewerChanged:
+		field = "new_reviewer_id"
+	default:
+		return nil
+	}
+
+	reviewerID, ok := intField(event.Data, field)
+	if !ok {
+		return nil
+	}
+
+	user, err := n.recipients.GetUser(reviewerID)
+	if err != nil || user.Email == "" || !user.IsActive {
+		return nil
+	}
+
+	prefs, err := n.recipients.GetNotificationPreferences(reviewerID)
+	if err != nil {
+		return fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	if !prefs.EmailAssignments {
+		return nil
+	}
+
+	prID, _ := intField(event.Data, "pr_id")
+	title, _ := event.Data["title"].(string)
+	if title == "" {
+		title = fmt.Sprintf("PR #%d", prID)
+	}
+
+	var body bytes.Buffer
+	err = assignmentTemplate.Execute(&body, map[string]interface{}{
+		"Name":  user.Name,
+		"Title": title,
+		"URL":   n.prURL(prID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render assignment email: %w", err)
+	}
+
+	claimed, err := n.recipients.ClaimAssignmentEmail(event.ID, reviewerID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		n.logger.Debugw("Assignment email already sent", "user_id", reviewerID, "outbox_id", event.ID)
+		return nil
+	}
+
+	msg := &Message{
+		To:      []string{user.Email},
+		Subject: "Review requested: " + title,
+		Body:    body.String(),
+	}
+	if err := n.mailer.Send(ctx, msg); err != nil {
+		n.logger.Errorw("Failed to send assignment email",
+			"user_id", reviewerID,
+			"pr_id", prID,
+			"error", err,
+		)
+		// Снимаем резерв, чтобы письмо отправилось при повторной доставке
+		if releaseErr := n.recipients.ReleaseAssignmentEmail(event.ID, reviewerID); releaseErr != nil {
+			n.logger.Errorw("Failed to release assignment email", "user_id", reviewerID, "error", releaseErr)
+		}
+		return err
+	}
+
+	n.logger.Debugw("Assignment email sent", "user_id", reviewerID, "pr_id", prID)
+	return nil
+}
+
+// prURL формирует ссылку на PR в веб-интерфейсе
+func (n *EmailNotifier) prURL(prID int) string {
+	return prURL(n.baseURL, prID)
+}
+
+func prURL(baseURL string, prID int) string {
+	if baseURL == "" || prID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s/pull-requests?id=%d", baseURL, prID)
+}
+
+// intField читает числовое поле данных события
+func intField(data map[string]interface{}, key string) (int, bool) {
+	return toInt(data[key])
+}
+
+// toInt приводит число к int (в событиях из outbox числа приходят как float64)
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,150 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/logger"
+	"github.com/user/pr-reviewer/internal/models"
+	"github.com/user/pr-reviewer/internal/webhook"
+)
+
+// InboxHandlerName имя Inbox в outbox relay
+const InboxHandlerName = "inbox"
+
+// InboxStore хранилище уведомлений в приложении
+type InboxStore interface {
+	GetPullRequest(id int) (*models.PullRequest, error)
+	GetNotificationPreferences(userID int) (*models.NotificationPreferences, error)
+	CreateNotification(n *models.Notification) error
+	DeleteNotificationsBefore(cutoff time.Time) (int64, error)
+}
+
+// Inbox создает уведомления в приложении по событиям PR и рецензентов.
+// Реализует webhook.EventHandler и подключается к outbox relay.
+type Inbox struct {
+	store  InboxStore
+	logger *logger.Logger
+}
+
+// NewInbox создает обработчик inbox уведомлений
+func NewInbox(store InboxStore, log *logger.Logger) *Inbox {
+	return &Inbox{
+		store:  store,
+		logger: log,
+	}
+}
+
+// inboxRecipient получатель уведомления и его текст
+type inboxRecipient struct {
+	userID  int
+	message string
+}
+
+// HandleEvent создает уведомления для участников события
+func (i *Inbox) HandleEvent(_ context.Context, event *webhook.OutboxEvent) error {
+	prID, ok := intField(event.Data, "pr_id")
+	if !ok {
+		return nil
+	}
+
+	title, _ := event.Data["title"].(string)
+	if title == "" {
+		if pr, err := i.store.GetPullRequest(prID); err == nil {
+			title = pr.Title
+		} else {
+			title = fmt.Sprintf("PR #%d", prID)
+		}
+	}
+
+	for _, r := range recipientsFor(event, title) {
+		prefs, err := i.store.GetNotificationPreferences(r.userID)
+		if err != nil {
+			return fmt.Errorf("failed to get notification preferences: %w", err)
+		}
+		if !prefs.WantsInboxEvent(string(event.Event)) {
+			continue
+		}
+
+		outboxID := event.ID
+		pr := prID
+		n := &models.Notification{
+			UserID:   r.userID,
+			Event:    string(event.Event),
+			PRID:     &pr,
+			Title:    title,
+			Message:  r.message,
+			OutboxID: &outboxID,
+		}
+		if err := i.store.CreateNotification(n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recipientsFor определяет получателей уведомлений по событию
+func recipientsFor(event *webhook.OutboxEvent, title string) []inboxRecipient {
+	data := event.Data
+	var result []inboxRecipient
+	add := func(field, message string) {
+		if id, ok := intField(data, field); ok {
+			result = append(result, inboxRecipient{userID: id, message: message})
+		}
+	}
+	addReviewers := func(message string) {
+		switch ids := data[webhook.FieldReviewerIDs].(type) {
+		case []int:
+			for _, id := range ids {
+				result = append(result, inboxRecipient{userID: id, message: message})
+			}
+		case []interface{}:
+			for _, v := range ids {
+				if id, ok := toInt(v); ok {
+					result = append(result, inboxRecipient{userID: id, message: message})
+				}
+			}
+		}
+	}
+
+	switch event.Event {
+	case webhook.EventPRCreated:
+		addReviewers(fmt.Sprintf("You were assigned to review \"%s\"", title))
+	case webhook.EventReviewerAssigned:
+		add("reviewer_id", fmt.Sprintf("You were assigned to review \"%s\"", title))
+	case webhook.EventReviewerChanged:
+		add("new_reviewer_id", fmt.Sprintf("You were assigned to review \"%s\"", title))
+		add("old_reviewer_id", fmt.Sprintf("You are no longer reviewing \"%s\"", title))
+	case webhook.EventPRMerged:
+		add(webhook.FieldAuthorID, fmt.Sprintf("Your pull request \"%s\" was merged", title))
+		addReviewers(fmt.Sprintf("\"%s\" was merged", title))
+	case webhook.EventPRClosed:
+		add(webhook.FieldAuthorID, fmt.Sprintf("Your pull request \"%s\" was closed", title))
+		addReviewers(fmt.Sprintf("\"%s\" was closed", title))
+	}
+
+	return result
+}
+
+// RunRetention периодически удаляет уведомления старше retention
+func (i *Inbox) RunRetention(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := i.store.DeleteNotificationsBefore(time.Now().Add(-retention))
+		if err != nil {
+			i.logger.Errorw("Failed to clean up notifications", "error", err)
+		} else if deleted > 0 {
+			i.logger.Infow("Old notifications deleted", "count", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/user/pr-reviewer/internal/models"
+	"github.com/user/pr-reviewer/internal/webhook"
+)
+
+func TestRecipientsFor(t *testing.T) {
+	// Данные как после чтения из outbox (JSON round-trip)
+	var data map[string]interface{}
+	raw := `{"pr_id": 5, "author_id": 1, "reviewer_ids": [2, 3], "old_reviewer_id": 4, "new_reviewer_id": 3}`
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+
+	tests := []struct {
+		event webhook.EventType
+		want  []int
+	}{
+		{webhook.EventPRCreated, []int{2, 3}},
+		{webhook.EventReviewerChanged, []int{3, 4}},
+		{webhook.EventPRMerged, []int{1, 2, 3}},
+		{webhook.EventUserDeactivated, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.event), func(t *testing.T) {
+			got := recipientsFor(&webhook.OutboxEvent{Event: tt.event, Data: data}, "Add caching")
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d recipients, got %d", len(tt.want), len(got))
+			}
+			for i, r := range got {
+				if r.userID != tt.want[i] {
+					t.Errorf("recipient %d: expected user %d, got %d", i, tt.want[i], r.userID)
+				}
+			}
+		})
+	}
+}
+
+func TestNotificationPreferences_WantsInboxEvent(t *testing.T) {
+	prefs := &models.NotificationPreferences{}
+	if !prefs.WantsInboxEvent("pr.merged") {
+		t.Error("expected all events by default")
+	}
+
+	prefs.InboxEvents = []string{"reviewer.assigned"}
+	if prefs.WantsInboxEvent("pr.merged") {
+		t.Error("expected pr.merged to be filtered out")
+	}
+	if !prefs.WantsInboxEvent("reviewer.assigned") {
+		t.Error("expected reviewer.assigned to be allowed")
+	}
+}
@@ -0,0 +1,145 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+var ErrStartTLSUnsupported = errors.New("smtp server does not support STARTTLS")
+
+// Message email сообщение
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer отправляет email
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// SMTPConfig конфигурация SMTP
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+
+	// AllowInsecure разрешает отправку без STARTTLS (только для локальной разработки)
+	AllowInsecure bool
+	// TLSConfig переопределяет настройки TLS (например, корневые сертификаты в тестах)
+	TLSConfig *tls.Config
+	Timeout   time.Duration
+}
+
+// SMTPMailer отправляет письма через SMTP с STARTTLS
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+// NewSMTPMailer создает новый SMTP mailer
+func NewSMTPMailer(cfg SMTPConfig) *SMTPMailer {
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return &SMTPMailer{config: cfg}
+}
+
+// Send отправляет письмо. Соединение всегда переводится в TLS через STARTTLS,
+// если только AllowInsecure не включен явно.
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	if len(msg.To) == 0 {
+		return errors.New("message has no recipients")
+	}
+
+	addr := net.JoinHostPort(m.config.Host, fmt.Sprintf("%d", m.config.Port))
+	dialer := &net.Dialer{Timeout: m.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+
+	deadline := time.Now().Add(m.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to create smtp client: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		tlsConfig := m.config.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: m.config.Host, MinVersion: tls.VersionTLS12}
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("failed to start tls: %w", err)
+		}
+	} else if !m.config.AllowInsecure {
+		return ErrStartTLSUnsupported
+	}
+
+	if m.config.Username != "" {
+		auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := client.Mail(m.config.From); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	for _, rcpt := range msg.To {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("failed to set recipient %s: %w", rcpt, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message data: %w", err)
+	}
+	if _, err := w.Write(m.compose(msg)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// compose формирует RFC 5322 сообщение в UTF-8
+func (m *SMTPMailer) compose(msg *Message) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + m.config.From + "\r\n")
+	buf.WriteString("To: " + strings.Join(msg.To, ", ") + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return buf.Bytes()
+}
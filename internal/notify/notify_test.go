@@ -0,0 +1,426 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/logger"
+	"github.com/user/pr-reviewer/internal/models"
+	"github.com/user/pr-reviewer/internal/webhook"
+)
+
+// fakeSMTPServer минимальный SMTP сервер с поддержкой STARTTLS и AUTH PLAIN
+type fakeSMTPServer struct {
+	listener net.Listener
+	tls      *tls.Config
+	noTLS    bool
+
+	mu       sync.Mutex
+	upgraded bool
+	from     string
+	rcpts    []string
+	data     string
+}
+
+func newFakeSMTPServer(t *testing.T, noTLS bool) (*fakeSMTPServer, *x509.CertPool) {
+	t.Helper()
+
+	cert, pool := selfSignedCert(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	s := &fakeSMTPServer{
+		listener: ln,
+		tls:      &tls.Config{Certificates: []tls.Certificate{cert}},
+		noTLS:    noTLS,
+	}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s, pool
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	reply := func(line string) {
+		rw.WriteString(line + "\r\n")
+		rw.Flush()
+	}
+
+	reply("220 localhost ESMTP fake")
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			if s.noTLS || s.upgraded {
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			} else {
+				reply("250-localhost")
+				reply("250 STARTTLS")
+			}
+		case cmd == "STARTTLS":
+			reply("220 Ready to start TLS")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			rw = bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+			s.mu.Lock()
+			s.upgraded = true
+			s.mu.Unlock()
+		case strings.HasPrefix(cmd, "AUTH"):
+			reply("235 Authentication successful")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.mu.Lock()
+			s.from = strings.Trim(strings.TrimSpace(line)[10:], "<>")
+			s.mu.Unlock()
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, strings.Trim(strings.TrimSpace(line)[8:], "<>"))
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := rw.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+func TestSMTPMailer_SendWithStartTLS(t *testing.T) {
+	server, pool := newFakeSMTPServer(t, false)
+
+	mailer := NewSMTPMailer(SMTPConfig{
+		Host:      "127.0.0.1",
+		Port:      server.port(),
+		Username:  "user",
+		Password:  "secret",
+		From:      "reviewer@example.com",
+		TLSConfig: &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"},
+		Timeout:   5 * time.Second,
+	})
+
+	err := mailer.Send(context.Background(), &Message{
+		To:      []string{"alice@example.com"},
+		Subject: "Review requested: Add caching",
+		Body:    "line one\nline two",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if !server.upgraded {
+		t.Error("expected connection to be upgraded with STARTTLS")
+	}
+	if server.from != "reviewer@example.com" {
+		t.Errorf("unexpected sender: %s", server.from)
+	}
+	if len(server.rcpts) != 1 || server.rcpts[0] != "alice@example.com" {
+		t.Errorf("unexpected recipients: %v", server.rcpts)
+	}
+	if !strings.Contains(server.data, "Subject: Review requested: Add caching\r\n") {
+		t.Errorf("expected subject header, got %q", server.data)
+	}
+	if !strings.Contains(server.data, "line one\r\nline two") {
+		t.Errorf("expected CRLF body, got %q", server.data)
+	}
+}
+
+func TestSMTPMailer_RequiresStartTLS(t *testing.T) {
+	server, _ := newFakeSMTPServer(t, true)
+
+	mailer := NewSMTPMailer(SMTPConfig{
+		Host:    "127.0.0.1",
+		Port:    server.port(),
+		From:    "reviewer@example.com",
+		Timeout: 5 * time.Second,
+	})
+
+	err := mailer.Send(context.Background(), &Message{To: []string{"alice@example.com"}, Subject: "s", Body: "b"})
+	if err != ErrStartTLSUnsupported {
+		t.Errorf("expected ErrStartTLSUnsupported, got %v", err)
+	}
+}
+
+type fakeMailer struct {
+	sent []*Message
+	err  error
+}
+
+func (m *fakeMailer) Send(_ context.Context, msg *Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+type fakeDigestSource struct {
+	users   []*models.User
+	pending map[int][]*models.PullRequest
+	claimed map[int]bool
+}
+
+func (s *fakeDigestSource) GetDigestRecipients() ([]*models.User, error) {
+	return s.users, nil
+}
+
+func (s *fakeDigestSource) GetPendingReviews(userID int) ([]*models.PullRequest, error) {
+	return s.pending[userID], nil
+}
+
+func (s *fakeDigestSource) ClaimDigest(userID int, _ time.Time) (bool, error) {
+	if s.claimed[userID] {
+		return false, nil
+	}
+	s.claimed[userID] = true
+	return true, nil
+}
+
+func (s *fakeDigestSource) ReleaseDigest(userID int, _ time.Time) error {
+	delete(s.claimed, userID)
+	return nil
+}
+
+func TestDigestScheduler_SendDigests(t *testing.T) {
+	log, err := logger.New("error", "test")
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	now := time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)
+	source := &fakeDigestSource{
+		users: []*models.User{
+			{ID: 1, Name: "Alice", Email: "alice@example.com"},
+			{ID: 2, Name: "Bob", Email: "bob@example.com"},
+		},
+		pending: map[int][]*models.PullRequest{
+			1: {{ID: 10, Title: "Add caching", CreatedAt: now.Add(-50 * time.Hour)}},
+		},
+		claimed: map[int]bool{},
+	}
+	mailer := &fakeMailer{}
+
+	scheduler := NewDigestScheduler(source, mailer, 8, time.UTC, "https://reviewer.example.com", log)
+	scheduler.now = func() time.Time { return now }
+
+	sent, err := scheduler.SendDigests(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent != 1 || len(mailer.sent) != 1 {
+		t.Fatalf("expected one digest (Bob has no pending reviews), got %d", sent)
+	}
+
+	msg := mailer.sent[0]
+	if msg.To[0] != "alice@example.com" {
+		t.Errorf("unexpected recipient: %v", msg.To)
+	}
+	for _, want := range []string{"Add caching", "2d", "https://reviewer.example.com/pull-requests?id=10"} {
+		if !strings.Contains(msg.Body, want) {
+			t.Errorf("expected digest to contain %q, got:\n%s", want, msg.Body)
+		}
+	}
+
+	// Повторный запуск в тот же день не отправляет дайджест снова
+	sent, _ = scheduler.SendDigests(context.Background())
+	if sent != 0 {
+		t.Errorf("expected no digests on second run, got %d", sent)
+	}
+}
+
+func TestDigestScheduler_ReleasesClaimOnSendError(t *testing.T) {
+	log, err := logger.New("error", "test")
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	now := time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)
+	source := &fakeDigestSource{
+		users:   []*models.User{{ID: 1, Name: "Alice", Email: "alice@example.com"}},
+		pending: map[int][]*models.PullRequest{1: {{ID: 10, Title: "Add caching", CreatedAt: now}}},
+		claimed: map[int]bool{},
+	}
+	mailer := &fakeMailer{err: errors.New("smtp unavailable")}
+
+	scheduler := NewDigestScheduler(source, mailer, 8, time.UTC, "", log)
+	scheduler.now = func() time.Time { return now }
+
+	if _, err := scheduler.SendDigests(context.Background()); !errors.Is(err, errDigestsNotSent) {
+		t.Fatalf("expected errDigestsNotSent, got %v", err)
+	}
+	if source.claimed[1] {
+		t.Fatal("expected claim to be released after send error")
+	}
+
+	// Повтор в тот же день отправляет дайджест
+	mailer.err = nil
+	sent, err := scheduler.SendDigests(context.Background())
+	if err != nil || sent != 1 {
+		t.Errorf("expected digest to be sent on retry, got %d, %v", sent, err)
+	}
+}
+
+func TestDigestScheduler_NextRun(t *testing.T) {
+	scheduler := NewDigestScheduler(nil, nil, 8, time.UTC, "", nil)
+
+	tests := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{time.Date(2024, 3, 4, 7, 30, 0, 0, time.UTC), time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC), time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 3, 4, 23, 0, 0, 0, time.UTC), time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.now.Hour()), func(t *testing.T) {
+			scheduler.now = func() time.Time { return tt.now }
+			if got := scheduler.nextRun(); !got.Equal(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+type fakeRecipients struct {
+	claimed map[string]bool
+}
+
+func (r *fakeRecipients) GetUser(id int) (*models.User, error) {
+	return &models.User{ID: id, Name: "Alice", Email: "alice@example.com", IsActive: true}, nil
+}
+
+func (r *fakeRecipients) GetNotificationPreferences(userID int) (*models.NotificationPreferences, error) {
+	return &models.NotificationPreferences{UserID: userID, EmailAssignments: true}, nil
+}
+
+func (r *fakeRecipients) ClaimAssignmentEmail(outboxID int64, userID int) (bool, error) {
+	key := strconv.FormatInt(outboxID, 10) + "/" + strconv.Itoa(userID)
+	if r.claimed[key] {
+		return false, nil
+	}
+	r.claimed[key] = true
+	return true, nil
+}
+
+func (r *fakeRecipients) ReleaseAssignmentEmail(outboxID int64, userID int) error {
+	delete(r.claimed, strconv.FormatInt(outboxID, 10)+"/"+strconv.Itoa(userID))
+	return nil
+}
+
+func TestEmailNotifier_SendsOncePerEvent(t *testing.T) {
+	log, err := logger.New("error", "test")
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	mailer := &fakeMailer{err: errors.New("connection refused")}
+	n := NewEmailNotifier(mailer, &fakeRecipients{claimed: map[string]bool{}}, "", log)
+	event := &webhook.OutboxEvent{
+		ID:    7,
+		Event: webhook.EventReviewerAssigned,
+		Data:  map[string]interface{}{"pr_id": float64(10), "reviewer_id": float64(2), "title": "Add caching"},
+	}
+
+	// Неудачная отправка не резервирует письмо
+	if err := n.HandleEvent(context.Background(), event); err == nil {
+		t.Fatal("expected send error")
+	}
+
+	mailer.err = nil
+	for i := 0; i < 2; i++ {
+		if err := n.HandleEvent(context.Background(), event); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(mailer.sent) != 1 {
+		t.Errorf("expected one email on repeated delivery, got %d", len(mailer.sent))
+	}
+}
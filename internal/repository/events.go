@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/user/pr-reviewer/internal/webhook"
+)
+
+// EventRecorder сохраняет доменные события в той же транзакции,
+// что и изменение данных (transactional outbox)
+type EventRecorder interface {
+	Enqueue(ctx context.Context, tx *sql.Tx, event webhook.EventType, aggregateKey string, data map[string]interface{}) error
+}
+
+// recordEvent записывает событие в транзакции, если recorder подключен
+func recordEvent(rec EventRecorder, tx *sql.Tx, event webhook.EventType, aggregateKey string, data map[string]interface{}) error {
+	if rec == nil {
+		return nil
+	}
+	return rec.Enqueue(context.Background(), tx, event, aggregateKey, data)
+}
+
+// recordPREvent дополняет событие PR контекстом для фильтров подписок
+// (автор, команда автора, рецензенты, репозиторий, метки) и записывает его в транзакции
+func recordPREvent(rec EventRecorder, tx *sql.Tx, event webhook.EventType, prID int, data map[string]interface{}) error {
+	if rec == nil {
+		return nil
+	}
+
+	var authorID int
+	var teamID sql.NullInt64
+	var repository string
+	var labels []string
+	err := tx.QueryRow(`
+		SELECT p.author_id, u.team_id, COALESCE(p.repository, ''), p.labels
+		FROM pull_requests p
+		JOIN users u ON u.id = p.author_id
+		WHERE p.id = $1`, prID).Scan(&authorID, &teamID, &repository, pq.Array(&labels))
+	if err != nil {
+		return fmt.Errorf("failed to load PR event context: %w", err)
+	}
+
+	rows, err := tx.Query(`SELECT reviewer_id FROM pr_reviewers WHERE pr_id = $1 ORDER BY reviewer_id`, prID)
+	if err != nil {
+		return fmt.Errorf("failed to load PR reviewers: %w", err)
+	}
+	reviewerIDs := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan PR reviewer: %w", err)
+		}
+		reviewerIDs = append(reviewerIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate PR reviewers: %w", err)
+	}
+
+	var team *int
+	if teamID.Valid {
+		id := int(teamID.Int64)
+		team = &id
+	}
+
+	data = webhook.WithPRContext(data, webhook.PRContext{
+		AuthorID:    authorID,
+		TeamID:      team,
+		ReviewerIDs: reviewerIDs,
+		Repository:  repository,
+		Labels:      labels,
+	})
+	return rec.Enqueue(context.Background(), tx, event, webhook.PRAggregateKey(prID), data)
+}
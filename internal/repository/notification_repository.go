@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/database"
+	"github.com/user/pr-reviewer/internal/models"
+)
+
+// NotificationRepository репозиторий уведомлений в приложении
+type NotificationRepository struct {
+	db *database.DB
+}
+
+// NewNotificationRepository создаёт новый репозиторий уведомлений
+func NewNotificationRepository(db *database.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// Create сохраняет уведомление. Повторное уведомление по тому же событию outbox игнорируется.
+func (r *NotificationRepository) Create(n *models.Notification) error {
+	query := `
+		INSERT INTO notifications (user_id, event, pr_id, title, message, outbox_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, outbox_id, event) WHERE outbox_id IS NOT NULL DO NOTHING
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(query, n.UserID, n.Event, n.PRID, n.Title, n.Message, n.OutboxID).
+		Scan(&n.ID, &n.CreatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	return nil
+}
+
+// GetByUser возвращает уведомления пользователя, новые первыми
+func (r *NotificationRepository) GetByUser(userID int, unreadOnly bool, limit, offset int) ([]*models.Notification, error) {
+	query := `
+		SELECT id, user_id, event, pr_id, title, message, read_at, created_at
+		FROM notifications
+		WHERE user_id = $1`
+
+	if unreadOnly {
+		query += " AND read_at IS NULL"
+	}
+	query += " ORDER BY id DESC LIMIT $2 OFFSET $3"
+
+	rows, err := r.db.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []*models.Notification{}
+	for rows.Next() {
+		n := &models.Notification{}
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Event, &n.PRID, &n.Title, &n.Message, &n.ReadAt, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// CountUnread возвращает количество непрочитанных уведомлений
+func (r *NotificationRepository) CountUnread(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userID).
+		Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkRead отмечает уведомление прочитанным
+func (r *NotificationRepository) MarkRead(userID int, id int64) error {
+	result, err := r.db.Exec(`
+		UPDATE notifications
+		SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("notification not found")
+	}
+
+	return nil
+}
+
+// MarkAllRead отмечает все уведомления пользователя прочитанными
+func (r *NotificationRepository) MarkAllRead(userID int) (int, error) {
+	result, err := r.db.Exec(`UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return int(rows), nil
+}
+
+// DeleteOlderThan удаляет уведомления, созданные раньше cutoff
+func (r *NotificationRepository) DeleteOlderThan(cutoff time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM notifications WHERE created_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old notifications: %w", err)
+	}
+	return result.RowsAffected()
+}
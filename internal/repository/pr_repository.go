@@ -6,13 +6,16 @@ import (
 	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/user/pr-reviewer/internal/database"
 	"github.com/user/pr-reviewer/internal/models"
+	"github.com/user/pr-reviewer/internal/webhook"
 )
 
 // PRRepository репозиторий для работы с Pull Requests
 type PRRepository struct {
-	db *database.DB
+	db     *database.DB
+	events EventRecorder
 }
 
 // NewPRRepository создаёт новый репозиторий PR
@@ -20,6 +23,11 @@ func NewPRRepository(db *database.DB) *PRRepository {
 	return &PRRepository{db: db}
 }
 
+// SetEventRecorder подключает запись событий в outbox
+func (r *PRRepository) SetEventRecorder(rec EventRecorder) {
+	r.events = rec
+}
+
 // Create создаёт новый PR
 func (r *PRRepository) Create(pr *models.PullRequest) error {
 	tx, err := r.db.Begin()
@@ -32,11 +40,14 @@ func (r *PRRepository) Create(pr *models.PullRequest) error {
 
 	// Создаём PR
 	query := `
-		INSERT INTO pull_requests (title, author_id, status) 
-		VALUES ($1, $2, $3) 
+		INSERT INTO pull_requests (title, repository, labels, author_id, status) 
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5) 
 		RETURNING id, created_at, updated_at`
 
-	err = tx.QueryRow(query, pr.Title, pr.AuthorID, pr.Status).
+	if pr.Labels == nil {
+		pr.Labels = []string{}
+	}
+	err = tx.QueryRow(query, pr.Title, pr.Repository, pq.Array(pr.Labels), pr.AuthorID, pr.Status).
 		Scan(&pr.ID, &pr.CreatedAt, &pr.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create PR: %w", err)
@@ -49,6 +60,11 @@ func (r *PRRepository) Create(pr *models.PullRequest) error {
 		}
 	}
 
+	// Событие фиксируется вместе с созданием PR
+	if err := recordPREvent(r.events, tx, webhook.EventPRCreated, pr.ID, webhook.PRCreatedData(pr)); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -60,12 +76,12 @@ func (r *PRRepository) Create(pr *models.PullRequest) error {
 func (r *PRRepository) GetByID(id int) (*models.PullRequest, error) {
 	pr := &models.PullRequest{}
 	query := `
-		SELECT id, title, author_id, status, created_at, merged_at, updated_at 
+		SELECT id, title, COALESCE(repository, ''), labels, author_id, status, created_at, merged_at, updated_at 
 		FROM pull_requests 
 		WHERE id = $1`
 
 	err := r.db.QueryRow(query, id).Scan(
-		&pr.ID, &pr.Title, &pr.AuthorID, &pr.Status,
+		&pr.ID, &pr.Title, &pr.Repository, pq.Array(&pr.Labels), &pr.AuthorID, &pr.Status,
 		&pr.CreatedAt, &pr.MergedAt, &pr.UpdatedAt,
 	)
 	if err != nil {
@@ -88,7 +104,7 @@ func (r *PRRepository) GetByID(id int) (*models.PullRequest, error) {
 // GetAll возвращает все PR с фильтрами
 func (r *PRRepository) GetAll(userID *int, authorID *int, status *string) ([]*models.PullRequest, error) {
 	baseQuery := `
-		SELECT DISTINCT p.id, p.title, p.author_id, p.status, p.created_at, p.merged_at, p.updated_at 
+		SELECT DISTINCT p.id, p.title, COALESCE(p.repository, ''), p.labels, p.author_id, p.status, p.created_at, p.merged_at, p.updated_at 
 		FROM pull_requests p`
 
 	whereClauses := []string{}
@@ -135,7 +151,7 @@ func (r *PRRepository) GetAll(userID *int, authorID *int, status *string) ([]*mo
 	var prs []*models.PullRequest
 	for rows.Next() {
 		pr := &models.PullRequest{}
-		if err := rows.Scan(&pr.ID, &pr.Title, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &pr.MergedAt, &pr.UpdatedAt); err != nil {
+		if err := rows.Scan(&pr.ID, &pr.Title, &pr.Repository, pq.Array(&pr.Labels), &pr.AuthorID, &pr.Status, &pr.CreatedAt, &pr.MergedAt, &pr.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan PR: %w", err)
 		}
 
@@ -158,16 +174,34 @@ func (r *PRRepository) GetAll(userID *int, authorID *int, status *string) ([]*mo
 
 // Merge переводит PR в состояние MERGED
 func (r *PRRepository) Merge(id int) (*models.PullRequest, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// Запоминаем исходный статус, чтобы повторный merge не порождал событие
+	var prevStatus models.PRStatus
+	err = tx.QueryRow("SELECT status FROM pull_requests WHERE id = $1 FOR UPDATE", id).Scan(&prevStatus)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("PR not found")
+		}
+		return nil, fmt.Errorf("failed to check PR status: %w", err)
+	}
+
 	now := time.Now()
 	query := `
 		UPDATE pull_requests 
 		SET status = $1, merged_at = $2 
 		WHERE id = $3 AND (status = 'OPEN' OR status = 'MERGED')
-		RETURNING id, title, author_id, status, created_at, merged_at, updated_at`
+		RETURNING id, title, COALESCE(repository, ''), labels, author_id, status, created_at, merged_at, updated_at`
 
 	pr := &models.PullRequest{}
-	err := r.db.QueryRow(query, models.PRStatusMerged, now, id).Scan(
-		&pr.ID, &pr.Title, &pr.AuthorID, &pr.Status,
+	err = tx.QueryRow(query, models.PRStatusMerged, now, id).Scan(
+		&pr.ID, &pr.Title, &pr.Repository, pq.Array(&pr.Labels), &pr.AuthorID, &pr.Status,
 		&pr.CreatedAt, &pr.MergedAt, &pr.UpdatedAt,
 	)
 	if err != nil {
@@ -177,6 +211,16 @@ func (r *PRRepository) Merge(id int) (*models.PullRequest, error) {
 		return nil, fmt.Errorf("failed to merge PR: %w", err)
 	}
 
+	if prevStatus == models.PRStatusOpen {
+		if err := recordPREvent(r.events, tx, webhook.EventPRMerged, pr.ID, webhook.PRMergedData(pr)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	// Получаем рецензентов
 	reviewers, err := r.getReviewers(pr.ID)
 	if err != nil {
@@ -189,15 +233,23 @@ func (r *PRRepository) Merge(id int) (*models.PullRequest, error) {
 
 // Close переводит PR в состояние CLOSED (закрыт без мерджа)
 func (r *PRRepository) Close(id int) (*models.PullRequest, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
 	query := `
 		UPDATE pull_requests 
 		SET status = $1
 		WHERE id = $2 AND status = 'OPEN'
-		RETURNING id, title, author_id, status, created_at, merged_at, updated_at`
+		RETURNING id, title, COALESCE(repository, ''), labels, author_id, status, created_at, merged_at, updated_at`
 
 	pr := &models.PullRequest{}
-	err := r.db.QueryRow(query, models.PRStatusClosed, id).Scan(
-		&pr.ID, &pr.Title, &pr.AuthorID, &pr.Status,
+	err = tx.QueryRow(query, models.PRStatusClosed, id).Scan(
+		&pr.ID, &pr.Title, &pr.Repository, pq.Array(&pr.Labels), &pr.AuthorID, &pr.Status,
 		&pr.CreatedAt, &pr.MergedAt, &pr.UpdatedAt,
 	)
 	if err != nil {
@@ -207,6 +259,14 @@ func (r *PRRepository) Close(id int) (*models.PullRequest, error) {
 		return nil, fmt.Errorf("failed to close PR: %w", err)
 	}
 
+	if err := recordPREvent(r.events, tx, webhook.EventPRClosed, pr.ID, webhook.PRClosedData(pr)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	// Получаем рецензентов
 	reviewers, err := r.getReviewers(pr.ID)
 	if err != nil {
@@ -264,6 +324,12 @@ func (r *PRRepository) ReplaceReviewer(prID, oldReviewerID, newReviewerID int) e
 		return fmt.Errorf("failed to add new reviewer: %w", err)
 	}
 
+	err = recordPREvent(r.events, tx, webhook.EventReviewerChanged, prID,
+		webhook.ReviewerChangedData(prID, oldReviewerID, newReviewerID))
+	if err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -274,7 +340,7 @@ func (r *PRRepository) ReplaceReviewer(prID, oldReviewerID, newReviewerID int) e
 // GetOpenPRsWithReviewer возвращает открытые PR с указанным рецензентом
 func (r *PRRepository) GetOpenPRsWithReviewer(reviewerID int) ([]*models.PullRequest, error) {
 	query := `
-		SELECT DISTINCT p.id, p.title, p.author_id, p.status, p.created_at, p.merged_at, p.updated_at
+		SELECT DISTINCT p.id, p.title, COALESCE(p.repository, ''), p.labels, p.author_id, p.status, p.created_at, p.merged_at, p.updated_at
 		FROM pull_requests p
 		JOIN pr_reviewers pr ON p.id = pr.pr_id
 		WHERE pr.reviewer_id = $1 AND p.status = 'OPEN'
@@ -289,7 +355,7 @@ func (r *PRRepository) GetOpenPRsWithReviewer(reviewerID int) ([]*models.PullReq
 	var prs []*models.PullRequest
 	for rows.Next() {
 		pr := &models.PullRequest{}
-		if err := rows.Scan(&pr.ID, &pr.Title, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &pr.MergedAt, &pr.UpdatedAt); err != nil {
+		if err := rows.Scan(&pr.ID, &pr.Title, &pr.Repository, pq.Array(&pr.Labels), &pr.AuthorID, &pr.Status, &pr.CreatedAt, &pr.MergedAt, &pr.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan PR: %w", err)
 		}
 
@@ -376,6 +442,14 @@ func (r *PRRepository) AddReviewers(prID int, reviewers []models.User) error {
 		return err
 	}
 
+	for _, reviewer := range reviewers {
+		err := recordPREvent(r.events, tx, webhook.EventReviewerAssigned, prID,
+			webhook.ReviewerAssignedData(prID, reviewer.ID))
+		if err != nil {
+			return err
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/user/pr-reviewer/internal/database"
+	"github.com/user/pr-reviewer/internal/models"
+)
+
+// PreferencesRepository репозиторий настроек уведомлений
+type PreferencesRepository struct {
+	db *database.DB
+}
+
+// NewPreferencesRepository создаёт новый репозиторий настроек уведомлений
+func NewPreferencesRepository(db *database.DB) *PreferencesRepository {
+	return &PreferencesRepository{db: db}
+}
+
+// Get возвращает настройки пользователя (значения по умолчанию, если не заданы)
+func (r *PreferencesRepository) Get(userID int) (*models.NotificationPreferences, error) {
+	prefs := &models.NotificationPreferences{UserID: userID}
+	query := `
+		SELECT email_assignments, email_digest, inbox_events, last_digest_on, updated_at
+		FROM user_notification_preferences
+		WHERE user_id = $1`
+
+	err := r.db.QueryRow(query, userID).Scan(
+		&prefs.EmailAssignments, &prefs.EmailDigest, pq.Array(&prefs.InboxEvents),
+		&prefs.LastDigestOn, &prefs.UpdatedAt,
+	)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+
+	return prefs, nil
+}
+
+// Update сохраняет настройки пользователя
+func (r *PreferencesRepository) Update(userID int, req *models.UpdateNotificationPreferencesRequest) (*models.NotificationPreferences, error) {
+	current, err := r.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.EmailAssignments != nil {
+		current.EmailAssignments = *req.EmailAssignments
+	}
+	if req.EmailDigest != nil {
+		current.EmailDigest = *req.EmailDigest
+	}
+	if req.InboxEvents != nil {
+		current.InboxEvents = append([]string{}, *req.InboxEvents...)
+	}
+
+	query := `
+		INSERT INTO user_notification_preferences (user_id, email_assignments, email_digest, inbox_events, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET email_assignments = EXCLUDED.email_assignments,
+		    email_digest = EXCLUDED.email_digest,
+		    inbox_events = EXCLUDED.inbox_events,
+		    updated_at = NOW()
+		RETURNING updated_at`
+
+	err = r.db.QueryRow(query, userID, current.EmailAssignments, current.EmailDigest,
+		pq.Array(current.InboxEvents)).Scan(&current.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update notification preferences: %w", err)
+	}
+
+	return current, nil
+}
+
+// GetDigestRecipients возвращает активных пользователей с email, подписанных на дайджест
+func (r *PreferencesRepository) GetDigestRecipients() ([]*models.User, error) {
+	query := `
+		SELECT u.id, u.username, u.name, u.is_active, u.team_id,
+		       COALESCE(u.chat_handle, ''), COALESCE(u.email, ''), u.created_at, u.updated_at
+		FROM users u
+		JOIN user_notification_preferences p ON p.user_id = u.id
+		WHERE p.email_digest = true AND u.is_active = true
+		  AND u.email IS NOT NULL AND u.email != ''
+		ORDER BY u.id`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user := &models.User{}
+		if err := scanUser(rows, user); err != nil {
+			return nil, fmt.Errorf("failed to scan digest recipient: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate digest recipients: %w", err)
+	}
+
+	return users, nil
+}
+
+// ClaimDigest отмечает отправку дайджеста за день. Возвращает false, если
+// дайджест за этот день уже отправлен (например, другой репликой).
+func (r *PreferencesRepository) ClaimDigest(userID int, day time.Time) (bool, error) {
+	query := `
+		UPDATE user_notification_preferences
+		SET last_digest_on = $1
+		WHERE user_id = $2 AND (last_digest_on IS NULL OR last_digest_on < $1)`
+
+	result, err := r.db.Exec(query, day.Format("2006-01-02"), userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim digest: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// ReleaseDigest снимает резерв дайджеста за день, если письмо не отправлено
+func (r *PreferencesRepository) ReleaseDigest(userID int, day time.Time) error {
+	query := `
+		UPDATE user_notification_preferences
+		SET last_digest_on = NULL
+		WHERE user_id = $2 AND last_digest_on = $1`
+
+	if _, err := r.db.Exec(query, day.Format("2006-01-02"), userID); err != nil {
+		return fmt.Errorf("failed to release digest: %w", err)
+	}
+	return nil
+}
+
+// ClaimAssignmentEmail резервирует отправку письма о назначении по событию
+// outbox. Возвращает false, если письмо уже отправлено (например, при
+// повторной доставке события).
+func (r *PreferencesRepository) ClaimAssignmentEmail(outboxID int64, userID int) (bool, error) {
+	query := `
+		INSERT INTO assignment_emails_sent (outbox_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`
+
+	result, err := r.db.Exec(query, outboxID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim assignment email: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// ReleaseAssignmentEmail снимает резерв, если письмо не удалось отправить
+func (r *PreferencesRepository) ReleaseAssignmentEmail(outboxID int64, userID int) error {
+	query := `DELETE FROM assignment_emails_sent WHERE outbox_id = $1 AND user_id = $2`
+
+	if _, err := r.db.Exec(query, outboxID, userID); err != nil {
+		return fmt.Errorf("failed to release assignment email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/user/pr-reviewer/internal/database"
+	"github.com/user/pr-reviewer/internal/models"
+)
+
+// ErrRefreshTokenRevoked токен уже отозван или использован для ротации
+var ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+
+// RefreshTokenRepository репозиторий refresh токенов
+type RefreshTokenRepository struct {
+	db *database.DB
+}
+
+// NewRefreshTokenRepository создаёт новый репозиторий refresh токенов
+func NewRefreshTokenRepository(db *database.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// Create сохраняет новый refresh токен
+func (r *RefreshTokenRepository) Create(t *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, token_hash, family_id, expires_at, user_agent, ip)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(query, t.UserID, t.TokenHash, t.FamilyID, t.ExpiresAt,
+		nullString(t.UserAgent), nullString(t.IP)).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// GetByHash возвращает refresh токен по хешу
+func (r *RefreshTokenRepository) GetByHash(hash string) (*models.RefreshToken, error) {
+	t := &models.RefreshToken{}
+	query := `
+		SELECT id, user_id, token_hash, family_id, expires_at, revoked_at, replaced_by,
+		       COALESCE(user_agent, ''), COALESCE(ip, ''), created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1`
+
+	err := r.db.QueryRow(query, hash).Scan(&t.ID, &t.UserID, &t.TokenHash, &t.FamilyID, &t.ExpiresAt,
+		&t.RevokedAt, &t.ReplacedBy, &t.UserAgent, &t.IP, &t.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("refresh token not found")
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	return t, nil
+}
+
+// Rotate отзывает старый токен и сохраняет новый в одной транзакции.
+// Если старый токен уже отозван (параллельная ротация), возвращает ErrRefreshTokenRevoked.
+func (r *RefreshTokenRepository) Rotate(oldID int64, next *models.RefreshToken) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	result, err := tx.Exec(`
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL`, oldID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrRefreshTokenRevoked
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO refresh_tokens (user_id, token_hash, family_id, expires_at, user_agent, ip)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		next.UserID, next.TokenHash, next.FamilyID, next.ExpiresAt,
+		nullString(next.UserAgent), nullString(next.IP)).Scan(&next.ID, &next.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE refresh_tokens SET replaced_by = $1 WHERE id = $2`, next.ID, oldID); err != nil {
+		return fmt.Errorf("failed to link refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeFamily отзывает все токены цепочки ротаций
+func (r *RefreshTokenRepository) RevokeFamily(familyID string) (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL`, familyID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// RevokeAllForUser отзывает все активные токены пользователя
+func (r *RefreshTokenRepository) RevokeAllForUser(userID int) (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return result.RowsAffected()
+}
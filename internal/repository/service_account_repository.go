@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/user/pr-reviewer/internal/database"
+	"github.com/user/pr-reviewer/internal/models"
+)
+
+// ErrServiceAccountExists сервисный аккаунт с таким именем уже существует
+var ErrServiceAccountExists = errors.New("service account already exists")
+
+// uniqueViolation код ошибки Postgres при нарушении уникальности
+const uniqueViolation = "23505"
+
+// ServiceAccountRepository репозиторий сервисных аккаунтов и их API ключей
+type ServiceAccountRepository struct {
+	db *database.DB
+}
+
+// NewServiceAccountRepository создаёт новый репозиторий сервисных аккаунтов
+func NewServiceAccountRepository(db *database.DB) *ServiceAccountRepository {
+	return &ServiceAccountRepository{db: db}
+}
+
+// Create создаёт сервисный аккаунт
+func (r *ServiceAccountRepository) Create(account *models.ServiceAccount) error {
+	query := `
+		INSERT INTO service_accounts (name, description, role, team_id, is_active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(query, account.Name, nullString(account.Description), account.Role,
+		account.TeamID, account.IsActive).Scan(&account.ID, &account.CreatedAt, &account.UpdatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return ErrServiceAccountExists
+		}
+		return fmt.Errorf("failed to create service account: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID возвращает сервисный аккаунт по ID
+func (r *ServiceAccountRepository) GetByID(id int) (*models.ServiceAccount, error) {
+	account := &models.ServiceAccount{}
+	query := `
+		SELECT id, name, COALESCE(description, ''), role, team_id, is_active, created_at, updated_at
+		FROM service_accounts
+		WHERE id = $1`
+
+	err := r.db.QueryRow(query, id).Scan(&account.ID, &account.Name, &account.Description, &account.Role,
+		&account.TeamID, &account.IsActive, &account.CreatedAt, &account.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("service account not found")
+		}
+		return nil, fmt.Errorf("failed to get service account: %w", err)
+	}
+
+	return account, nil
+}
+
+// GetAll возвращает все сервисные аккаунты
+func (r *ServiceAccountRepository) GetAll() ([]*models.ServiceAccount, error) {
+	query := `
+		SELECT id, name, COALESCE(description, ''), role, team_id, is_active, created_at, updated_at
+		FROM service_accounts
+		ORDER BY name`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := make([]*models.ServiceAccount, 0)
+	for rows.Next() {
+		account := &models.ServiceAccount{}
+		if err := rows.Scan(&account.ID, &account.Name, &account.Description, &account.Role,
+			&account.TeamID, &account.IsActive, &account.CreatedAt, &account.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan service account: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
+
+// CreateAPIKey сохраняет новый API ключ
+func (r *ServiceAccountRepository) CreateAPIKey(key *models.APIKey) error {
+	query := `
+		INSERT INTO api_keys (service_account_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(query, key.ServiceAccountID, key.Name, key.Prefix, key.KeyHash,
+		pq.Array(key.Scopes), key.ExpiresAt).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKeys возвращает ключи сервисного аккаунта
+func (r *ServiceAccountRepository) GetAPIKeys(accountID int) ([]*models.APIKey, error) {
+	query := `
+		SELECT id, service_account_id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys
+		WHERE service_account_id = $1
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(query, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*models.APIKey, 0)
+	for rows.Next() {
+		key := &models.APIKey{}
+		if err := rows.Scan(&key.ID, &key.ServiceAccountID, &key.Name, &key.Prefix, pq.Array(&key.Scopes),
+			&key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// GetAPIKeyByHash возвращает ключ и его сервисный аккаунт по хешу ключа
+func (r *ServiceAccountRepository) GetAPIKeyByHash(hash string) (*models.APIKey, *models.ServiceAccount, error) {
+	key := &models.APIKey{}
+	account := &models.ServiceAccount{}
+	query := `
+		SELECT k.id, k.service_account_id, k.name, k.prefix, k.key_hash, k.scopes,
+		       k.expires_at, k.last_used_at, k.revoked_at, k.created_at,
+		       a.id, a.name, a.role, a.team_id, a.is_active
+		FROM api_keys k
+		JOIN service_accounts a ON a.id = k.service_account_id
+		WHERE k.key_hash = $1`
+
+	err := r.db.QueryRow(query, hash).Scan(&key.ID, &key.ServiceAccountID, &key.Name, &key.Prefix, &key.KeyHash,
+		pq.Array(&key.Scopes), &key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedAt,
+		&account.ID, &account.Name, &account.Role, &account.TeamID, &account.IsActive)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, fmt.Errorf("API key not found")
+		}
+		return nil, nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return key, account, nil
+}
+
+// RevokeAPIKey отзывает ключ сервисного аккаунта
+func (r *ServiceAccountRepository) RevokeAPIKey(accountID int, keyID int64) error {
+	result, err := r.db.Exec(`
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND service_account_id = $2 AND revoked_at IS NULL`, keyID, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("API key not found")
+	}
+
+	return nil
+}
+
+// TouchAPIKey обновляет время последнего использования ключа.
+// Запись выполняется не чаще раза в минуту, чтобы не нагружать БД на каждом запросе.
+func (r *ServiceAccountRepository) TouchAPIKey(id int64) error {
+	_, err := r.db.Exec(`
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`, id)
+	if err != nil {
+		return fmt.Errorf("failed to update API key last used time: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/database"
+	"github.com/user/pr-reviewer/internal/models"
+)
+
+// SigningKeyRepository репозиторий ключей подписи JWT
+type SigningKeyRepository struct {
+	db *database.DB
+}
+
+// NewSigningKeyRepository создаёт новый репозиторий ключей подписи
+func NewSigningKeyRepository(db *database.DB) *SigningKeyRepository {
+	return &SigningKeyRepository{db: db}
+}
+
+// GetAll возвращает все ключи, от новых к старым
+func (r *SigningKeyRepository) GetAll() ([]*models.SigningKey, error) {
+	query := `
+		SELECT id, algorithm, private_key, created_at, retired_at
+		FROM jwt_signing_keys
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*models.SigningKey, 0)
+	for rows.Next() {
+		key := &models.SigningKey{}
+		if err := rows.Scan(&key.ID, &key.Algorithm, &key.PrivateKey, &key.CreatedAt, &key.RetiredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan signing key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Create сохраняет новый ключ
+func (r *SigningKeyRepository) Create(key *models.SigningKey) error {
+	query := `
+		INSERT INTO jwt_signing_keys (id, algorithm, private_key, created_at)
+		VALUES ($1, $2, $3, $4)`
+
+	if _, err := r.db.Exec(query, key.ID, key.Algorithm, string(key.PrivateKey), key.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create signing key: %w", err)
+	}
+	return nil
+}
+
+// ReplacePrivateKey заменяет сохраненный приватный ключ, если он не изменился
+// с момента чтения (другая реплика могла заменить его раньше)
+func (r *SigningKeyRepository) ReplacePrivateKey(id string, old, updated []byte) error {
+	_, err := r.db.Exec(`UPDATE jwt_signing_keys SET private_key = $1 WHERE id = $2 AND private_key = $3`,
+		string(updated), id, string(old))
+	if err != nil {
+		return fmt.Errorf("failed to update signing key: %w", err)
+	}
+	return nil
+}
+
+// RetireAllButNewest выводит из работы все активные ключи, кроме самого нового
+func (r *SigningKeyRepository) RetireAllButNewest(at time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE jwt_signing_keys SET retired_at = $1
+		WHERE retired_at IS NULL
+		  AND id <> (SELECT id FROM jwt_signing_keys ORDER BY created_at DESC, id DESC LIMIT 1)`, at)
+	if err != nil {
+		return fmt.Errorf("failed to retire signing keys: %w", err)
+	}
+	return nil
+}
+
+// DeleteRetiredBefore удаляет ключи, выведенные из работы до before
+func (r *SigningKeyRepository) DeleteRetiredBefore(before time.Time) error {
+	if _, err := r.db.Exec(`DELETE FROM jwt_signing_keys WHERE retired_at < $1`, before); err != nil {
+		return fmt.Errorf("failed to delete retired signing keys: %w", err)
+	}
+	return nil
+}
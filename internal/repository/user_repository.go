@@ -7,11 +7,16 @@ import (
 
 	"github.com/user/pr-reviewer/internal/database"
 	"github.com/user/pr-reviewer/internal/models"
+	"github.com/user/pr-reviewer/internal/webhook"
 )
 
+// userColumns столбцы пользователя в порядке, ожидаемом scanUser
+const userColumns = `id, username, name, is_active, team_id, COALESCE(chat_handle, ''), COALESCE(email, ''), role, created_at, updated_at`
+
 // UserRepository репозиторий для работы с пользователями
 type UserRepository struct {
-	db *database.DB
+	db     *database.DB
+	events EventRecorder
 }
 
 // NewUserRepository создаёт новый репозиторий пользователей
@@ -19,14 +24,24 @@ func NewUserRepository(db *database.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// SetEventRecorder подключает запись событий в outbox
+func (r *UserRepository) SetEventRecorder(rec EventRecorder) {
+	r.events = rec
+}
+
 // Create создаёт нового пользователя
 func (r *UserRepository) Create(user *models.User) error {
+	if user.Role == "" {
+		user.Role = "member"
+	}
+
 	query := `
-		INSERT INTO users (username, name, is_active, team_id) 
-		VALUES ($1, $2, $3, $4) 
+		INSERT INTO users (username, name, is_active, team_id, chat_handle, email, role, password_hash) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
 		RETURNING id, created_at, updated_at`
 
-	err := r.db.QueryRow(query, user.Username, user.Name, user.IsActive, user.TeamID).
+	err := r.db.QueryRow(query, user.Username, user.Name, user.IsActive, user.TeamID,
+		nullString(user.ChatHandle), nullString(user.Email), user.Role, nullString(user.PasswordHash)).
 		Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
@@ -39,13 +54,11 @@ func (r *UserRepository) Create(user *models.User) error {
 func (r *UserRepository) GetByID(id int) (*models.User, error) {
 	user := &models.User{}
 	query := `
-		SELECT id, username, name, is_active, team_id, created_at, updated_at 
+		SELECT ` + userColumns + `
 		FROM users 
 		WHERE id = $1`
 
-	err := r.db.QueryRow(query, id).Scan(
-		&user.ID, &user.Username, &user.Name, &user.IsActive, &user.TeamID, &user.CreatedAt, &user.UpdatedAt,
-	)
+	err := scanUser(r.db.QueryRow(query, id), user)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -59,7 +72,7 @@ func (r *UserRepository) GetByID(id int) (*models.User, error) {
 // GetAll возвращает всех пользователей с фильтрами
 func (r *UserRepository) GetAll(teamID *int, isActive *bool) ([]*models.User, error) {
 	query := `
-		SELECT id, username, name, is_active, team_id, created_at, updated_at 
+		SELECT ` + userColumns + `
 		FROM users 
 		WHERE 1=1`
 
@@ -89,7 +102,7 @@ func (r *UserRepository) GetAll(teamID *int, isActive *bool) ([]*models.User, er
 	var users []*models.User
 	for rows.Next() {
 		user := &models.User{}
-		if err := rows.Scan(&user.ID, &user.Username, &user.Name, &user.IsActive, &user.TeamID, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		if err := scanUser(rows, user); err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
 		users = append(users, user)
@@ -116,7 +129,7 @@ func (r *UserRepository) GetByIDs(ids []int) ([]*models.User, error) {
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, username, name, is_active, team_id, created_at, updated_at 
+		SELECT `+userColumns+`
 		FROM users 
 		WHERE id IN (%s)
 		ORDER BY id`, strings.Join(placeholders, ","))
@@ -130,7 +143,7 @@ func (r *UserRepository) GetByIDs(ids []int) ([]*models.User, error) {
 	var users []*models.User
 	for rows.Next() {
 		user := &models.User{}
-		if err := rows.Scan(&user.ID, &user.Username, &user.Name, &user.IsActive, &user.TeamID, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		if err := scanUser(rows, user); err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
 		users = append(users, user)
@@ -165,7 +178,25 @@ func (r *UserRepository) Update(id int, req *models.UpdateUserRequest) (*models.
 	if req.IsActive != nil {
 		setClauses = append(setClauses, fmt.Sprintf("is_active = $%d", argNum))
 		args = append(args, *req.IsActive)
-		// argNum++ не нужен, так как это последнее использование
+		argNum++
+	}
+
+	if req.ChatHandle != nil {
+		setClauses = append(setClauses, fmt.Sprintf("chat_handle = $%d", argNum))
+		args = append(args, nullString(*req.ChatHandle))
+		argNum++
+	}
+
+	if req.Email != nil {
+		setClauses = append(setClauses, fmt.Sprintf("email = $%d", argNum))
+		args = append(args, nullString(*req.Email))
+		argNum++
+	}
+
+	if req.Role != nil {
+		setClauses = append(setClauses, fmt.Sprintf("role = $%d", argNum))
+		args = append(args, *req.Role)
+		argNum++
 	}
 
 	if len(setClauses) == 0 {
@@ -177,12 +208,10 @@ func (r *UserRepository) Update(id int, req *models.UpdateUserRequest) (*models.
 		UPDATE users 
 		SET %s 
 		WHERE id = $%d
-		RETURNING id, username, name, is_active, team_id, created_at, updated_at`,
+		RETURNING `+userColumns,
 		strings.Join(setClauses, ", "), argNum)
 
-	err = r.db.QueryRow(query, args...).Scan(
-		&user.ID, &user.Username, &user.Name, &user.IsActive, &user.TeamID, &user.CreatedAt, &user.UpdatedAt,
-	)
+	err = scanUser(r.db.QueryRow(query, args...), user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
@@ -190,10 +219,97 @@ func (r *UserRepository) Update(id int, req *models.UpdateUserRequest) (*models.
 	return user, nil
 }
 
+// GetByUsername возвращает пользователя по логину вместе с хешем пароля
+func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
+	user := &models.User{}
+	query := `
+		SELECT ` + userColumns + `, COALESCE(password_hash, '')
+		FROM users 
+		WHERE username = $1`
+
+	err := r.db.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.Name, &user.IsActive,
+		&user.TeamID, &user.ChatHandle, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt,
+		&user.PasswordHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// UpdatePassword сохраняет новый хеш пароля
+func (r *UserRepository) UpdatePassword(id int, passwordHash string) error {
+	result, err := r.db.Exec(`UPDATE users SET password_hash = $1 WHERE id = $2`, passwordHash, id)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// GetByOIDCSubject возвращает пользователя, привязанного к учетной записи IdP
+func (r *UserRepository) GetByOIDCSubject(issuer, subject string) (*models.User, error) {
+	user := &models.User{}
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE oidc_issuer = $1 AND oidc_subject = $2`
+
+	if err := scanUser(r.db.QueryRow(query, issuer, subject), user); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// GetByEmail возвращает пользователя по email
+func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
+	user := &models.User{}
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE LOWER(email) = LOWER($1)
+		ORDER BY id
+		LIMIT 1`
+
+	if err := scanUser(r.db.QueryRow(query, email), user); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// LinkOIDCSubject привязывает пользователя к учетной записи IdP
+func (r *UserRepository) LinkOIDCSubject(id int, issuer, subject string) error {
+	_, err := r.db.Exec(`UPDATE users SET oidc_issuer = $1, oidc_subject = $2 WHERE id = $3`,
+		issuer, subject, id)
+	if err != nil {
+		return fmt.Errorf("failed to link OIDC identity: %w", err)
+	}
+	return nil
+}
+
 // GetActiveUsersFromTeam возвращает активных пользователей из команды
 func (r *UserRepository) GetActiveUsersFromTeam(teamID int, excludeUserID int) ([]*models.User, error) {
 	query := `
-		SELECT id, username, name, is_active, team_id, created_at, updated_at 
+		SELECT ` + userColumns + `
 		FROM users 
 		WHERE team_id = $1 AND is_active = true AND id != $2
 		ORDER BY RANDOM()`
@@ -207,7 +323,7 @@ func (r *UserRepository) GetActiveUsersFromTeam(teamID int, excludeUserID int) (
 	var users []*models.User
 	for rows.Next() {
 		user := &models.User{}
-		if err := rows.Scan(&user.ID, &user.Username, &user.Name, &user.IsActive, &user.TeamID, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		if err := scanUser(rows, user); err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
 		users = append(users, user)
@@ -235,21 +351,64 @@ func (r *UserRepository) BulkDeactivate(teamID int, userIDs []int) (int, error)
 		args[i+1] = id
 	}
 
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
 	query := fmt.Sprintf(`
 		UPDATE users 
 		SET is_active = false 
-		WHERE team_id = $1 AND id IN (%s) AND is_active = true`,
+		WHERE team_id = $1 AND id IN (%s) AND is_active = true
+		RETURNING id`,
 		strings.Join(placeholders, ","))
 
-	result, err := r.db.Exec(query, args...)
+	rows, err := tx.Query(query, args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to bulk deactivate users: %w", err)
 	}
 
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	var deactivated []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan deactivated user: %w", err)
+		}
+		deactivated = append(deactivated, id)
 	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate deactivated users: %w", err)
+	}
+
+	for _, id := range deactivated {
+		err := recordEvent(r.events, tx, webhook.EventUserDeactivated, webhook.UserAggregateKey(id),
+			webhook.UserDeactivatedData(id, teamID))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(deactivated), nil
+}
+
+// scanUser сканирует строку со столбцами userColumns
+func scanUser(row interface {
+	Scan(dest ...interface{}) error
+}, user *models.User) error {
+	return row.Scan(&user.ID, &user.Username, &user.Name, &user.IsActive, &user.TeamID,
+		&user.ChatHandle, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+}
 
-	return int(affected), nil
+// nullString сохраняет пустую строку как NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
 }
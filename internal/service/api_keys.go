@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/auth"
+	"github.com/user/pr-reviewer/internal/models"
+)
+
+// ErrInvalidScope неизвестная область доступа API ключа
+var ErrInvalidScope = errors.New("invalid scope")
+
+// CreateServiceAccount создаёт сервисный аккаунт
+func (s *Service) CreateServiceAccount(req *models.CreateServiceAccountRequest) (*models.ServiceAccount, error) {
+	account := &models.ServiceAccount{
+		Name:        req.Name,
+		Description: req.Description,
+		Role:        req.Role,
+		TeamID:      req.TeamID,
+		IsActive:    true,
+	}
+
+	if account.Role == "" {
+		account.Role = auth.RoleMember
+	}
+	if !auth.ValidRole(account.Role) {
+		return nil, fmt.Errorf("invalid role: %s", account.Role)
+	}
+
+	if req.TeamID != nil {
+		if _, err := s.teamRepo.GetByID(*req.TeamID); err != nil {
+			return nil, fmt.Errorf(errTeamNotFound)
+		}
+	}
+
+	if err := s.saRepo.Create(account); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// GetServiceAccounts возвращает все сервисные аккаунты
+func (s *Service) GetServiceAccounts() ([]*models.ServiceAccount, error) {
+	return s.saRepo.GetAll()
+}
+
+// CreateAPIKey выпускает API ключ сервисного аккаунта. Ключ возвращается
+// только в ответе на этот запрос, в БД сохраняется его хеш.
+func (s *Service) CreateAPIKey(accountID int, req *models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+	if _, err := s.saRepo.GetByID(accountID); err != nil {
+		return nil, err
+	}
+
+	if len(req.Scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	for _, scope := range req.Scopes {
+		if !auth.ValidScope(scope) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("expiresAt must be in the future")
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey := &models.APIKey{
+		ServiceAccountID: accountID,
+		Name:             req.Name,
+		Prefix:           prefix,
+		KeyHash:          hash,
+		Scopes:           req.Scopes,
+		ExpiresAt:        req.ExpiresAt,
+	}
+	if err := s.saRepo.CreateAPIKey(apiKey); err != nil {
+		return nil, err
+	}
+
+	return &models.CreateAPIKeyResponse{APIKey: apiKey, Key: key}, nil
+}
+
+// GetAPIKeys возвращает ключи сервисного аккаунта (без самих ключей)
+func (s *Service) GetAPIKeys(accountID int) ([]*models.APIKey, error) {
+	if _, err := s.saRepo.GetByID(accountID); err != nil {
+		return nil, err
+	}
+	return s.saRepo.GetAPIKeys(accountID)
+}
+
+// RevokeAPIKey отзывает API ключ
+func (s *Service) RevokeAPIKey(accountID int, keyID int64) error {
+	return s.saRepo.RevokeAPIKey(accountID, keyID)
+}
+
+// ValidateAPIKey проверяет API ключ и отмечает время его использования.
+// Реализует auth.APIKeyValidator.
+func (s *Service) ValidateAPIKey(_ context.Context, key string) (*auth.APIKeyPrincipal, error) {
+	apiKey, account, err := s.saRepo.GetAPIKeyByHash(auth.HashAPIKey(key))
+	if err != nil {
+		if err.Error() == "API key not found" {
+			return nil, auth.ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if apiKey.RevokedAt != nil || !account.IsActive {
+		return nil, auth.ErrInvalidAPIKey
+	}
+	if apiKey.ExpiresAt != nil && time.Now().After(*apiKey.ExpiresAt) {
+		return nil, auth.ErrExpiredAPIKey
+	}
+
+	// Время использования информационное: ошибка записи не блокирует запрос
+	_ = s.saRepo.TouchAPIKey(apiKey.ID)
+
+	principal := &auth.APIKeyPrincipal{
+		ServiceAccountID: int64(account.ID),
+		Name:             account.Name,
+		Role:             account.Role,
+		Scopes:           apiKey.Scopes,
+	}
+	if account.TeamID != nil {
+		principal.TeamID = int64(*account.TeamID)
+	}
+
+	return principal, nil
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/user/pr-reviewer/internal/auth"
+	"github.com/user/pr-reviewer/internal/models"
+	"github.com/user/pr-reviewer/internal/repository"
+)
+
+// DefaultRefreshTokenTTL время жизни refresh токена по умолчанию
+const DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	ErrInvalidCredentials  = errors.New("invalid username or password")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+)
+
+// SetRefreshTokenTTL задает время жизни refresh токенов
+func (s *Service) SetRefreshTokenTTL(ttl time.Duration) {
+	s.refreshTokenTTL = ttl
+}
+
+// Authenticate проверяет логин и пароль. Для неизвестного логина, пользователя
+// без пароля и деактивированного пользователя возвращается одна и та же ошибка;
+// если пользователь найден, он возвращается вместе с ошибкой для audit log.
+func (s *Service) Authenticate(username, password string) (*models.User, error) {
+	user, err := s.userRepo.GetByUsername(username)
+	if err != nil {
+		if err.Error() != errUserNotFound {
+			return nil, err
+		}
+		auth.CheckPassword("", password)
+		return nil, ErrInvalidCredentials
+	}
+
+	if !auth.CheckPassword(user.PasswordHash, password) || !user.IsActive {
+		return user, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// IssueRefreshToken выдает refresh токен для новой сессии
+func (s *Service) IssueRefreshToken(userID int, userAgent, ip string) (string, error) {
+	token, hash, err := newRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.tokenRepo.Create(&models.RefreshToken{
+		UserID:    userID,
+		TokenHash: hash,
+		FamilyID:  uuid.NewString(),
+		ExpiresAt: time.Now().Add(s.refreshTokenTTL),
+		UserAgent: userAgent,
+		IP:        ip,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken обменивает refresh токен на новый. Повторное использование
+// уже обмененного токена означает его утечку: вся цепочка сессии отзывается.
+func (s *Service) RotateRefreshToken(token, userAgent, ip string) (*models.User, string, error) {
+	current, err := s.tokenRepo.GetByHash(hashRefreshToken(token))
+	if err != nil {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	if current.RevokedAt != nil {
+		if _, err := s.tokenRepo.RevokeFamily(current.FamilyID); err != nil {
+			return nil, "", err
+		}
+		return nil, "", ErrInvalidRefreshToken
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	user, err := s.GetUser(current.UserID)
+	if err != nil || !user.IsActive {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	next, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = s.tokenRepo.Rotate(current.ID, &models.RefreshToken{
+		UserID:    current.UserID,
+		TokenHash: hash,
+		FamilyID:  current.FamilyID,
+		ExpiresAt: time.Now().Add(s.refreshTokenTTL),
+		UserAgent: userAgent,
+		IP:        ip,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenRevoked) {
+			// Токен обменян параллельным запросом
+			if _, err := s.tokenRepo.RevokeFamily(current.FamilyID); err != nil {
+				return nil, "", err
+			}
+			return nil, "", ErrInvalidRefreshToken
+		}
+		return nil, "", err
+	}
+
+	return user, next, nil
+}
+
+// RevokeRefreshToken завершает сессию: отзывает токен и всю цепочку его ротаций.
+// Возвращает ID владельца токена.
+func (s *Service) RevokeRefreshToken(token string) (int, error) {
+	current, err := s.tokenRepo.GetByHash(hashRefreshToken(token))
+	if err != nil {
+		return 0, ErrInvalidRefreshToken
+	}
+
+	if _, err := s.tokenRepo.RevokeFamily(current.FamilyID); err != nil {
+		return 0, err
+	}
+
+	return current.UserID, nil
+}
+
+// RevokeUserSessions завершает все сессии пользователя: отзывает его refresh токены.
+// Возвращает количество отозванных токенов.
+func (s *Service) RevokeUserSessions(userID int) (int64, error) {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return 0, err
+	}
+	return s.tokenRepo.RevokeAllForUser(userID)
+}
+
+// newRefreshToken генерирует случайный токен и его хеш для хранения
+func newRefreshToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashRefreshToken(token), nil
+}
+
+// hashRefreshToken возвращает SHA-256 токена в hex
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/user/pr-reviewer/internal/auth"
+	"github.com/user/pr-reviewer/internal/models"
+)
+
+// LoginWithOIDC находит или создает пользователя для учетной записи IdP.
+// Существующий пользователь привязывается по подтвержденному email. Роль
+// (если ее сопоставление настроено) и команда по группам синхронизируются
+// с IdP при каждом входе.
+func (s *Service) LoginWithOIDC(identity *auth.OIDCIdentity) (*models.User, error) {
+	user, err := s.userRepo.GetByOIDCSubject(identity.Issuer, identity.Subject)
+	if err != nil && err.Error() != errUserNotFound {
+		return nil, err
+	}
+
+	if user == nil && identity.Email != "" && identity.EmailVerified {
+		user, err = s.userRepo.GetByEmail(identity.Email)
+		if err != nil && err.Error() != errUserNotFound {
+			return nil, err
+		}
+		if user != nil {
+			if err := s.userRepo.LinkOIDCSubject(user.ID, identity.Issuer, identity.Subject); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var team *models.Team
+	if identity.Team != "" {
+		// Команда, которой нет в сервисе, не создается автоматически
+		team, err = s.teamRepo.GetByName(identity.Team)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if user == nil {
+		return s.createOIDCUser(identity, team)
+	}
+
+	if !user.IsActive {
+		return user, ErrInvalidCredentials
+	}
+
+	if identity.ManagedRole && user.Role != identity.Role {
+		updated, err := s.userRepo.Update(user.ID, &models.UpdateUserRequest{Role: &identity.Role})
+		if err != nil {
+			return nil, err
+		}
+		user = updated
+	}
+	if team != nil && (user.TeamID == nil || *user.TeamID != team.ID) {
+		if err := s.teamRepo.AddUser(team.ID, user.ID); err != nil {
+			return nil, err
+		}
+		user.TeamID = &team.ID
+	}
+
+	return user, nil
+}
+
+// createOIDCUser создает пользователя при первом входе через IdP
+func (s *Service) createOIDCUser(identity *auth.OIDCIdentity, team *models.Team) (*models.User, error) {
+	username := identity.Username
+	if existing, err := s.userRepo.GetByUsername(username); err == nil && existing != nil {
+		// Логин занят локальным пользователем: добавляем суффикс от subject
+		sum := sha256.Sum256([]byte(identity.Issuer + identity.Subject))
+		username = fmt.Sprintf("%s-%s", username, hex.EncodeToString(sum[:])[:8])
+	}
+
+	user := &models.User{
+		Username: username,
+		Name:     identity.Name,
+		IsActive: true,
+		Email:    identity.Email,
+		Role:     identity.Role,
+	}
+	if team != nil {
+		user.TeamID = &team.ID
+	}
+
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.LinkOIDCSubject(user.ID, identity.Issuer, identity.Subject); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -3,7 +3,9 @@ package service
 import (
 	"fmt"
 	"math/rand"
+	"time"
 
+	"github.com/user/pr-reviewer/internal/auth"
 	"github.com/user/pr-reviewer/internal/database"
 	"github.com/user/pr-reviewer/internal/models"
 	"github.com/user/pr-reviewer/internal/repository"
@@ -15,12 +17,25 @@ const (
 	errPRNotFound   = "PR not found"
 )
 
+const (
+	defaultNotificationsPageSize = 20
+	maxNotificationsPageSize     = 100
+)
+
 // Service предоставляет бизнес-логику приложения
 type Service struct {
 	teamRepo  *repository.TeamRepository
 	userRepo  *repository.UserRepository
 	prRepo    *repository.PRRepository
 	statsRepo *repository.StatisticsRepository
+	prefsRepo *repository.PreferencesRepository
+	notifRepo *repository.NotificationRepository
+	tokenRepo *repository.RefreshTokenRepository
+	saRepo    *repository.ServiceAccountRepository
+	keyRepo   *repository.SigningKeyRepository
+
+	refreshTokenTTL time.Duration
+	keyEncryptor    *auth.KeyEncryptor
 }
 
 // New создаёт новый экземпляр сервиса
@@ -30,9 +45,23 @@ func New(db *database.DB) *Service {
 		userRepo:  repository.NewUserRepository(db),
 		prRepo:    repository.NewPRRepository(db),
 		statsRepo: repository.NewStatisticsRepository(db),
+		prefsRepo: repository.NewPreferencesRepository(db),
+		notifRepo: repository.NewNotificationRepository(db),
+		tokenRepo: repository.NewRefreshTokenRepository(db),
+		saRepo:    repository.NewServiceAccountRepository(db),
+		keyRepo:   repository.NewSigningKeyRepository(db),
+
+		refreshTokenTTL: DefaultRefreshTokenTTL,
 	}
 }
 
+// SetEventRecorder подключает transactional outbox: события PR и рецензентов
+// записываются в той же транзакции, что и изменение данных
+func (s *Service) SetEventRecorder(rec repository.EventRecorder) {
+	s.prRepo.SetEventRecorder(rec)
+	s.userRepo.SetEventRecorder(rec)
+}
+
 // CreateTeam создаёт новую команду
 func (s *Service) CreateTeam(req *models.CreateTeamRequest) (*models.Team, error) {
 	// Проверяем, не существует ли уже команда с таким именем
@@ -78,10 +107,24 @@ func (s *Service) DeleteTeam(id int) error {
 // CreateUser создаёт нового пользователя
 func (s *Service) CreateUser(req *models.CreateUserRequest) (*models.User, error) {
 	user := &models.User{
-		Username: req.Username,
-		Name:     req.Name,
-		IsActive: true,
-		TeamID:   req.TeamID,
+		Username:   req.Username,
+		Name:       req.Name,
+		IsActive:   true,
+		TeamID:     req.TeamID,
+		ChatHandle: req.ChatHandle,
+		Email:      req.Email,
+		Role:       req.Role,
+	}
+
+	if req.Role != "" && !auth.ValidRole(req.Role) {
+		return nil, fmt.Errorf("invalid role: %s", req.Role)
+	}
+	if req.Password != "" {
+		hash, err := auth.HashPassword(req.Password)
+		if err != nil {
+			return nil, err
+		}
+		user.PasswordHash = hash
 	}
 
 	// Проверяем существование команды, если указана
@@ -146,6 +189,24 @@ func (s *Service) GetAllUsers(teamID *int, isActive *bool) ([]*models.User, erro
 
 // UpdateUser обновляет пользователя
 func (s *Service) UpdateUser(id int, req *models.UpdateUserRequest) (*models.User, error) {
+	if req.Role != nil && !auth.ValidRole(*req.Role) {
+		return nil, fmt.Errorf("invalid role: %s", *req.Role)
+	}
+
+	// Смена пароля завершает все сессии пользователя
+	if req.Password != nil {
+		hash, err := auth.HashPassword(*req.Password)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.userRepo.UpdatePassword(id, hash); err != nil {
+			return nil, err
+		}
+		if _, err := s.tokenRepo.RevokeAllForUser(id); err != nil {
+			return nil, err
+		}
+	}
+
 	user, err := s.userRepo.Update(id, req)
 	if err != nil {
 		return nil, err
@@ -197,9 +258,11 @@ func (s *Service) CreatePullRequest(req *models.CreatePullRequestRequest) (*mode
 	}
 
 	pr := &models.PullRequest{
-		Title:    req.Title,
-		AuthorID: req.AuthorID,
-		Status:   models.PRStatusOpen,
+		Title:      req.Title,
+		Repository: req.Repository,
+		Labels:     req.Labels,
+		AuthorID:   req.AuthorID,
+		Status:     models.PRStatusOpen,
 	}
 
 	// Автоматически назначаем рецензентов, если автор в команде
@@ -442,6 +505,99 @@ func (s *Service) BulkDeactivateUsers(teamID int, req *models.BulkDeactivateRequ
 	}, nil
 }
 
+// GetNotificationPreferences возвращает настройки уведомлений пользователя
+func (s *Service) GetNotificationPreferences(userID int) (*models.NotificationPreferences, error) {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, fmt.Errorf(errUserNotFound)
+	}
+	return s.prefsRepo.Get(userID)
+}
+
+// UpdateNotificationPreferences изменяет настройки уведомлений пользователя
+func (s *Service) UpdateNotificationPreferences(userID int, req *models.UpdateNotificationPreferencesRequest) (*models.NotificationPreferences, error) {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, fmt.Errorf(errUserNotFound)
+	}
+	return s.prefsRepo.Update(userID, req)
+}
+
+// GetDigestRecipients возвращает пользователей, подписанных на дайджест
+func (s *Service) GetDigestRecipients() ([]*models.User, error) {
+	return s.prefsRepo.GetDigestRecipients()
+}
+
+// ClaimDigest резервирует отправку дайджеста пользователю за день
+func (s *Service) ClaimDigest(userID int, day time.Time) (bool, error) {
+	return s.prefsRepo.ClaimDigest(userID, day)
+}
+
+// ReleaseDigest снимает резерв дайджеста, если письмо не удалось отправить
+func (s *Service) ReleaseDigest(userID int, day time.Time) error {
+	return s.prefsRepo.ReleaseDigest(userID, day)
+}
+
+// ClaimAssignmentEmail резервирует отправку письма о назначении по событию outbox
+func (s *Service) ClaimAssignmentEmail(outboxID int64, userID int) (bool, error) {
+	return s.prefsRepo.ClaimAssignmentEmail(outboxID, userID)
+}
+
+// ReleaseAssignmentEmail снимает резерв неотправленного письма о назначении
+func (s *Service) ReleaseAssignmentEmail(outboxID int64, userID int) error {
+	return s.prefsRepo.ReleaseAssignmentEmail(outboxID, userID)
+}
+
+// CreateNotification сохраняет уведомление пользователя
+func (s *Service) CreateNotification(n *models.Notification) error {
+	return s.notifRepo.Create(n)
+}
+
+// GetNotifications возвращает страницу уведомлений пользователя
+func (s *Service) GetNotifications(userID int, unreadOnly bool, limit, offset int) (*models.NotificationList, error) {
+	if limit <= 0 || limit > maxNotificationsPageSize {
+		limit = defaultNotificationsPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	notifications, err := s.notifRepo.GetByUser(userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	unread, err := s.notifRepo.CountUnread(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.NotificationList{
+		Notifications: notifications,
+		UnreadCount:   unread,
+		Limit:         limit,
+		Offset:        offset,
+	}, nil
+}
+
+// MarkNotificationRead отмечает уведомление пользователя прочитанным
+func (s *Service) MarkNotificationRead(userID int, id int64) error {
+	return s.notifRepo.MarkRead(userID, id)
+}
+
+// MarkAllNotificationsRead отмечает все уведомления пользователя прочитанными
+func (s *Service) MarkAllNotificationsRead(userID int) (int, error) {
+	return s.notifRepo.MarkAllRead(userID)
+}
+
+// DeleteNotificationsBefore удаляет уведомления старше cutoff
+func (s *Service) DeleteNotificationsBefore(cutoff time.Time) (int64, error) {
+	return s.notifRepo.DeleteOlderThan(cutoff)
+}
+
+// GetPendingReviews возвращает открытые PR, ожидающие ревью пользователя
+func (s *Service) GetPendingReviews(userID int) ([]*models.PullRequest, error) {
+	return s.prRepo.GetOpenPRsWithReviewer(userID)
+}
+
 // GetStatistics возвращает статистику
 func (s *Service) GetStatistics() (*models.Statistics, error) {
 	return s.statsRepo.GetStatistics()
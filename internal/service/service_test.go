@@ -105,3 +105,21 @@ func TestPRStatusValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestNewRefreshToken(t *testing.T) {
+	token, hash, err := newRefreshToken()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(token) != 43 {
+		t.Errorf("expected 43 character token, got %d", len(token))
+	}
+	if hash != hashRefreshToken(token) || len(hash) != 64 {
+		t.Errorf("unexpected token hash %q", hash)
+	}
+
+	other, _, _ := newRefreshToken()
+	if other == token {
+		t.Error("expected unique refresh tokens")
+	}
+}
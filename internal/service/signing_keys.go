@@ -0,0 +1,103 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/auth"
+	"github.com/user/pr-reviewer/internal/models"
+)
+
+// Методы ниже реализуют auth.KeyStore: ключи подписи JWT хранятся в БД,
+// чтобы все реплики подписывали и проверяли токены одним набором ключей.
+
+// SetKeyEncryptor включает шифрование приватных ключей подписи в БД. Ключи,
+// сохраненные до включения шифрования, шифруются при следующей загрузке.
+func (s *Service) SetKeyEncryptor(enc *auth.KeyEncryptor) {
+	s.keyEncryptor = enc
+}
+
+// LoadSigningKeys загружает ключи подписи JWT
+func (s *Service) LoadSigningKeys() ([]*auth.SigningKey, error) {
+	stored, err := s.keyRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*auth.SigningKey, 0, len(stored))
+	for _, k := range stored {
+		pemKey, err := s.decryptSigningKey(k)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, err := auth.ParsePrivateKey(k.Algorithm, pemKey)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, &auth.SigningKey{
+			ID:         k.ID,
+			Algorithm:  k.Algorithm,
+			PrivateKey: privateKey,
+			CreatedAt:  k.CreatedAt,
+			RetiredAt:  k.RetiredAt,
+		})
+	}
+
+	return keys, nil
+}
+
+// SaveSigningKey сохраняет новый ключ подписи JWT
+func (s *Service) SaveSigningKey(key *auth.SigningKey) error {
+	privateKey, err := key.MarshalPrivateKey()
+	if err != nil {
+		return err
+	}
+	if s.keyEncryptor != nil {
+		if privateKey, err = s.keyEncryptor.Encrypt(key.ID, privateKey); err != nil {
+			return err
+		}
+	}
+
+	return s.keyRepo.Create(&models.SigningKey{
+		ID:         key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: privateKey,
+		CreatedAt:  key.CreatedAt,
+	})
+}
+
+// decryptSigningKey возвращает приватный ключ в PEM. Открытый ключ,
+// сохраненный до включения шифрования, шифруется в БД.
+func (s *Service) decryptSigningKey(k *models.SigningKey) ([]byte, error) {
+	if auth.IsEncryptedKey(k.PrivateKey) {
+		if s.keyEncryptor == nil {
+			return nil, fmt.Errorf("signing key %s is encrypted but no key encryption key is configured", k.ID)
+		}
+		pemKey, err := s.keyEncryptor.Decrypt(k.ID, k.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", k.ID, err)
+		}
+		return pemKey, nil
+	}
+
+	if s.keyEncryptor != nil {
+		encrypted, err := s.keyEncryptor.Encrypt(k.ID, k.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.keyRepo.ReplacePrivateKey(k.ID, k.PrivateKey, encrypted); err != nil {
+			return nil, err
+		}
+	}
+	return k.PrivateKey, nil
+}
+
+// RetireOlderSigningKeys выводит из работы все ключи подписи, кроме самого нового
+func (s *Service) RetireOlderSigningKeys(at time.Time) error {
+	return s.keyRepo.RetireAllButNewest(at)
+}
+
+// DeleteSigningKeysRetiredBefore удаляет ключи подписи с истекшим grace периодом
+func (s *Service) DeleteSigningKeysRetiredBefore(before time.Time) error {
+	return s.keyRepo.DeleteRetiredBefore(before)
+}
@@ -0,0 +1,212 @@
+package stream
+
+import (
+	"sync"
+)
+
+// DefaultBufferSize размер буфера событий для возобновления по Last-Event-ID
+const DefaultBufferSize = 1000
+
+// subscriberBuffer размер очереди одного подписчика
+const subscriberBuffer = 64
+
+// Event событие изменения PR или рецензентов для live stream.
+// ID совпадает с ID события в webhook outbox.
+type Event struct {
+	ID   int64                  `json:"id"`
+	Type string                 `json:"event"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// Filter ограничивает события командой и/или пользователем.
+// Пользователь совпадает, если он автор PR или один из рецензентов.
+type Filter struct {
+	TeamID int64
+	UserID int64
+}
+
+// userFields поля события, в которых может встречаться пользователь
+var userFields = []string{"author_id", "reviewer_ids", "reviewer_id", "new_reviewer_id", "old_reviewer_id"}
+
+// Matches проверяет, подходит ли событие под фильтр
+func (f Filter) Matches(e *Event) bool {
+	if f.TeamID != 0 && !containsID(e.Data["team_id"], f.TeamID) {
+		return false
+	}
+	if f.UserID != 0 {
+		for _, field := range userFields {
+			if containsID(e.Data[field], f.UserID) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Subscription подписка на события. Канал C закрывается, если подписчик
+// не успевает читать события; клиент должен переподключиться с Last-Event-ID.
+type Subscription struct {
+	C      <-chan *Event
+	ch     chan *Event
+	filter Filter
+}
+
+// Broker раздает события подписчикам текущей реплики и хранит последние
+// события в кольцевом буфере для возобновления после переподключения.
+type Broker struct {
+	mu          sync.Mutex
+	buffer      []*Event
+	next        int
+	size        int
+	seen        map[int64]struct{}
+	subscribers map[*Subscription]struct{}
+}
+
+// NewBroker создает брокер с буфером на bufferSize событий
+func NewBroker(bufferSize int) *Broker {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	return &Broker{
+		buffer:      make([]*Event, bufferSize),
+		seen:        make(map[int64]struct{}, bufferSize),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish добавляет событие в буфер и рассылает подписчикам.
+// Повторно опубликованные события (at-least-once доставка outbox) игнорируются.
+func (b *Broker) Publish(e *Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.seen[e.ID]; ok {
+		return
+	}
+
+	if old := b.buffer[b.next]; old != nil {
+		delete(b.seen, old.ID)
+	}
+	b.buffer[b.next] = e
+	b.seen[e.ID] = struct{}{}
+	b.next = (b.next + 1) % len(b.buffer)
+	if b.size < len(b.buffer) {
+		b.size++
+	}
+
+	for sub := range b.subscribers {
+		if !sub.filter.Matches(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			// Медленный подписчик: отключаем, он продолжит с Last-Event-ID
+			delete(b.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Subscribe регистрирует подписчика и возвращает события из буфера после
+// lastEventID. resumed равен false, если lastEventID уже вытеснен из буфера
+// и клиенту нужно заново загрузить состояние.
+func (b *Broker) Subscribe(filter Filter, lastEventID int64) (sub *Subscription, replay []*Event, resumed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	resumed = true
+	if lastEventID > 0 {
+		events := b.buffered()
+		pos := -1
+		for i, e := range events {
+			if e.ID == lastEventID {
+				pos = i
+				break
+			}
+		}
+		if pos < 0 {
+			resumed = false
+		} else {
+			for _, e := range events[pos+1:] {
+				if filter.Matches(e) {
+					replay = append(replay, e)
+				}
+			}
+		}
+	}
+
+	ch := make(chan *Event, subscriberBuffer)
+	sub = &Subscription{C: ch, ch: ch, filter: filter}
+	b.subscribers[sub] = struct{}{}
+	return sub, replay, resumed
+}
+
+// Unsubscribe удаляет подписчика
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// LastID возвращает ID последнего опубликованного события
+func (b *Broker) LastID() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.size == 0 {
+		return 0
+	}
+	return b.buffer[(b.next-1+len(b.buffer))%len(b.buffer)].ID
+}
+
+// buffered возвращает события буфера в порядке публикации
+func (b *Broker) buffered() []*Event {
+	events := make([]*Event, 0, b.size)
+	start := (b.next - b.size + len(b.buffer)) % len(b.buffer)
+	for i := 0; i < b.size; i++ {
+		events = append(events, b.buffer[(start+i)%len(b.buffer)])
+	}
+	return events
+}
+
+// containsID проверяет, содержит ли значение (число или массив чисел) id.
+// События из outbox проходят через JSON, поэтому числа приходят как float64.
+func containsID(value interface{}, id int64) bool {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if n, ok := toInt64(item); ok && n == id {
+				return true
+			}
+		}
+	case []int:
+		for _, item := range v {
+			if int64(item) == id {
+				return true
+			}
+		}
+	default:
+		if n, ok := toInt64(v); ok && n == id {
+			return true
+		}
+	}
+	return false
+}
+
+func toInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,115 @@
+package stream
+
+import (
+	"testing"
+)
+
+func prEvent(id int64, teamID, authorID int, reviewers ...interface{}) *Event {
+	return &Event{
+		ID:   id,
+		Type: "pr.created",
+		Data: map[string]interface{}{
+			"pr_id":        float64(id),
+			"team_id":      float64(teamID),
+			"author_id":    float64(authorID),
+			"reviewer_ids": reviewers,
+		},
+	}
+}
+
+func TestFilter_Matches(t *testing.T) {
+	event := prEvent(1, 3, 10, float64(20), float64(21))
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty", Filter{}, true},
+		{"team", Filter{TeamID: 3}, true},
+		{"other team", Filter{TeamID: 4}, false},
+		{"author", Filter{UserID: 10}, true},
+		{"reviewer", Filter{UserID: 21}, true},
+		{"unrelated user", Filter{UserID: 99}, false},
+		{"team and user", Filter{TeamID: 3, UserID: 20}, true},
+		{"team mismatch with user", Filter{TeamID: 4, UserID: 20}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(event); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	reassigned := &Event{ID: 2, Type: "reviewer.changed", Data: map[string]interface{}{
+		"pr_id": float64(1), "old_reviewer_id": float64(20), "new_reviewer_id": float64(22),
+	}}
+	if !(Filter{UserID: 20}).Matches(reassigned) || !(Filter{UserID: 22}).Matches(reassigned) {
+		t.Error("expected both old and new reviewer to match reviewer.changed")
+	}
+}
+
+func TestBroker_PublishAndResume(t *testing.T) {
+	b := NewBroker(3)
+
+	sub, replay, resumed := b.Subscribe(Filter{TeamID: 1}, 0)
+	if !resumed || len(replay) != 0 {
+		t.Fatalf("expected fresh subscription, got resumed=%v replay=%d", resumed, len(replay))
+	}
+
+	b.Publish(prEvent(1, 1, 10))
+	b.Publish(prEvent(2, 2, 10))
+	b.Publish(prEvent(1, 1, 10)) // повторная доставка из outbox
+	b.Publish(prEvent(3, 1, 10))
+
+	if got := (<-sub.C).ID; got != 1 {
+		t.Errorf("expected event 1, got %d", got)
+	}
+	if got := (<-sub.C).ID; got != 3 {
+		t.Errorf("expected event 3, got %d", got)
+	}
+	select {
+	case e := <-sub.C:
+		t.Errorf("unexpected event %d", e.ID)
+	default:
+	}
+	b.Unsubscribe(sub)
+
+	// Возобновление после события 1 отдает только подходящие события после него
+	_, replay, resumed = b.Subscribe(Filter{TeamID: 1}, 1)
+	if !resumed || len(replay) != 1 || replay[0].ID != 3 {
+		t.Errorf("expected replay of event 3, got resumed=%v replay=%v", resumed, replay)
+	}
+
+	// Буфер на 3 события: событие 1 вытесняется
+	b.Publish(prEvent(4, 1, 10))
+	_, _, resumed = b.Subscribe(Filter{}, 1)
+	if resumed {
+		t.Error("expected reset when Last-Event-ID is no longer buffered")
+	}
+	if b.LastID() != 4 {
+		t.Errorf("expected last ID 4, got %d", b.LastID())
+	}
+}
+
+func TestBroker_DropsSlowSubscriber(t *testing.T) {
+	b := NewBroker(subscriberBuffer * 2)
+	sub, _, _ := b.Subscribe(Filter{}, 0)
+
+	for i := 1; i <= subscriberBuffer+1; i++ {
+		b.Publish(prEvent(int64(i), 1, 10))
+	}
+
+	count := 0
+	for range sub.C {
+		count++
+	}
+	if count != subscriberBuffer {
+		t.Errorf("expected %d buffered events before disconnect, got %d", subscriberBuffer, count)
+	}
+
+	// Повторная отписка уже отключенного подписчика безопасна
+	b.Unsubscribe(sub)
+}
@@ -0,0 +1,192 @@
+package stream
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/user/pr-reviewer/internal/logger"
+	"github.com/user/pr-reviewer/internal/webhook"
+)
+
+// Channel канал Postgres LISTEN/NOTIFY для событий PR
+const Channel = "pr_events"
+
+// HandlerName имя Notifier в outbox relay
+const HandlerName = "stream"
+
+// maxNotifyPayload ограничение на размер NOTIFY payload (лимит Postgres 8000 байт).
+// Большие события передаются только по ID и загружаются из outbox.
+const maxNotifyPayload = 7500
+
+// Notifier публикует события PR и рецензентов через pg_notify, чтобы их
+// получили все реплики. Реализует webhook.EventHandler и подключается к outbox relay.
+type Notifier struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewNotifier создает новый notifier
+func NewNotifier(db *sql.DB, log *logger.Logger) *Notifier {
+	return &Notifier{
+		db:     db,
+		logger: log,
+	}
+}
+
+// HandleEvent отправляет событие в канал NOTIFY. Live stream работает по
+// принципу best-effort, поэтому ошибка только логируется и не вызывает
+// повторную доставку события relay.
+func (n *Notifier) HandleEvent(ctx context.Context, event *webhook.OutboxEvent) error {
+	if _, ok := event.Data["pr_id"]; !ok {
+		return nil
+	}
+
+	payload, err := json.Marshal(&Event{ID: event.ID, Type: string(event.Event), Data: event.Data})
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream event: %w", err)
+	}
+	if len(payload) > maxNotifyPayload {
+		payload, _ = json.Marshal(&Event{ID: event.ID})
+	}
+
+	if _, err := n.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", Channel, string(payload)); err != nil {
+		n.logger.Warnw("Failed to notify stream event",
+			"outbox_id", event.ID,
+			"error", err,
+		)
+	}
+	return nil
+}
+
+// Listener получает события из LISTEN/NOTIFY и публикует их в локальный брокер
+type Listener struct {
+	dsn    string
+	db     *sql.DB
+	broker *Broker
+	logger *logger.Logger
+}
+
+// NewListener создает listener. db используется для загрузки событий,
+// переданных только по ID, и для догрузки пропущенных событий после переподключения.
+func NewListener(dsn string, db *sql.DB, broker *Broker, log *logger.Logger) *Listener {
+	return &Listener{
+		dsn:    dsn,
+		db:     db,
+		broker: broker,
+		logger: log,
+	}
+}
+
+// Run слушает канал до отмены контекста
+func (l *Listener) Run(ctx context.Context) error {
+	listener := pq.NewListener(l.dsn, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			l.logger.Warnw("Stream listener connection event", "event", ev, "error", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(Channel); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", Channel, err)
+	}
+	l.logger.Infow("Stream listener started", "channel", Channel)
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			l.logger.Info("Stream listener stopped")
+			return nil
+		case n := <-listener.Notify:
+			if n == nil {
+				// Соединение было восстановлено: уведомления за время разрыва потеряны
+				l.backfill(ctx)
+				continue
+			}
+			l.handle(ctx, n.Extra)
+		case <-ping.C:
+			go func() {
+				_ = listener.Ping()
+			}()
+		}
+	}
+}
+
+// handle разбирает уведомление и публикует событие
+func (l *Listener) handle(ctx context.Context, payload string) {
+	event := &Event{}
+	if err := json.Unmarshal([]byte(payload), event); err != nil {
+		l.logger.Warnw("Failed to decode stream notification", "error", err)
+		return
+	}
+
+	if event.Data == nil {
+		loaded, err := l.load(ctx, event.ID)
+		if err != nil {
+			l.logger.Warnw("Failed to load stream event", "outbox_id", event.ID, "error", err)
+			return
+		}
+		event = loaded
+	}
+
+	l.broker.Publish(event)
+}
+
+// load загружает событие из outbox по ID
+func (l *Listener) load(ctx context.Context, id int64) (*Event, error) {
+	event := &Event{ID: id}
+	var payload []byte
+	err := l.db.QueryRowContext(ctx,
+		"SELECT event, payload FROM webhook_outbox WHERE id = $1", id,
+	).Scan(&event.Type, &payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(payload, &event.Data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal outbox payload: %w", err)
+	}
+	return event, nil
+}
+
+// backfill догружает обработанные события после последнего известного брокеру
+func (l *Listener) backfill(ctx context.Context) {
+	lastID := l.broker.LastID()
+	if lastID == 0 {
+		return
+	}
+
+	rows, err := l.db.QueryContext(ctx, `
+		SELECT o.id, o.event, o.payload
+		FROM webhook_outbox o
+		JOIN webhook_outbox_deliveries d ON d.outbox_id = o.id AND d.handler = $3
+		WHERE o.id > $1 AND d.status = 'processed' AND o.payload ? 'pr_id'
+		ORDER BY o.id
+		LIMIT $2`, lastID, len(l.broker.buffer), HandlerName)
+	if err != nil {
+		l.logger.Warnw("Failed to backfill stream events", "error", err)
+		return
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		event := &Event{}
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.Type, &payload); err != nil {
+			l.logger.Warnw("Failed to scan stream event", "error", err)
+			return
+		}
+		if err := json.Unmarshal(payload, &event.Data); err != nil {
+			continue
+		}
+		l.broker.Publish(event)
+		count++
+	}
+	l.logger.Infow("Stream listener reconnected", "backfilled", count)
+}
@@ -0,0 +1,387 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/user/pr-reviewer/internal/auth"
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+const (
+	// wsWriteWait время на запись одного сообщения клиенту
+	wsWriteWait = 10 * time.Second
+	// wsPongWait время ожидания pong (или любого сообщения) от клиента
+	wsPongWait = 60 * time.Second
+	// wsPingPeriod интервал ping, должен быть меньше wsPongWait
+	wsPingPeriod = 30 * time.Second
+	// wsMaxMessageSize максимальный размер сообщения клиента
+	wsMaxMessageSize = 4096
+	// wsMaxTopics максимальное число тем на одно соединение
+	wsMaxTopics = 100
+)
+
+// Типы тем подписки
+const (
+	TopicTeam = "team"
+	TopicPR   = "pr"
+	TopicUser = "user"
+)
+
+var (
+	ErrInvalidTopic   = errors.New("invalid topic")
+	ErrForbiddenTopic = errors.New("topic is not allowed")
+)
+
+// Topic тема подписки WebSocket: team:5, pr:42, user:me
+type Topic struct {
+	Kind string
+	ID   int64
+}
+
+// String возвращает тему в формате kind:id
+func (t Topic) String() string {
+	return t.Kind + ":" + strconv.FormatInt(t.ID, 10)
+}
+
+// Access права подписчика. Admin видит все события, остальные - только
+// события своей команды и события со своим участием.
+type Access struct {
+	UserID int64
+	TeamID int64
+	Admin  bool
+}
+
+// CanSee проверяет, что событие доступно подписчику
+func (a Access) CanSee(e *Event) bool {
+	if a.Admin {
+		return true
+	}
+	if a.TeamID != 0 && (Filter{TeamID: a.TeamID}).Matches(e) {
+		return true
+	}
+	return a.UserID != 0 && (Filter{UserID: a.UserID}).Matches(e)
+}
+
+// ParseTopic разбирает тему. user:me заменяется на ID текущего пользователя.
+// Не admin может подписаться только на свою команду и на себя; события
+// темы pr:N дополнительно проверяются через Access.CanSee.
+func ParseTopic(s string, access Access) (Topic, error) {
+	kind, value, ok := strings.Cut(s, ":")
+	if !ok {
+		return Topic{}, ErrInvalidTopic
+	}
+
+	if kind == TopicUser && value == "me" {
+		if access.UserID == 0 {
+			return Topic{}, ErrForbiddenTopic
+		}
+		return Topic{Kind: TopicUser, ID: access.UserID}, nil
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return Topic{}, ErrInvalidTopic
+	}
+
+	switch kind {
+	case TopicPR:
+		return Topic{Kind: kind, ID: id}, nil
+	case TopicTeam:
+		if !access.Admin && id != access.TeamID {
+			return Topic{}, ErrForbiddenTopic
+		}
+		return Topic{Kind: kind, ID: id}, nil
+	case TopicUser:
+		if !access.Admin && id != access.UserID {
+			return Topic{}, ErrForbiddenTopic
+		}
+		return Topic{Kind: kind, ID: id}, nil
+	}
+	return Topic{}, ErrInvalidTopic
+}
+
+// Matches проверяет, относится ли событие к теме
+func (t Topic) Matches(e *Event) bool {
+	switch t.Kind {
+	case TopicTeam:
+		return Filter{TeamID: t.ID}.Matches(e)
+	case TopicPR:
+		return containsID(e.Data["pr_id"], t.ID)
+	case TopicUser:
+		return Filter{UserID: t.ID}.Matches(e)
+	}
+	return false
+}
+
+// Типы сообщений WebSocket протокола
+const (
+	wsActionSubscribe   = "subscribe"
+	wsActionUnsubscribe = "unsubscribe"
+
+	wsTypeEvent        = "event"
+	wsTypeSubscribed   = "subscribed"
+	wsTypeUnsubscribed = "unsubscribed"
+	wsTypeReset        = "reset"
+	wsTypeError        = "error"
+)
+
+// wsRequest сообщение клиента
+type wsRequest struct {
+	Action string   `json:"action"`
+	Topics []string `json:"topics"`
+}
+
+// wsMessage сообщение сервера. События передаются в том же конверте, что и в SSE.
+type wsMessage struct {
+	Type   string   `json:"type"`
+	Topics []string `json:"topics,omitempty"`
+	Error  string   `json:"error,omitempty"`
+	*Event
+}
+
+// WebSocketServer обслуживает WebSocket соединения дашборда
+type WebSocketServer struct {
+	broker   *Broker
+	upgrader websocket.Upgrader
+	logger   *logger.Logger
+}
+
+// NewWebSocketServer создает WebSocket сервер. allowedOrigins ограничивает
+// Origin браузерных клиентов; пустой список разрешает только тот же хост.
+func NewWebSocketServer(broker *Broker, allowedOrigins []string, log *logger.Logger) *WebSocketServer {
+	s := &WebSocketServer{
+		broker: broker,
+		logger: log,
+	}
+	s.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 4096,
+		// Сервер выбирает только "bearer": токен из соседнего подпротокола
+		// не возвращается в ответе (см. auth.WebSocketProtocol)
+		Subprotocols: []string{auth.WebSocketProtocol},
+	}
+	if len(allowedOrigins) > 0 {
+		s.upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return true
+			}
+			for _, allowed := range allowedOrigins {
+				if allowed == "*" || strings.EqualFold(origin, allowed) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return s
+}
+
+// wsSession одно WebSocket соединение
+type wsSession struct {
+	conn    *websocket.Conn
+	access  Access
+	sub     *Subscription
+	control chan *wsMessage
+
+	mu     sync.RWMutex
+	topics map[Topic]struct{}
+}
+
+// Serve переводит соединение в WebSocket и обслуживает его до закрытия.
+// access ограничивает темы и события аутентифицированного пользователя.
+func (s *WebSocketServer) Serve(w http.ResponseWriter, r *http.Request, access Access, lastEventID int64) error {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrader уже отправил ответ с ошибкой
+		return fmt.Errorf("failed to upgrade connection: %w", err)
+	}
+	defer conn.Close()
+
+	sub, replay, resumed := s.broker.Subscribe(Filter{}, lastEventID)
+	defer s.broker.Unsubscribe(sub)
+
+	session := &wsSession{
+		conn:    conn,
+		access:  access,
+		sub:     sub,
+		control: make(chan *wsMessage, 16),
+		topics:  make(map[Topic]struct{}),
+	}
+
+	// lastEventId вытеснен из буфера: клиент должен заново загрузить состояние
+	if !resumed {
+		session.control <- &wsMessage{Type: wsTypeReset}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		session.readLoop()
+	}()
+
+	s.logger.Debugw("WebSocket client connected", "user_id", access.UserID)
+	session.writeLoop(done, replay)
+	s.logger.Debugw("WebSocket client disconnected", "user_id", access.UserID)
+	return nil
+}
+
+// readLoop обрабатывает команды подписки клиента
+func (c *wsSession) readLoop() {
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var req wsRequest
+		if err := c.conn.ReadJSON(&req); err != nil {
+			// Некорректный JSON не закрывает соединение, остальные ошибки фатальны
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr) {
+				return
+			}
+			if !c.reply(&wsMessage{Type: wsTypeError, Error: "invalid message"}) {
+				return
+			}
+			continue
+		}
+		_ = c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		if !c.reply(c.handle(&req)) {
+			return
+		}
+	}
+}
+
+// handle применяет команду и возвращает ответ клиенту
+func (c *wsSession) handle(req *wsRequest) *wsMessage {
+	topics := make([]Topic, 0, len(req.Topics))
+	for _, raw := range req.Topics {
+		topic, err := ParseTopic(raw, c.access)
+		if err != nil {
+			return &wsMessage{Type: wsTypeError, Error: fmt.Sprintf("%s: %s", err.Error(), raw)}
+		}
+		topics = append(topics, topic)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch req.Action {
+	case wsActionSubscribe:
+		if len(c.topics)+len(topics) > wsMaxTopics {
+			return &wsMessage{Type: wsTypeError, Error: "too many topics"}
+		}
+		for _, topic := range topics {
+			c.topics[topic] = struct{}{}
+		}
+		return &wsMessage{Type: wsTypeSubscribed, Topics: topicNames(topics)}
+	case wsActionUnsubscribe:
+		for _, topic := range topics {
+			delete(c.topics, topic)
+		}
+		return &wsMessage{Type: wsTypeUnsubscribed, Topics: topicNames(topics)}
+	}
+	return &wsMessage{Type: wsTypeError, Error: "unknown action: " + req.Action}
+}
+
+// reply ставит ответ в очередь записи. Переполнение очереди означает, что
+// клиент не читает ответы, и соединение закрывается.
+func (c *wsSession) reply(msg *wsMessage) bool {
+	select {
+	case c.control <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeLoop единственный писатель в соединение: события, ответы и ping
+func (c *wsSession) writeLoop(done <-chan struct{}, replay []*Event) {
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case msg := <-c.control:
+			if err := c.write(msg); err != nil {
+				return
+			}
+			// Буфер событий отдается после первой подписки, когда известны темы
+			if msg.Type == wsTypeSubscribed && replay != nil {
+				for _, event := range replay {
+					if c.wants(event) {
+						if err := c.write(&wsMessage{Type: wsTypeEvent, Event: event}); err != nil {
+							return
+						}
+					}
+				}
+				replay = nil
+			}
+		case event, ok := <-c.sub.C:
+			if !ok {
+				// Брокер отключил медленного клиента: он переподключится с lastEventId
+				c.close(websocket.CloseTryAgainLater, "slow consumer")
+				return
+			}
+			if !c.wants(event) {
+				continue
+			}
+			if err := c.write(&wsMessage{Type: wsTypeEvent, Event: event}); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// wants проверяет, подписан ли клиент на событие и доступно ли оно ему
+func (c *wsSession) wants(e *Event) bool {
+	if !c.access.CanSee(e) {
+		return false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for topic := range c.topics {
+		if topic.Matches(e) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *wsSession) write(msg *wsMessage) error {
+	_ = c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return c.conn.WriteJSON(msg)
+}
+
+func (c *wsSession) close(code int, reason string) {
+	_ = c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason), time.Now().Add(wsWriteWait))
+}
+
+func topicNames(topics []Topic) []string {
+	names := make([]string, 0, len(topics))
+	for _, t := range topics {
+		names = append(names, t.String())
+	}
+	return names
+}
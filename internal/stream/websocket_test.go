@@ -0,0 +1,111 @@
+package stream
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/user/pr-reviewer/internal/auth"
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+func TestParseTopic(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    Topic
+		wantErr error
+	}{
+		{"team:5", Topic{Kind: TopicTeam, ID: 5}, nil},
+		{"pr:42", Topic{Kind: TopicPR, ID: 42}, nil},
+		{"user:me", Topic{Kind: TopicUser, ID: 7}, nil},
+		{"user:7", Topic{Kind: TopicUser, ID: 7}, nil},
+		{"user:8", Topic{}, ErrForbiddenTopic},
+		{"team:6", Topic{}, ErrForbiddenTopic},
+		{"team:abc", Topic{}, ErrInvalidTopic},
+		{"repo:1", Topic{}, ErrInvalidTopic},
+		{"team", Topic{}, ErrInvalidTopic},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseTopic(tt.raw, Access{UserID: 7, TeamID: 5})
+			if err != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// Admin может подписаться на любую команду и пользователя
+	if _, err := ParseTopic("team:6", Access{UserID: 1, Admin: true}); err != nil {
+		t.Errorf("expected admin to subscribe to any team, got %v", err)
+	}
+}
+
+func TestWebSocketServer_Subscribe(t *testing.T) {
+	log, err := logger.New("error", "test")
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	broker := NewBroker(10)
+	ws := NewWebSocketServer(broker, nil, log)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = ws.Serve(w, r, Access{UserID: 7, TeamID: 1}, 0)
+	}))
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{auth.WebSocketProtocol, "token"}}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	if protocol := resp.Header.Get("Sec-WebSocket-Protocol"); protocol != auth.WebSocketProtocol {
+		t.Errorf("expected server to select %q without echoing the token, got %q", auth.WebSocketProtocol, protocol)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	read := func() map[string]interface{} {
+		t.Helper()
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("failed to read message: %v", err)
+		}
+		return msg
+	}
+
+	if err := conn.WriteJSON(map[string]interface{}{"action": "subscribe", "topics": []string{"pr:2", "pr:4", "user:me"}}); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if msg := read(); msg["type"] != "subscribed" {
+		t.Fatalf("expected subscribed, got %v", msg)
+	}
+
+	_ = conn.WriteJSON(map[string]interface{}{"action": "subscribe", "topics": []string{"user:8"}})
+	if msg := read(); msg["type"] != "error" {
+		t.Fatalf("expected error for another user's topic, got %v", msg)
+	}
+
+	_ = conn.WriteJSON(map[string]interface{}{"action": "subscribe", "topics": []string{"team:2"}})
+	if msg := read(); msg["type"] != "error" {
+		t.Fatalf("expected error for another team's topic, got %v", msg)
+	}
+
+	broker.Publish(prEvent(1, 1, 10))             // не подписан
+	broker.Publish(prEvent(2, 1, 10))             // pr:2
+	broker.Publish(prEvent(4, 2, 10))             // pr:4 чужой команды
+	broker.Publish(prEvent(3, 2, 10, float64(7))) // user:me как рецензент в чужой команде
+
+	for _, want := range []float64{2, 3} {
+		msg := read()
+		if msg["type"] != "event" || msg["id"] != want || msg["event"] != "pr.created" {
+			t.Errorf("expected event %v, got %v", want, msg)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package tracing
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 
+	"github.com/user/pr-reviewer/internal/featureflags"
 	"github.com/user/pr-reviewer/internal/logger"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -30,6 +33,7 @@ type Tracer struct {
 	tracer trace.Tracer
 	logger *logger.Logger
 	config Config
+	flags  *featureflags.Manager
 }
 
 // Init инициализирует OpenTelemetry tracing
@@ -94,6 +98,12 @@ func Init(cfg Config, log *logger.Logger) (*Tracer, error) {
 	}, nil
 }
 
+// SetFeatureFlags подключает feature flags: при выключенном флаге
+// distributed_tracing middleware не создает spans
+func (t *Tracer) SetFeatureFlags(flags *featureflags.Manager) {
+	t.flags = flags
+}
+
 // Start начинает новый span
 func (t *Tracer) Start(ctx context.Context, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
 	return t.tracer.Start(ctx, spanName, opts...)
@@ -102,7 +112,7 @@ func (t *Tracer) Start(ctx context.Context, spanName string, opts ...trace.SpanS
 // Middleware HTTP middleware для трейсинга
 func (t *Tracer) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !t.config.Enabled {
+		if !t.config.Enabled || (t.flags != nil && !t.flags.IsEnabled(featureflags.FlagDistributedTracing)) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -229,6 +239,16 @@ func (w *statusWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap дает http.ResponseController доступ к Flush и дедлайнам
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack нужен для перехода соединения на WebSocket
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
 // Helper: извлечение trace ID для логирования
 func GetTraceID(ctx context.Context) string {
 	span := trace.SpanFromContext(ctx)
@@ -0,0 +1,420 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+// Target тип получателя подписки
+type Target string
+
+const (
+	// TargetWebhook произвольный HTTP endpoint (формат задается Format)
+	TargetWebhook Target = "webhook"
+	// TargetSlack Slack incoming webhook (Block Kit)
+	TargetSlack Target = "slack"
+	// TargetMattermost Mattermost incoming webhook (attachments)
+	TargetMattermost Target = "mattermost"
+)
+
+// IsChat проверяет, что получатель - чат
+func (t Target) IsChat() bool {
+	return t == TargetSlack || t == TargetMattermost
+}
+
+// ChatUser пользователь для отображения в чате
+type ChatUser struct {
+	ID         int
+	Name       string
+	ChatHandle string
+}
+
+// UserDirectory возвращает пользователей для отображения в чат-уведомлениях
+type UserDirectory interface {
+	Users(ctx context.Context, ids []int) (map[int]ChatUser, error)
+}
+
+// SQLUserDirectory читает пользователей из таблицы users
+type SQLUserDirectory struct {
+	db *sql.DB
+}
+
+// NewUserDirectory создает справочник пользователей
+func NewUserDirectory(db *sql.DB) *SQLUserDirectory {
+	return &SQLUserDirectory{db: db}
+}
+
+// Users возвращает пользователей по ID
+func (d *SQLUserDirectory) Users(ctx context.Context, ids []int) (map[int]ChatUser, error) {
+	users := make(map[int]ChatUser, len(ids))
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	rows, err := d.db.QueryContext(ctx,
+		`SELECT id, name, COALESCE(chat_handle, '') FROM users WHERE id = ANY($1)`,
+		pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u ChatUser
+		if err := rows.Scan(&u.ID, &u.Name, &u.ChatHandle); err != nil {
+			return nil, fmt.Errorf("failed to scan chat user: %w", err)
+		}
+		users[u.ID] = u
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate chat users: %w", err)
+	}
+
+	return users, nil
+}
+
+// ChatMessage данные события для шаблона сообщения. Title и имена
+// пользователей уже экранированы для разметки чата.
+type ChatMessage struct {
+	Event     EventType
+	PRID      int64
+	Title     string
+	Author    string
+	Reviewers []string
+	URL       string
+	Summary   string
+
+	// plainTitle неэкранированное название для полей без разметки
+	plainTitle string
+}
+
+// defaultChatTemplate шаблон сообщения по умолчанию
+const defaultChatTemplate = `{{.Summary}}`
+
+// ChatDeliverer доставляет события в Slack/Mattermost, формируя сообщение
+// для чата. Подписки с другим Target передаются следующему Deliverer.
+type ChatDeliverer struct {
+	next      Deliverer
+	client    *http.Client
+	users     UserDirectory
+	baseURL   string
+	templates sync.Map // шаблоны подписок: key -> *template.Template
+	logger    *logger.Logger
+}
+
+// NewChatDeliverer создает deliverer для чатов.
+// baseURL используется для ссылок на PR в веб-интерфейсе.
+func NewChatDeliverer(next Deliverer, users UserDirectory, baseURL string, log *logger.Logger) *ChatDeliverer {
+	return &ChatDeliverer{
+		next: next,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		users:   users,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		logger:  log,
+	}
+}
+
+// Deliver отправляет событие в чат или передает его следующему Deliverer
+func (d *ChatDeliverer) Deliver(ctx context.Context, sub *Subscription, payload *Payload) error {
+	if !sub.Target.IsChat() {
+		return d.next.Deliver(ctx, sub, payload)
+	}
+
+	body, err := d.Render(ctx, sub, payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", sub.URL, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		d.logger.Errorw("Failed to deliver chat notification",
+			"subscription_id", sub.ID,
+			"target", sub.Target,
+			"error", err,
+		)
+		return fmt.Errorf("failed to deliver chat notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		d.logger.Warnw("Chat notification failed with non-2xx status",
+			"subscription_id", sub.ID,
+			"target", sub.Target,
+			"status_code", resp.StatusCode,
+		)
+		return fmt.Errorf("chat webhook returned status %d", resp.StatusCode)
+	}
+
+	d.logger.Debugw("Chat notification delivered",
+		"subscription_id", sub.ID,
+		"target", sub.Target,
+		"event", payload.Event,
+	)
+
+	return nil
+}
+
+// Render формирует тело запроса к incoming webhook чата
+func (d *ChatDeliverer) Render(ctx context.Context, sub *Subscription, payload *Payload) ([]byte, error) {
+	msg, err := d.buildMessage(ctx, sub.Target, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := d.template(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, msg); err != nil {
+		return nil, fmt.Errorf("failed to render chat template: %w", err)
+	}
+
+	var body interface{}
+	if sub.Target == TargetSlack {
+		body = slackPayload(msg, text.String())
+	} else {
+		body = mattermostPayload(msg, text.String())
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal chat payload: %w", err)
+	}
+	return data, nil
+}
+
+// template возвращает шаблон подписки (или шаблон по умолчанию)
+func (d *ChatDeliverer) template(sub *Subscription) (*template.Template, error) {
+	source := sub.Template
+	if source == "" {
+		source = defaultChatTemplate
+	}
+
+	key := fmt.Sprintf("%d:%s", sub.ID, source)
+	if cached, ok := d.templates.Load(key); ok {
+		return cached.(*template.Template), nil
+	}
+
+	tmpl, err := template.New("chat").Option("missingkey=zero").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chat template for subscription %d: %w", sub.ID, err)
+	}
+	d.templates.Store(key, tmpl)
+	return tmpl, nil
+}
+
+// buildMessage собирает данные сообщения, подставляя упоминания пользователей
+func (d *ChatDeliverer) buildMessage(ctx context.Context, target Target, payload *Payload) (*ChatMessage, error) {
+	data := payload.Data
+	msg := &ChatMessage{Event: payload.Event}
+
+	if prID, ok := toInt64(data["pr_id"]); ok {
+		msg.PRID = prID
+		if d.baseURL != "" {
+			msg.URL = fmt.Sprintf("%s/pull-requests?id=%d", d.baseURL, prID)
+		}
+	}
+	msg.plainTitle, _ = data["title"].(string)
+	if msg.plainTitle == "" && msg.PRID > 0 {
+		msg.plainTitle = fmt.Sprintf("PR #%d", msg.PRID)
+	}
+	msg.Title = escapeChatText(target, msg.plainTitle)
+
+	authorIDs := idsFrom(data, FieldAuthorID)
+	reviewerIDs := idsFrom(data, FieldReviewerIDs)
+	subjectIDs := idsFrom(data, "reviewer_id", "new_reviewer_id", "user_id")
+
+	ids := make([]int, 0, len(authorIDs)+len(reviewerIDs)+len(subjectIDs))
+	for _, group := range [][]int64{authorIDs, reviewerIDs, subjectIDs} {
+		for _, id := range group {
+			ids = append(ids, int(id))
+		}
+	}
+
+	users := map[int]ChatUser{}
+	if d.users != nil && len(ids) > 0 {
+		found, err := d.users.Users(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		users = found
+	}
+
+	mention := func(id int64) string {
+		return chatMention(target, users[int(id)], id)
+	}
+
+	if len(authorIDs) > 0 {
+		msg.Author = mention(authorIDs[0])
+	}
+	for _, id := range reviewerIDs {
+		msg.Reviewers = append(msg.Reviewers, mention(id))
+	}
+
+	var subject string
+	if len(subjectIDs) > 0 {
+		subject = mention(subjectIDs[0])
+	}
+	msg.Summary = chatSummary(msg, subject)
+
+	return msg, nil
+}
+
+// chatSummary формирует текст сообщения по умолчанию
+func chatSummary(msg *ChatMessage, subject string) string {
+	title := msg.Title
+	if msg.URL != "" {
+		title = fmt.Sprintf("<%s|%s>", msg.URL, msg.Title)
+	}
+
+	switch msg.Event {
+	case EventPRCreated:
+		return fmt.Sprintf("New pull request %s by %s", title, msg.Author)
+	case EventPRMerged:
+		return fmt.Sprintf("Pull request %s by %s was merged", title, msg.Author)
+	case EventPRClosed:
+		return fmt.Sprintf("Pull request %s by %s was closed", title, msg.Author)
+	case EventReviewerAssigned:
+		return fmt.Sprintf("%s was assigned to review %s", subject, title)
+	case EventReviewerChanged:
+		return fmt.Sprintf("%s is now reviewing %s", subject, title)
+	case EventUserDeactivated:
+		return fmt.Sprintf("User %s was deactivated", subject)
+	}
+	return fmt.Sprintf("%s: %s", msg.Event, title)
+}
+
+// chatMention формирует упоминание пользователя в синтаксисе чата.
+// Для Slack chat_handle должен быть member ID (U0123ABC), для Mattermost - username.
+func chatMention(target Target, user ChatUser, id int64) string {
+	switch {
+	case user.ChatHandle != "" && target == TargetSlack:
+		return "<@" + slackEscaper.Replace(user.ChatHandle) + ">"
+	case user.ChatHandle != "":
+		return "@" + mattermostEscaper.Replace(strings.TrimPrefix(user.ChatHandle, "@"))
+	case user.Name != "":
+		return escapeChatText(target, user.Name)
+	}
+	return fmt.Sprintf("user #%d", id)
+}
+
+var (
+	// slackEscaper экранирование управляющих символов Slack mrkdwn
+	slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	// mattermostEscaper экранирование markdown ссылок Mattermost
+	mattermostEscaper = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`)
+	// mattermostMention упоминания Mattermost (@all, @channel, @username)
+	mattermostMention = regexp.MustCompile(`(^|\W)@+`)
+)
+
+// escapeChatText экранирует пользовательский текст (название PR, имена),
+// чтобы он не превращался в массовые упоминания и поддельные ссылки
+func escapeChatText(target Target, s string) string {
+	if target == TargetSlack {
+		return slackEscaper.Replace(s)
+	}
+	return mattermostEscaper.Replace(mattermostMention.ReplaceAllString(s, "$1"))
+}
+
+// slackPayload формирует сообщение Slack Block Kit
+func slackPayload(msg *ChatMessage, text string) map[string]interface{} {
+	blocks := []map[string]interface{}{
+		{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": text},
+		},
+	}
+
+	var elements []map[string]interface{}
+	if msg.Author != "" {
+		elements = append(elements, map[string]interface{}{"type": "mrkdwn", "text": "*Author:* " + msg.Author})
+	}
+	if len(msg.Reviewers) > 0 {
+		elements = append(elements, map[string]interface{}{"type": "mrkdwn", "text": "*Reviewers:* " + strings.Join(msg.Reviewers, ", ")})
+	}
+	if len(elements) > 0 {
+		blocks = append(blocks, map[string]interface{}{"type": "context", "elements": elements})
+	}
+
+	if msg.URL != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "actions",
+			"elements": []map[string]interface{}{{
+				"type": "button",
+				"text": map[string]interface{}{"type": "plain_text", "text": "Open pull request"},
+				"url":  msg.URL,
+			}},
+		})
+	}
+
+	return map[string]interface{}{
+		"text":   text,
+		"blocks": blocks,
+	}
+}
+
+// mattermostPayload формирует сообщение Mattermost с attachment
+func mattermostPayload(msg *ChatMessage, text string) map[string]interface{} {
+	// Mattermost использует markdown-ссылки вместо <url|text>
+	text = strings.ReplaceAll(text, fmt.Sprintf("<%s|%s>", msg.URL, msg.Title), fmt.Sprintf("[%s](%s)", msg.Title, msg.URL))
+
+	var fields []map[string]interface{}
+	if msg.Author != "" {
+		fields = append(fields, map[string]interface{}{"short": true, "title": "Author", "value": msg.Author})
+	}
+	if len(msg.Reviewers) > 0 {
+		fields = append(fields, map[string]interface{}{"short": true, "title": "Reviewers", "value": strings.Join(msg.Reviewers, ", ")})
+	}
+
+	attachment := map[string]interface{}{
+		"fallback": text,
+		"color":    chatColor(msg.Event),
+		"text":     text,
+		"fields":   fields,
+	}
+	if msg.plainTitle != "" {
+		// Заголовок attachment - простой текст без разметки и упоминаний
+		attachment["title"] = msg.plainTitle
+		if msg.URL != "" {
+			attachment["title_link"] = msg.URL
+		}
+	}
+
+	return map[string]interface{}{
+		"attachments": []map[string]interface{}{attachment},
+	}
+}
+
+// chatColor цвет полосы attachment по типу события
+func chatColor(event EventType) string {
+	switch event {
+	case EventPRMerged:
+		return "#6f42c1"
+	case EventPRClosed, EventUserDeactivated:
+		return "#d73a49"
+	}
+	return "#2da44e"
+}
@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+type stubDirectory map[int]ChatUser
+
+func (d stubDirectory) Users(_ context.Context, _ []int) (map[int]ChatUser, error) {
+	return d, nil
+}
+
+type stubDeliverer struct {
+	called bool
+}
+
+func (d *stubDeliverer) Deliver(_ context.Context, _ *Subscription, _ *Payload) error {
+	d.called = true
+	return nil
+}
+
+func newTestChatDeliverer(t *testing.T, next Deliverer) *ChatDeliverer {
+	t.Helper()
+	log, err := logger.New("error", "test")
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	users := stubDirectory{
+		1: {ID: 1, Name: "Alice", ChatHandle: "U01ALICE"},
+		2: {ID: 2, Name: "Bob"},
+	}
+	return NewChatDeliverer(next, users, "https://reviewer.example.com/", log)
+}
+
+func prCreatedPayload() *Payload {
+	return &Payload{
+		Event: EventPRCreated,
+		Data: map[string]interface{}{
+			"pr_id":        12,
+			"title":        "Add caching",
+			"author_id":    1,
+			"reviewer_ids": []int{2},
+		},
+	}
+}
+
+func TestChatDeliverer_Slack(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	d := newTestChatDeliverer(t, &stubDeliverer{})
+	sub := &Subscription{ID: 1, URL: server.URL, Target: TargetSlack}
+
+	if err := d.Deliver(context.Background(), sub, prCreatedPayload()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	text, _ := received["text"].(string)
+	if !strings.Contains(text, "<@U01ALICE>") {
+		t.Errorf("expected Slack mention of author, got %q", text)
+	}
+	if !strings.Contains(text, "<https://reviewer.example.com/pull-requests?id=12|Add caching>") {
+		t.Errorf("expected deep link, got %q", text)
+	}
+	if _, ok := received["blocks"].([]interface{}); !ok {
+		t.Errorf("expected Block Kit blocks in payload")
+	}
+}
+
+func TestChatDeliverer_MattermostTemplate(t *testing.T) {
+	d := newTestChatDeliverer(t, &stubDeliverer{})
+	sub := &Subscription{
+		ID:       2,
+		Target:   TargetMattermost,
+		Template: `{{.Title}} needs review from {{range .Reviewers}}{{.}} {{end}}`,
+	}
+
+	body, err := d.Render(context.Background(), sub, prCreatedPayload())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var payload struct {
+		Attachments []struct {
+			Text      string `json:"text"`
+			TitleLink string `json:"title_link"`
+		} `json:"attachments"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if len(payload.Attachments) != 1 {
+		t.Fatalf("expected one attachment, got %d", len(payload.Attachments))
+	}
+	if payload.Attachments[0].Text != "Add caching needs review from Bob " {
+		t.Errorf("unexpected text: %q", payload.Attachments[0].Text)
+	}
+	if payload.Attachments[0].TitleLink != "https://reviewer.example.com/pull-requests?id=12" {
+		t.Errorf("unexpected link: %q", payload.Attachments[0].TitleLink)
+	}
+}
+
+func TestChatDeliverer_PassesThroughWebhookTarget(t *testing.T) {
+	next := &stubDeliverer{}
+	d := newTestChatDeliverer(t, next)
+
+	if err := d.Deliver(context.Background(), &Subscription{ID: 3}, prCreatedPayload()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !next.called {
+		t.Error("expected delivery to be passed to next deliverer")
+	}
+}
+
+func TestChatDeliverer_EscapesUserText(t *testing.T) {
+	log, err := logger.New("error", "test")
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	users := stubDirectory{1: {ID: 1, Name: "<!here> @all"}}
+	d := NewChatDeliverer(&stubDeliverer{}, users, "https://reviewer.example.com", log)
+
+	payload := prCreatedPayload()
+	payload.Data["title"] = "<!channel> @all <http://evil|click> [click](http://evil)"
+	payload.Data["reviewer_ids"] = []int{}
+
+	body, err := d.Render(context.Background(), &Subscription{ID: 1, Target: TargetSlack}, payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var slack struct {
+		Text string `json:"text"`
+	}
+	_ = json.Unmarshal(body, &slack)
+	for _, forbidden := range []string{"<!channel>", "<!here>", "<http://evil|click>"} {
+		if strings.Contains(slack.Text, forbidden) {
+			t.Errorf("expected %q to be escaped in Slack text %q", forbidden, slack.Text)
+		}
+	}
+	if !strings.Contains(slack.Text, "&lt;!channel&gt;") {
+		t.Errorf("expected escaped title in Slack text, got %q", slack.Text)
+	}
+
+	body, err = d.Render(context.Background(), &Subscription{ID: 2, Target: TargetMattermost}, payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var mattermost struct {
+		Attachments []struct {
+			Text string `json:"text"`
+		} `json:"attachments"`
+	}
+	_ = json.Unmarshal(body, &mattermost)
+	text := mattermost.Attachments[0].Text
+	for _, forbidden := range []string{"@all", "[click](http://evil)"} {
+		if strings.Contains(text, forbidden) {
+			t.Errorf("expected %q to be neutralized in Mattermost text %q", forbidden, text)
+		}
+	}
+}
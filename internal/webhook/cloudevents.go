@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Format формат тела webhook запроса
+type Format string
+
+const (
+	// FormatPayload исходный формат webhook.Payload
+	FormatPayload Format = "payload"
+	// FormatCloudEvents CloudEvents 1.0, structured mode (событие целиком в теле)
+	FormatCloudEvents Format = "cloudevents"
+	// FormatCloudEventsBinary CloudEvents 1.0, binary mode (атрибуты в ce-* заголовках)
+	FormatCloudEventsBinary Format = "cloudevents-binary"
+)
+
+const (
+	// CloudEventsSpecVersion поддерживаемая версия спецификации
+	CloudEventsSpecVersion = "1.0"
+
+	// CloudEventsTypePrefix префикс типа события: com.prreviewer.pr.created
+	CloudEventsTypePrefix = "com.prreviewer."
+
+	// DefaultEventSource source по умолчанию
+	DefaultEventSource = "/pr-reviewer"
+
+	contentTypeJSON        = "application/json"
+	contentTypeCloudEvents = "application/cloudevents+json"
+)
+
+// ErrInvalidFormat формат подписки не поддерживается
+var ErrInvalidFormat = errors.New("unsupported webhook format")
+
+// Valid проверяет, что формат поддерживается (пустой означает FormatPayload)
+func (f Format) Valid() bool {
+	switch f {
+	case "", FormatPayload, FormatCloudEvents, FormatCloudEventsBinary:
+		return true
+	}
+	return false
+}
+
+// CloudEvent событие в формате CloudEvents 1.0 (JSON event format)
+type CloudEvent struct {
+	SpecVersion     string                 `json:"specversion"`
+	ID              string                 `json:"id"`
+	Source          string                 `json:"source"`
+	Type            string                 `json:"type"`
+	Subject         string                 `json:"subject,omitempty"`
+	Time            time.Time              `json:"time"`
+	DataContentType string                 `json:"datacontenttype"`
+	Data            map[string]interface{} `json:"data"`
+}
+
+// NewCloudEvent преобразует payload в CloudEvent.
+// ID и source стабильны между повторными доставками, поэтому пара (source, id)
+// подходит для дедупликации на стороне получателя.
+func NewCloudEvent(payload *Payload, source string) *CloudEvent {
+	return &CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              payload.ID,
+		Source:          source,
+		Type:            CloudEventsTypePrefix + string(payload.Event),
+		Subject:         payload.Subject,
+		Time:            payload.Timestamp.UTC(),
+		DataContentType: contentTypeJSON,
+		Data:            payload.Data,
+	}
+}
+
+// encodePayload формирует тело и заголовки запроса для формата подписки
+func encodePayload(format Format, payload *Payload, source string) ([]byte, map[string]string, error) {
+	switch format {
+	case FormatCloudEvents:
+		body, err := json.Marshal(NewCloudEvent(payload, source))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal cloud event: %w", err)
+		}
+		return body, map[string]string{"Content-Type": contentTypeCloudEvents}, nil
+
+	case FormatCloudEventsBinary:
+		ce := NewCloudEvent(payload, source)
+		body, err := json.Marshal(ce.Data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal cloud event data: %w", err)
+		}
+		headers := map[string]string{
+			"Content-Type":   ce.DataContentType,
+			"ce-specversion": ce.SpecVersion,
+			"ce-id":          ce.ID,
+			"ce-source":      ce.Source,
+			"ce-type":        ce.Type,
+			"ce-time":        ce.Time.Format(time.RFC3339Nano),
+		}
+		if ce.Subject != "" {
+			headers["ce-subject"] = ce.Subject
+		}
+		return body, headers, nil
+
+	case "", FormatPayload:
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		return body, map[string]string{"Content-Type": contentTypeJSON}, nil
+
+	default:
+		return nil, nil, fmt.Errorf("%w: %q", ErrInvalidFormat, format)
+	}
+}
+
+// newEventID генерирует ID для событий вне outbox
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testPayload() *Payload {
+	return &Payload{
+		ID:        "42",
+		Event:     EventPRCreated,
+		Subject:   "pull-requests/7",
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Data:      map[string]interface{}{"pr_id": float64(7)},
+	}
+}
+
+func TestEncodePayload_Structured(t *testing.T) {
+	body, headers, err := encodePayload(FormatCloudEvents, testPayload(), "/pr-reviewer")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if headers["Content-Type"] != "application/cloudevents+json" {
+		t.Errorf("unexpected content type: %s", headers["Content-Type"])
+	}
+
+	var ce CloudEvent
+	if err := json.Unmarshal(body, &ce); err != nil {
+		t.Fatalf("failed to decode cloud event: %v", err)
+	}
+
+	if ce.SpecVersion != "1.0" || ce.ID != "42" || ce.Source != "/pr-reviewer" {
+		t.Errorf("unexpected attributes: %+v", ce)
+	}
+	if ce.Type != "com.prreviewer.pr.created" {
+		t.Errorf("expected type com.prreviewer.pr.created, got %s", ce.Type)
+	}
+	if ce.Subject != "pull-requests/7" {
+		t.Errorf("unexpected subject: %s", ce.Subject)
+	}
+}
+
+func TestEncodePayload_Binary(t *testing.T) {
+	body, headers, err := encodePayload(FormatCloudEventsBinary, testPayload(), "/pr-reviewer")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"Content-Type":   "application/json",
+		"ce-specversion": "1.0",
+		"ce-id":          "42",
+		"ce-source":      "/pr-reviewer",
+		"ce-type":        "com.prreviewer.pr.created",
+		"ce-subject":     "pull-requests/7",
+	}
+	for key, value := range want {
+		if headers[key] != value {
+			t.Errorf("header %s: expected %q, got %q", key, value, headers[key])
+		}
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		t.Fatalf("failed to decode data: %v", err)
+	}
+	if data["pr_id"] != float64(7) {
+		t.Errorf("expected body to contain event data only, got %v", data)
+	}
+}
+
+func TestEncodePayload_UnknownFormat(t *testing.T) {
+	// Опечатка в формате не должна молча превращаться в обычный payload
+	if _, _, err := encodePayload("cloudevent", testPayload(), "/pr-reviewer"); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("expected ErrInvalidFormat, got %v", err)
+	}
+}
+
+func TestOutboxEvent_PayloadStableID(t *testing.T) {
+	event := &OutboxEvent{ID: 15, Event: EventPRMerged, Data: map[string]interface{}{"pr_id": 3}}
+
+	first, second := event.Payload(), event.Payload()
+	if first.ID != "15" || first.ID != second.ID {
+		t.Errorf("expected stable ID 15, got %s and %s", first.ID, second.ID)
+	}
+	if first.Subject != "pull-requests/3" {
+		t.Errorf("unexpected subject: %s", first.Subject)
+	}
+}
@@ -0,0 +1,204 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+var (
+	ErrDeadLetterNotFound   = errors.New("dead letter not found")
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+)
+
+// DeadLetter доставка, исчерпавшая все попытки
+type DeadLetter struct {
+	ID             int64      `json:"id"`
+	SubscriptionID int64      `json:"subscription_id"`
+	OutboxID       *int64     `json:"outbox_id,omitempty"`
+	Event          EventType  `json:"event"`
+	Payload        *Payload   `json:"payload"`
+	Error          string     `json:"error"`
+	Attempts       int        `json:"attempts"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReplayedAt     *time.Time `json:"replayed_at,omitempty"`
+}
+
+// DeadLetterFilter фильтр для выборки dead letters
+type DeadLetterFilter struct {
+	SubscriptionID  int64
+	AfterID         int64
+	From            time.Time
+	To              time.Time
+	IncludeReplayed bool
+	Limit           int
+	Offset          int
+}
+
+// ReplayResult результат массового replay
+type ReplayResult struct {
+	Replayed int      `json:"replayed"`
+	Failed   int      `json:"failed"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// DeadLetterStore хранит dead letters в таблице webhook_dead_letters
+type DeadLetterStore struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewDeadLetterStore создает новое хранилище dead letters
+func NewDeadLetterStore(db *sql.DB, log *logger.Logger) *DeadLetterStore {
+	return &DeadLetterStore{
+		db:     db,
+		logger: log,
+	}
+}
+
+// Save сохраняет dead letter
+func (s *DeadLetterStore) Save(ctx context.Context, dl *DeadLetter) error {
+	payload, err := json.Marshal(dl.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter payload: %w", err)
+	}
+
+	query := `
+		INSERT INTO webhook_dead_letters (subscription_id, outbox_id, event, payload, error, attempts)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	err = s.db.QueryRowContext(ctx, query,
+		dl.SubscriptionID, dl.OutboxID, dl.Event, payload, dl.Error, dl.Attempts,
+	).Scan(&dl.ID, &dl.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save dead letter: %w", err)
+	}
+
+	s.logger.Warnw("Webhook delivery moved to dead letters",
+		"dead_letter_id", dl.ID,
+		"subscription_id", dl.SubscriptionID,
+		"event", dl.Event,
+		"error", dl.Error,
+	)
+
+	return nil
+}
+
+// Get возвращает dead letter по ID
+func (s *DeadLetterStore) Get(ctx context.Context, id int64) (*DeadLetter, error) {
+	query := `
+		SELECT id, subscription_id, outbox_id, event, payload, error, attempts, created_at, replayed_at
+		FROM webhook_dead_letters
+		WHERE id = $1`
+
+	dl, err := scanDeadLetter(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDeadLetterNotFound
+		}
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	return dl, nil
+}
+
+// List возвращает dead letters по фильтру
+func (s *DeadLetterStore) List(ctx context.Context, filter DeadLetterFilter) ([]*DeadLetter, error) {
+	query := `
+		SELECT id, subscription_id, outbox_id, event, payload, error, attempts, created_at, replayed_at
+		FROM webhook_dead_letters
+		WHERE 1=1`
+	args := []interface{}{}
+	argNum := 1
+
+	if filter.SubscriptionID > 0 {
+		query += fmt.Sprintf(" AND subscription_id = $%d", argNum)
+		args = append(args, filter.SubscriptionID)
+		argNum++
+	}
+
+	if filter.AfterID > 0 {
+		query += fmt.Sprintf(" AND id > $%d", argNum)
+		args = append(args, filter.AfterID)
+		argNum++
+	}
+
+	if !filter.From.IsZero() {
+		query += fmt.Sprintf(" AND created_at >= $%d", argNum)
+		args = append(args, filter.From)
+		argNum++
+	}
+
+	if !filter.To.IsZero() {
+		query += fmt.Sprintf(" AND created_at <= $%d", argNum)
+		args = append(args, filter.To)
+		argNum++
+	}
+
+	if !filter.IncludeReplayed {
+		query += " AND replayed_at IS NULL"
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	query += fmt.Sprintf(" ORDER BY id LIMIT $%d OFFSET $%d", argNum, argNum+1)
+	args = append(args, limit, filter.Offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	letters := []*DeadLetter{}
+	for rows.Next() {
+		dl, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+		}
+		letters = append(letters, dl)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate dead letters: %w", err)
+	}
+
+	return letters, nil
+}
+
+// MarkReplayed отмечает dead letter как успешно переотправленный
+func (s *DeadLetterStore) MarkReplayed(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE webhook_dead_letters SET replayed_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark dead letter replayed: %w", err)
+	}
+	return nil
+}
+
+// rowScanner общий интерфейс для *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDeadLetter(row rowScanner) (*DeadLetter, error) {
+	dl := &DeadLetter{}
+	var payload []byte
+	if err := row.Scan(&dl.ID, &dl.SubscriptionID, &dl.OutboxID, &dl.Event, &payload,
+		&dl.Error, &dl.Attempts, &dl.CreatedAt, &dl.ReplayedAt); err != nil {
+		return nil, err
+	}
+
+	dl.Payload = &Payload{}
+	if err := json.Unmarshal(payload, dl.Payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dead letter payload: %w", err)
+	}
+	return dl, nil
+}
@@ -0,0 +1,204 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Поля данных события, по которым работают фильтры подписок
+const (
+	FieldTeamID      = "team_id"
+	FieldAuthorID    = "author_id"
+	FieldReviewerIDs = "reviewer_ids"
+	FieldLabels      = "labels"
+	FieldRepository  = "repository"
+)
+
+// ErrInvalidFilter фильтр с условием, которое не может выполниться
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// reviewerFields поля с ID рецензентов, участвующих в событии
+var reviewerFields = []string{FieldReviewerIDs, "reviewer_id", "new_reviewer_id", "old_reviewer_id"}
+
+// Filter дополнительный фильтр подписки.
+// Внутри одного условия значения объединяются через ИЛИ, условия между собой — через И.
+// Если поле отсутствует в данных события, условие по нему не выполняется.
+type Filter struct {
+	TeamIDs     []int64  `json:"team_ids,omitempty"`
+	AuthorIDs   []int64  `json:"author_ids,omitempty"`
+	ReviewerIDs []int64  `json:"reviewer_ids,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	Repository  string   `json:"repository,omitempty"`
+}
+
+// Validate проверяет, что каждое условие фильтра может выполниться
+func (f *Filter) Validate() error {
+	if f == nil {
+		return nil
+	}
+	for _, label := range f.Labels {
+		if strings.TrimSpace(label) == "" {
+			return fmt.Errorf("%w: labels must not be empty", ErrInvalidFilter)
+		}
+	}
+	if f.Repository != "" && strings.TrimSpace(f.Repository) != f.Repository {
+		return fmt.Errorf("%w: repository must not have surrounding spaces", ErrInvalidFilter)
+	}
+	return nil
+}
+
+// IsEmpty проверяет, что фильтр не содержит условий
+func (f *Filter) IsEmpty() bool {
+	return f == nil || (len(f.TeamIDs) == 0 && len(f.AuthorIDs) == 0 &&
+		len(f.ReviewerIDs) == 0 && len(f.Labels) == 0 && f.Repository == "")
+}
+
+// Matches проверяет, проходит ли событие фильтр
+func (f *Filter) Matches(data map[string]interface{}) bool {
+	return len(f.Mismatches(data)) == 0
+}
+
+// Mismatches возвращает условия фильтра, которым не соответствует событие
+func (f *Filter) Mismatches(data map[string]interface{}) []string {
+	if f.IsEmpty() {
+		return nil
+	}
+
+	var failed []string
+	if len(f.TeamIDs) > 0 && !intersectsIDs(f.TeamIDs, idsFrom(data, FieldTeamID)) {
+		failed = append(failed, "team_ids")
+	}
+	if len(f.AuthorIDs) > 0 && !intersectsIDs(f.AuthorIDs, idsFrom(data, FieldAuthorID)) {
+		failed = append(failed, "author_ids")
+	}
+	if len(f.ReviewerIDs) > 0 && !intersectsIDs(f.ReviewerIDs, idsFrom(data, reviewerFields...)) {
+		failed = append(failed, "reviewer_ids")
+	}
+	if len(f.Labels) > 0 && !intersectsStrings(f.Labels, stringsFrom(data[FieldLabels])) {
+		failed = append(failed, "labels")
+	}
+	if f.Repository != "" {
+		if repo, _ := data[FieldRepository].(string); repo != f.Repository {
+			failed = append(failed, "repository")
+		}
+	}
+	return failed
+}
+
+// PRContext контекст PR, по которому работают фильтры подписок
+type PRContext struct {
+	AuthorID    int
+	TeamID      *int
+	ReviewerIDs []int
+	Repository  string
+	Labels      []string
+}
+
+// WithPRContext добавляет в данные события PR контекст для фильтров:
+// автора, команду автора, текущих рецензентов, репозиторий и метки
+func WithPRContext(data map[string]interface{}, pr PRContext) map[string]interface{} {
+	data[FieldAuthorID] = pr.AuthorID
+	if pr.TeamID != nil {
+		data[FieldTeamID] = *pr.TeamID
+	}
+	data[FieldReviewerIDs] = pr.ReviewerIDs
+	if pr.Repository != "" {
+		data[FieldRepository] = pr.Repository
+	}
+	labels := pr.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	data[FieldLabels] = labels
+	return data
+}
+
+// idsFrom собирает числовые ID из указанных полей (скаляры и массивы)
+func idsFrom(data map[string]interface{}, fields ...string) []int64 {
+	var ids []int64
+	for _, field := range fields {
+		switch v := data[field].(type) {
+		case []int:
+			for _, id := range v {
+				ids = append(ids, int64(id))
+			}
+		case []int64:
+			ids = append(ids, v...)
+		case []interface{}:
+			for _, item := range v {
+				if id, ok := toInt64(item); ok {
+					ids = append(ids, id)
+				}
+			}
+		default:
+			if id, ok := toInt64(v); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// toInt64 приводит число к int64. Данные из outbox приходят
+// после JSON десериализации как float64.
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	case json.Number:
+		id, err := n.Int64()
+		return id, err == nil
+	case string:
+		id, err := strconv.ParseInt(n, 10, 64)
+		return id, err == nil
+	}
+	return 0, false
+}
+
+// stringsFrom приводит значение к списку строк
+func stringsFrom(v interface{}) []string {
+	switch s := v.(type) {
+	case []string:
+		return s
+	case []interface{}:
+		result := make([]string, 0, len(s))
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	case string:
+		return []string{s}
+	}
+	return nil
+}
+
+func intersectsIDs(want, have []int64) bool {
+	for _, w := range want {
+		for _, h := range have {
+			if w == h {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func intersectsStrings(want, have []string) bool {
+	for _, w := range want {
+		for _, h := range have {
+			if w == h {
+				return true
+			}
+		}
+	}
+	return false
+}
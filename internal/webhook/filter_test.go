@@ -0,0 +1,80 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestFilter_Matches(t *testing.T) {
+	team := 3
+	data := WithPRContext(map[string]interface{}{"pr_id": 10}, PRContext{
+		AuthorID:    5,
+		TeamID:      &team,
+		ReviewerIDs: []int{7, 8},
+		Repository:  "org/api",
+		Labels:      []string{"backend"},
+	})
+
+	tests := []struct {
+		name   string
+		filter *Filter
+		want   bool
+	}{
+		{"nil filter", nil, true},
+		{"empty filter", &Filter{}, true},
+		{"team match", &Filter{TeamIDs: []int64{1, 3}}, true},
+		{"team mismatch", &Filter{TeamIDs: []int64{4}}, false},
+		{"author match", &Filter{AuthorIDs: []int64{5}}, true},
+		{"reviewer match", &Filter{ReviewerIDs: []int64{8}}, true},
+		{"team and author must both match", &Filter{TeamIDs: []int64{3}, AuthorIDs: []int64{6}}, false},
+		{"label match", &Filter{Labels: []string{"frontend", "backend"}}, true},
+		{"label mismatch", &Filter{Labels: []string{"urgent"}}, false},
+		{"repository match", &Filter{Repository: "org/api"}, true},
+		{"repository mismatch", &Filter{Repository: "org/web"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(data); got != tt.want {
+				t.Errorf("expected %v, got %v (mismatches: %v)", tt.want, got, tt.filter.Mismatches(data))
+			}
+		})
+	}
+}
+
+func TestFilter_MatchesDecodedJSON(t *testing.T) {
+	// Данные из outbox приходят после JSON round-trip: числа становятся float64
+	raw := `{"pr_id": 1, "team_id": 2, "reviewer_id": 9, "labels": ["backend", "urgent"], "repository": "org/api"}`
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+
+	filter := &Filter{
+		TeamIDs:     []int64{2},
+		ReviewerIDs: []int64{9},
+		Labels:      []string{"urgent"},
+		Repository:  "org/api",
+	}
+	if !filter.Matches(data) {
+		t.Errorf("expected match, mismatches: %v", filter.Mismatches(data))
+	}
+}
+
+func TestFilter_Validate(t *testing.T) {
+	valid := []*Filter{nil, {}, {TeamIDs: []int64{1}, Labels: []string{"backend"}, Repository: "org/api"}}
+	for _, f := range valid {
+		if err := f.Validate(); err != nil {
+			t.Errorf("expected %+v to be valid, got %v", f, err)
+		}
+	}
+
+	// Такие условия не совпали бы ни с одним событием
+	invalid := []*Filter{{Labels: []string{""}}, {TeamIDs: []int64{1}, Repository: " org/api"}}
+	for _, f := range invalid {
+		if err := f.Validate(); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("expected ErrInvalidFilter for %+v, got %v", f, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+// Listener перечитывает подписки при их изменении на любой реплике
+type Listener struct {
+	dsn     string
+	manager *Manager
+	logger  *logger.Logger
+}
+
+// NewListener создает listener изменений подписок
+func NewListener(dsn string, manager *Manager, log *logger.Logger) *Listener {
+	return &Listener{
+		dsn:     dsn,
+		manager: manager,
+		logger:  log,
+	}
+}
+
+// Run слушает канал до отмены контекста
+func (l *Listener) Run(ctx context.Context) error {
+	listener := pq.NewListener(l.dsn, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			l.logger.Warnw("Webhook subscriptions listener connection event", "event", ev, "error", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(SubscriptionsChannel); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", SubscriptionsChannel, err)
+	}
+	l.logger.Infow("Webhook subscriptions listener started", "channel", SubscriptionsChannel)
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			// n == nil - соединение восстановлено и уведомления могли потеряться:
+			// в обоих случаях подписки перечитываются целиком
+			if n != nil {
+				l.logger.Debugw("Webhook subscription changed", "subscription_id", n.Extra)
+			}
+			if err := l.manager.Reload(ctx); err != nil {
+				l.logger.Errorw("Failed to reload webhook subscriptions", "error", err)
+			}
+		case <-ping.C:
+			go func() {
+				_ = listener.Ping()
+			}()
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/logger"
+	"github.com/user/pr-reviewer/internal/models"
+)
+
+// Статусы событий в outbox
+const (
+	OutboxStatusPending   = "pending"
+	OutboxStatusProcessed = "processed"
+	OutboxStatusFailed    = "failed"
+)
+
+// OutboxEvent событие, сохраненное в transactional outbox
+type OutboxEvent struct {
+	ID           int64                  `json:"id"`
+	AggregateKey string                 `json:"aggregate_key"`
+	Event        EventType              `json:"event"`
+	Data         map[string]interface{} `json:"data"`
+	Attempts     int                    `json:"attempts"`
+	DeliveredTo  []int64                `json:"delivered_to,omitempty"`
+	CreatedAt    time.Time              `json:"created_at"`
+}
+
+// Payload возвращает webhook payload для события
+func (e *OutboxEvent) Payload() *Payload {
+	return &Payload{
+		ID:        strconv.FormatInt(e.ID, 10),
+		Event:     e.Event,
+		Subject:   subjectFor(e.Data),
+		Timestamp: e.CreatedAt,
+		Data:      e.Data,
+	}
+}
+
+// IsDeliveredTo проверяет, было ли событие уже доставлено подписке
+func (e *OutboxEvent) IsDeliveredTo(subscriptionID int64) bool {
+	for _, id := range e.DeliveredTo {
+		if id == subscriptionID {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkDelivered отмечает успешную доставку подписке
+func (e *OutboxEvent) MarkDelivered(subscriptionID int64) {
+	if !e.IsDeliveredTo(subscriptionID) {
+		e.DeliveredTo = append(e.DeliveredTo, subscriptionID)
+	}
+}
+
+// execer общий интерфейс для *sql.DB и *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Outbox сохраняет события в таблицу webhook_outbox
+type Outbox struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewOutbox создает новый outbox
+func NewOutbox(db *sql.DB, log *logger.Logger) *Outbox {
+	return &Outbox{
+		db:     db,
+		logger: log,
+	}
+}
+
+// Enqueue сохраняет событие в outbox.
+// Если передана транзакция, событие будет записано в ней и станет видимым
+// только вместе с изменением данных.
+// Advisory lock по aggregate_key удерживается до конца транзакции: события
+// одного ключа получают ID в порядке фиксации транзакций, и событие N+1 не
+// может стать видимым раньше события N.
+func (o *Outbox) Enqueue(ctx context.Context, tx *sql.Tx, event EventType, aggregateKey string, data map[string]interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox payload: %w", err)
+	}
+
+	var exec execer = o.db
+	if tx != nil {
+		exec = tx
+	}
+
+	query := `
+		WITH ordered AS (
+		    SELECT pg_advisory_xact_lock(hashtextextended($1, 0))
+		)
+		INSERT INTO webhook_outbox (aggregate_key, event, payload)
+		SELECT $1, $2, $3 FROM ordered`
+
+	if _, err := exec.ExecContext(ctx, query, aggregateKey, event, payload); err != nil {
+		return fmt.Errorf("failed to enqueue outbox event: %w", err)
+	}
+
+	o.logger.Debugw("Outbox event enqueued",
+		"event", event,
+		"aggregate_key", aggregateKey,
+	)
+
+	return nil
+}
+
+// Ключи упорядочивания событий
+
+// PRAggregateKey возвращает ключ упорядочивания для событий PR
+func PRAggregateKey(prID int) string {
+	return fmt.Sprintf("pr:%d", prID)
+}
+
+// UserAggregateKey возвращает ключ упорядочивания для событий пользователя
+func UserAggregateKey(userID int) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// aggregateKeyFor определяет ключ упорядочивания по данным события
+func aggregateKeyFor(event EventType, data map[string]interface{}) string {
+	if prID, ok := data["pr_id"]; ok {
+		return fmt.Sprintf("pr:%v", prID)
+	}
+	if userID, ok := data["user_id"]; ok {
+		return fmt.Sprintf("user:%v", userID)
+	}
+	return string(event)
+}
+
+// subjectFor определяет субъект события (ресурс, к которому оно относится)
+func subjectFor(data map[string]interface{}) string {
+	if prID, ok := data["pr_id"]; ok {
+		return fmt.Sprintf("pull-requests/%v", prID)
+	}
+	if userID, ok := data["user_id"]; ok {
+		return fmt.Sprintf("users/%v", userID)
+	}
+	return ""
+}
+
+// Данные событий
+
+// PRCreatedData формирует данные события создания PR
+func PRCreatedData(pr *models.PullRequest) map[string]interface{} {
+	data := map[string]interface{}{
+		"pr_id":      pr.ID,
+		"title":      pr.Title,
+		"author_id":  pr.AuthorID,
+		"status":     pr.Status,
+		"reviewers":  pr.Reviewers,
+		"created_at": pr.CreatedAt,
+	}
+
+	// Add team_id if team is available
+	if pr.Team != nil {
+		data["team_id"] = pr.Team.ID
+	}
+
+	return data
+}
+
+// PRMergedData формирует данные события слияния PR
+func PRMergedData(pr *models.PullRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"pr_id":     pr.ID,
+		"title":     pr.Title,
+		"author_id": pr.AuthorID,
+		"merged_at": pr.MergedAt,
+	}
+}
+
+// PRClosedData формирует данные события закрытия PR
+func PRClosedData(pr *models.PullRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"pr_id":     pr.ID,
+		"title":     pr.Title,
+		"author_id": pr.AuthorID,
+		"closed_at": pr.UpdatedAt,
+	}
+}
+
+// ReviewerAssignedData формирует данные события назначения рецензента
+func ReviewerAssignedData(prID, reviewerID int) map[string]interface{} {
+	return map[string]interface{}{
+		"pr_id":       prID,
+		"reviewer_id": reviewerID,
+		"assigned_at": time.Now(),
+	}
+}
+
+// ReviewerChangedData формирует данные события замены рецензента
+func ReviewerChangedData(prID, oldReviewerID, newReviewerID int) map[string]interface{} {
+	return map[string]interface{}{
+		"pr_id":           prID,
+		"old_reviewer_id": oldReviewerID,
+		"new_reviewer_id": newReviewerID,
+		"changed_at":      time.Now(),
+	}
+}
+
+// UserDeactivatedData формирует данные события деактивации пользователя
+func UserDeactivatedData(userID, teamID int) map[string]interface{} {
+	return map[string]interface{}{
+		"user_id":        userID,
+		"team_id":        teamID,
+		"deactivated_at": time.Now(),
+	}
+}
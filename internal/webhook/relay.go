@@ -0,0 +1,476 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+// EventHandler обрабатывает события из outbox.
+// Обработчик может отметить частичный успех через OutboxEvent.MarkDelivered —
+// при повторной попытке уже доставленные подписки будут пропущены.
+type EventHandler interface {
+	HandleEvent(ctx context.Context, event *OutboxEvent) error
+}
+
+// ErrDeliveryDeferred возвращается обработчиком, который временно не
+// принимает события (например, выключен флагом). Доставка остается в очереди
+// и повторяется через DeferDelay без учета попыток.
+var ErrDeliveryDeferred = errors.New("delivery deferred")
+
+// ExhaustedHandler опционально реализуется обработчиком, чтобы получить
+// событие, исчерпавшее все попытки (например, для сохранения в dead letters)
+type ExhaustedHandler interface {
+	HandleExhausted(ctx context.Context, event *OutboxEvent, lastErr error)
+}
+
+// EventHandlerFunc адаптер функции к EventHandler
+type EventHandlerFunc func(ctx context.Context, event *OutboxEvent) error
+
+// HandleEvent вызывает функцию-обработчик
+func (f EventHandlerFunc) HandleEvent(ctx context.Context, event *OutboxEvent) error {
+	return f(ctx, event)
+}
+
+// RelayConfig конфигурация relay воркера
+type RelayConfig struct {
+	PollInterval   time.Duration
+	BatchSize      int
+	MaxAttempts    int
+	BaseBackoff    time.Duration
+	MaxBackoff     time.Duration
+	HandlerTimeout time.Duration
+	// LeaseDuration время аренды доставки; должно быть больше HandlerTimeout,
+	// иначе доставку может захватить другая реплика
+	LeaseDuration time.Duration
+	// DeferDelay задержка повтора доставки, отложенной обработчиком
+	DeferDelay time.Duration
+}
+
+// DefaultRelayConfig возвращает конфигурацию по умолчанию
+func DefaultRelayConfig() RelayConfig {
+	return RelayConfig{
+		PollInterval:   time.Second,
+		BatchSize:      50,
+		MaxAttempts:    8,
+		BaseBackoff:    2 * time.Second,
+		MaxBackoff:     10 * time.Minute,
+		HandlerTimeout: 30 * time.Second,
+		LeaseDuration:  time.Minute,
+		DeferDelay:     30 * time.Second,
+	}
+}
+
+// namedHandler обработчик с именем, под которым хранятся его доставки
+type namedHandler struct {
+	name    string
+	handler EventHandler
+}
+
+// delivery доставка события одному обработчику
+type delivery struct {
+	handler string
+	event   *OutboxEvent
+}
+
+// Relay вычитывает события из outbox и передает их обработчикам.
+// Гарантии: at-least-once доставка, порядок внутри одного aggregate_key
+// (например, одного PR) для каждого обработчика, ограниченное количество
+// повторов. Результат каждого обработчика хранится отдельно: ошибка одного
+// не повторяет и не задерживает доставку остальным.
+// Доставки захватываются арендой и выполняются вне транзакции, поэтому
+// несколько реплик могут работать одновременно, а доставку упавшей реплики
+// подхватит другая после истечения аренды.
+type Relay struct {
+	db         *sql.DB
+	config     RelayConfig
+	handlers   []namedHandler
+	workerID   string
+	registered bool
+	logger     *logger.Logger
+}
+
+// NewRelay создает новый relay
+func NewRelay(db *sql.DB, cfg RelayConfig, log *logger.Logger) *Relay {
+	hostname, _ := os.Hostname()
+	return &Relay{
+		db:       db,
+		config:   cfg,
+		handlers: make([]namedHandler, 0),
+		workerID: fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		logger:   log,
+	}
+}
+
+// AddHandler регистрирует обработчик событий. Имя сохраняется в доставках
+// и в реестре webhook_relay_handlers и не должно меняться между релизами.
+func (r *Relay) AddHandler(name string, h EventHandler) {
+	r.handlers = append(r.handlers, namedHandler{name: name, handler: h})
+}
+
+// handler возвращает обработчик по имени
+func (r *Relay) handler(name string) (EventHandler, bool) {
+	for _, h := range r.handlers {
+		if h.name == name {
+			return h.handler, true
+		}
+	}
+	return nil, false
+}
+
+// handlerNames возвращает имена зарегистрированных обработчиков
+func (r *Relay) handlerNames() []string {
+	names := make([]string, len(r.handlers))
+	for i, h := range r.handlers {
+		names[i] = h.name
+	}
+	return names
+}
+
+// Run обрабатывает outbox до отмены контекста
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	r.logger.Infow("Outbox relay started",
+		"poll_interval", r.config.PollInterval,
+		"batch_size", r.config.BatchSize,
+		"max_attempts", r.config.MaxAttempts,
+		"worker_id", r.workerID,
+	)
+
+	for {
+		// Вычитываем очередь пока есть события, затем ждем следующего тика
+		for {
+			n, err := r.ProcessBatch(ctx)
+			if err != nil {
+				r.logger.Errorw("Failed to process outbox batch", "error", err)
+				break
+			}
+			if n < r.config.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Outbox relay stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch распределяет новые события по обработчикам, захватывает
+// пачку доставок и выполняет их. Возвращает количество доставок.
+func (r *Relay) ProcessBatch(ctx context.Context) (int, error) {
+	if len(r.handlers) == 0 {
+		return 0, nil
+	}
+	if !r.registered {
+		if err := r.registerHandlers(ctx); err != nil {
+			return 0, err
+		}
+		r.registered = true
+	}
+	if err := r.fanOut(ctx); err != nil {
+		return 0, err
+	}
+
+	deliveries, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Доставки пачки выполняются параллельно, чтобы вся пачка уложилась
+	// в HandlerTimeout и аренду. Порядок не нарушается: из каждой очереди
+	// захватывается только одна доставка.
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		wg.Add(1)
+		go func(d *delivery) {
+			defer wg.Done()
+			r.process(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// registerHandlers добавляет обработчики реплики в общий реестр
+func (r *Relay) registerHandlers(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhook_relay_handlers (name)
+		SELECT unnest($1::text[])
+		ON CONFLICT (name) DO UPDATE SET last_seen_at = NOW()`,
+		pq.StringArray(r.handlerNames()))
+	if err != nil {
+		return fmt.Errorf("failed to register relay handlers: %w", err)
+	}
+	return nil
+}
+
+// fanOut создает доставки новых событий каждому обработчику из реестра,
+// в том числе обработчикам, которые зарегистрированы только на других репликах
+func (r *Relay) fanOut(ctx context.Context) error {
+	query := `
+		WITH fresh AS (
+		    UPDATE webhook_outbox
+		    SET status = 'dispatched'
+		    WHERE id IN (
+		        SELECT id FROM webhook_outbox
+		        WHERE status = 'pending'
+		        ORDER BY id
+		        LIMIT $1
+		        FOR UPDATE SKIP LOCKED
+		    )
+		    RETURNING id, aggregate_key, delivered_to
+		)
+		INSERT INTO webhook_outbox_deliveries (outbox_id, handler, aggregate_key, delivered_to)
+		SELECT f.id, h.name, f.aggregate_key, f.delivered_to
+		FROM fresh f CROSS JOIN webhook_relay_handlers h
+		ON CONFLICT DO NOTHING`
+
+	if _, err := r.db.ExecContext(ctx, query, r.config.BatchSize); err != nil {
+		return fmt.Errorf("failed to dispatch outbox events: %w", err)
+	}
+	return nil
+}
+
+// claim захватывает арендой доставки, готовые к выполнению. Берутся только
+// "головы" очередей: доставка с наименьшим ID события среди незавершенных
+// для своего обработчика и aggregate_key. Захваченная доставка остается
+// pending, поэтому следующая в очереди ждет ее завершения.
+// Доставка не захватывается, пока более раннее событие того же ключа еще не
+// распределено: fanOut разных реплик пропускает заблокированные события
+// (SKIP LOCKED), и без этой проверки событие N+1 могло бы обогнать событие N.
+func (r *Relay) claim(ctx context.Context) ([]*delivery, error) {
+	query := `
+		WITH claimable AS (
+		    SELECT d.outbox_id, d.handler
+		    FROM webhook_outbox_deliveries d
+		    WHERE d.status = 'pending'
+		      AND d.handler = ANY($1)
+		      AND d.next_attempt_at <= NOW()
+		      AND (d.locked_until IS NULL OR d.locked_until < NOW())
+		      AND NOT EXISTS (
+		          SELECT 1 FROM webhook_outbox_deliveries p
+		          WHERE p.handler = d.handler
+		            AND p.aggregate_key = d.aggregate_key
+		            AND p.status = 'pending'
+		            AND p.outbox_id < d.outbox_id
+		      )
+		      AND NOT EXISTS (
+		          SELECT 1 FROM webhook_outbox o
+		          WHERE o.aggregate_key = d.aggregate_key
+		            AND o.status = 'pending'
+		            AND o.id < d.outbox_id
+		      )
+		    ORDER BY d.outbox_id
+		    LIMIT $2
+		    FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_outbox_deliveries d
+		SET locked_until = NOW() + make_interval(secs => $3), claimed_by = $4
+		FROM claimable c, webhook_outbox o
+		WHERE d.outbox_id = c.outbox_id AND d.handler = c.handler AND o.id = d.outbox_id
+		RETURNING d.outbox_id, d.handler, o.aggregate_key, o.event, o.payload,
+		          d.attempts, d.delivered_to, o.created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.StringArray(r.handlerNames()), r.config.BatchSize,
+		r.config.LeaseDuration.Seconds(), r.workerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*delivery
+	for rows.Next() {
+		d := &delivery{event: &OutboxEvent{}}
+		var payload []byte
+		var deliveredTo pq.Int64Array
+		if err := rows.Scan(&d.event.ID, &d.handler, &d.event.AggregateKey, &d.event.Event, &payload,
+			&d.event.Attempts, &deliveredTo, &d.event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox delivery: %w", err)
+		}
+		if err := json.Unmarshal(payload, &d.event.Data); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal outbox payload %d: %w", d.event.ID, err)
+		}
+		d.event.DeliveredTo = deliveredTo
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate outbox deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// process выполняет доставку и фиксирует ее результат
+func (r *Relay) process(ctx context.Context, d *delivery) {
+	h, ok := r.handler(d.handler)
+	if !ok {
+		return
+	}
+
+	handlerCtx, cancel := context.WithTimeout(ctx, r.config.HandlerTimeout)
+	handleErr := h.HandleEvent(handlerCtx, d.event)
+	cancel()
+
+	if err := r.complete(ctx, d, h, handleErr); err != nil {
+		// Аренда истечет, и доставка будет выполнена повторно
+		r.logger.Errorw("Failed to complete outbox delivery",
+			"outbox_id", d.event.ID,
+			"handler", d.handler,
+			"error", err,
+		)
+	}
+}
+
+// complete фиксирует результат доставки. Когда завершена последняя доставка
+// события, событие получает итоговый статус: failed, если хотя бы одна
+// доставка исчерпала попытки, иначе processed.
+func (r *Relay) complete(ctx context.Context, d *delivery, h EventHandler, handleErr error) error {
+	event := d.event
+	res := r.outcome(ctx, d, h, handleErr, time.Now())
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// Блокировка события сериализует завершение его доставок, чтобы
+	// итоговый статус выставила ровно одна из них
+	var id int64
+	if err := tx.QueryRowContext(ctx,
+		"SELECT id FROM webhook_outbox WHERE id = $1 FOR UPDATE", event.ID,
+	).Scan(&id); err != nil {
+		return fmt.Errorf("failed to lock outbox event %d: %w", event.ID, err)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE webhook_outbox_deliveries
+		SET status = $1, attempts = $2, delivered_to = $3, last_error = $4,
+		    next_attempt_at = $5, locked_until = NULL, claimed_by = NULL,
+		    processed_at = CASE WHEN $1 = 'pending' THEN NULL ELSE NOW() END
+		WHERE outbox_id = $6 AND handler = $7 AND claimed_by = $8`,
+		res.status, event.Attempts, pq.Int64Array(event.DeliveredTo), res.lastErr,
+		res.nextAttempt, event.ID, d.handler, r.workerID)
+	if err != nil {
+		return fmt.Errorf("failed to update outbox delivery %d/%s: %w", event.ID, d.handler, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		// Аренда истекла и доставку захватила другая реплика
+		r.logger.Warnw("Outbox delivery lease lost", "outbox_id", event.ID, "handler", d.handler)
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE webhook_outbox o
+		SET status = CASE WHEN EXISTS (
+		        SELECT 1 FROM webhook_outbox_deliveries d
+		        WHERE d.outbox_id = o.id AND d.status = 'failed'
+		    ) THEN 'failed' ELSE 'processed' END,
+		    processed_at = NOW()
+		WHERE o.id = $1
+		  AND o.status = 'dispatched'
+		  AND NOT EXISTS (
+		      SELECT 1 FROM webhook_outbox_deliveries d
+		      WHERE d.outbox_id = o.id AND d.status = 'pending'
+		  )`, event.ID)
+	if err != nil {
+		return fmt.Errorf("failed to finalize outbox event %d: %w", event.ID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// deliveryOutcome итог попытки доставки, который сохраняется в БД
+type deliveryOutcome struct {
+	status      string
+	lastErr     interface{}
+	nextAttempt time.Time
+}
+
+// outcome определяет итог попытки доставки: отложенная доставка повторяется
+// через DeferDelay без учета попытки, ошибка — с экспоненциальной задержкой,
+// пока не исчерпаны попытки. Увеличивает счетчик попыток события.
+func (r *Relay) outcome(ctx context.Context, d *delivery, h EventHandler, handleErr error, now time.Time) deliveryOutcome {
+	event := d.event
+	status := OutboxStatusProcessed
+	var lastErr interface{}
+	nextAttempt := now
+
+	switch {
+	case errors.Is(handleErr, ErrDeliveryDeferred):
+		lastErr = handleErr.Error()
+		r.logger.Debugw("Outbox delivery deferred",
+			"outbox_id", event.ID,
+			"handler", d.handler,
+			"retry_in", r.config.DeferDelay,
+			"reason", handleErr,
+		)
+		status = OutboxStatusPending
+		nextAttempt = nextAttempt.Add(r.config.DeferDelay)
+	case handleErr != nil:
+		lastErr = handleErr.Error()
+		event.Attempts++
+		if event.Attempts >= r.config.MaxAttempts {
+			r.logger.Errorw("Outbox delivery exhausted retries",
+				"outbox_id", event.ID,
+				"handler", d.handler,
+				"event", event.Event,
+				"aggregate_key", event.AggregateKey,
+				"attempts", event.Attempts,
+				"error", handleErr,
+			)
+			if eh, ok := h.(ExhaustedHandler); ok {
+				eh.HandleExhausted(ctx, event, handleErr)
+			}
+			status = OutboxStatusFailed
+		} else {
+			delay := r.backoff(event.Attempts)
+			r.logger.Warnw("Outbox delivery failed, will retry",
+				"outbox_id", event.ID,
+				"handler", d.handler,
+				"event", event.Event,
+				"attempt", event.Attempts,
+				"retry_in", delay,
+				"error", handleErr,
+			)
+			status = OutboxStatusPending
+			nextAttempt = nextAttempt.Add(delay)
+		}
+	default:
+		event.Attempts++
+	}
+
+	return deliveryOutcome{status: status, lastErr: lastErr, nextAttempt: nextAttempt}
+}
+
+// backoff вычисляет задержку перед следующей попыткой (экспоненциально, с ограничением)
+func (r *Relay) backoff(attempt int) time.Duration {
+	delay := r.config.BaseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= r.config.MaxBackoff {
+			return r.config.MaxBackoff
+		}
+	}
+	return delay
+}
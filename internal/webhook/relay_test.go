@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+type exhaustedRecorder struct {
+	EventHandlerFunc
+	exhausted []int64
+}
+
+func (h *exhaustedRecorder) HandleExhausted(_ context.Context, event *OutboxEvent, _ error) {
+	h.exhausted = append(h.exhausted, event.ID)
+}
+
+func newTestRelay(t *testing.T) *Relay {
+	t.Helper()
+	log, err := logger.New("error", "test")
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	cfg := DefaultRelayConfig()
+	cfg.MaxAttempts = 3
+	cfg.BaseBackoff = time.Second
+	cfg.MaxBackoff = 5 * time.Second
+	return NewRelay(nil, cfg, log)
+}
+
+func TestRelay_Backoff(t *testing.T) {
+	r := newTestRelay(t)
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{20, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := r.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d): expected %v, got %v", tt.attempt, tt.want, got)
+		}
+	}
+}
+
+func TestRelay_OutcomeSuccess(t *testing.T) {
+	r := newTestRelay(t)
+	now := time.Now()
+	d := &delivery{handler: "test", event: &OutboxEvent{ID: 1}}
+
+	res := r.outcome(context.Background(), d, &exhaustedRecorder{}, nil, now)
+	if res.status != OutboxStatusProcessed || res.lastErr != nil {
+		t.Errorf("expected processed without error, got %+v", res)
+	}
+	if d.event.Attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", d.event.Attempts)
+	}
+}
+
+func TestRelay_OutcomeDeferred(t *testing.T) {
+	r := newTestRelay(t)
+	now := time.Now()
+	d := &delivery{handler: "test", event: &OutboxEvent{ID: 1, Attempts: 2}}
+
+	// Отложенная доставка не расходует попытки, даже последнюю
+	err := fmt.Errorf("%w: %w", ErrDeliveryDeferred, ErrWebhooksDisabled)
+	res := r.outcome(context.Background(), d, &exhaustedRecorder{}, err, now)
+	if res.status != OutboxStatusPending {
+		t.Errorf("expected deferred delivery to stay pending, got %s", res.status)
+	}
+	if !res.nextAttempt.Equal(now.Add(r.config.DeferDelay)) {
+		t.Errorf("expected retry after DeferDelay, got %v", res.nextAttempt.Sub(now))
+	}
+	if d.event.Attempts != 2 {
+		t.Errorf("expected attempts to stay 2, got %d", d.event.Attempts)
+	}
+}
+
+func TestRelay_OutcomeRetryAndExhausted(t *testing.T) {
+	r := newTestRelay(t)
+	now := time.Now()
+	h := &exhaustedRecorder{}
+	d := &delivery{handler: "test", event: &OutboxEvent{ID: 7}}
+	handleErr := errors.New("connection refused")
+
+	for attempt := 1; attempt < r.config.MaxAttempts; attempt++ {
+		res := r.outcome(context.Background(), d, h, handleErr, now)
+		if res.status != OutboxStatusPending {
+			t.Fatalf("attempt %d: expected pending, got %s", attempt, res.status)
+		}
+		if want := now.Add(r.backoff(attempt)); !res.nextAttempt.Equal(want) {
+			t.Errorf("attempt %d: expected retry in %v, got %v", attempt, r.backoff(attempt), res.nextAttempt.Sub(now))
+		}
+		if res.lastErr != handleErr.Error() {
+			t.Errorf("attempt %d: expected last error to be saved, got %v", attempt, res.lastErr)
+		}
+	}
+	if len(h.exhausted) != 0 {
+		t.Fatal("expected no exhausted callback before the last attempt")
+	}
+
+	res := r.outcome(context.Background(), d, h, handleErr, now)
+	if res.status != OutboxStatusFailed {
+		t.Errorf("expected failed after %d attempts, got %s", r.config.MaxAttempts, res.status)
+	}
+	if len(h.exhausted) != 1 || h.exhausted[0] != 7 {
+		t.Errorf("expected exhausted callback for event 7, got %v", h.exhausted)
+	}
+}
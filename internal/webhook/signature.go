@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader заголовок с подписью webhook
+	SignatureHeader = "X-Webhook-Signature"
+
+	// SignatureVersion текущая версия схемы подписи
+	SignatureVersion = "v1"
+
+	// DefaultSignatureTolerance допустимое расхождение времени подписи
+	DefaultSignatureTolerance = 5 * time.Minute
+
+	// maxVerifyBodySize ограничение размера тела при проверке запроса
+	maxVerifyBodySize = 1 << 20
+)
+
+var (
+	ErrMissingSignature       = errors.New("missing webhook signature")
+	ErrInvalidSignatureHeader = errors.New("invalid webhook signature header")
+	ErrSignatureExpired       = errors.New("webhook signature timestamp outside tolerance")
+	ErrSignatureMismatch      = errors.New("webhook signature mismatch")
+)
+
+// SignPayload формирует заголовок подписи вида "t=<unix>,v1=<hex>[,v1=<hex>]".
+// Подписывается строка "<timestamp>.<body>", поэтому перехваченный запрос
+// нельзя повторить после окончания окна допуска. Для каждого секрета
+// добавляется отдельная подпись v1 (используется при ротации).
+func SignPayload(body []byte, timestamp time.Time, secrets ...string) string {
+	ts := timestamp.Unix()
+
+	parts := make([]string, 0, len(secrets)+1)
+	parts = append(parts, "t="+strconv.FormatInt(ts, 10))
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		parts = append(parts, SignatureVersion+"="+computeSignature(ts, body, secret))
+	}
+	return strings.Join(parts, ",")
+}
+
+// computeSignature вычисляет HMAC-SHA256 от "<timestamp>.<body>"
+func computeSignature(timestamp int64, body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verifier проверяет подписи входящих webhook на стороне получателя.
+// Принимает несколько секретов, чтобы получатель мог пережить ротацию.
+type Verifier struct {
+	secrets   []string
+	tolerance time.Duration
+	now       func() time.Time
+}
+
+// NewVerifier создает verifier с окном допуска tolerance
+// (0 - DefaultSignatureTolerance)
+func NewVerifier(tolerance time.Duration, secrets ...string) *Verifier {
+	if tolerance <= 0 {
+		tolerance = DefaultSignatureTolerance
+	}
+	return &Verifier{
+		secrets:   secrets,
+		tolerance: tolerance,
+		now:       time.Now,
+	}
+}
+
+// Verify проверяет заголовок подписи для тела запроса
+func (v *Verifier) Verify(header string, body []byte) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+
+	timestamp, signatures, err := parseSignatureHeader(header)
+	if err != nil {
+		return err
+	}
+
+	signedAt := time.Unix(timestamp, 0)
+	if diff := v.now().Sub(signedAt); diff > v.tolerance || diff < -v.tolerance {
+		return ErrSignatureExpired
+	}
+
+	for _, secret := range v.secrets {
+		if secret == "" {
+			continue
+		}
+		expected := []byte(computeSignature(timestamp, body, secret))
+		for _, sig := range signatures {
+			if hmac.Equal(expected, []byte(sig)) {
+				return nil
+			}
+		}
+	}
+
+	return ErrSignatureMismatch
+}
+
+// VerifyRequest читает тело запроса и проверяет его подпись.
+// Возвращает прочитанное тело для дальнейшей обработки.
+func (v *Verifier) VerifyRequest(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxVerifyBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook body: %w", err)
+	}
+
+	if err := v.Verify(r.Header.Get(SignatureHeader), body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// parseSignatureHeader разбирает заголовок "t=...,v1=...,v1=..."
+func parseSignatureHeader(header string) (int64, []string, error) {
+	var timestamp int64
+	var signatures []string
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return 0, nil, ErrInvalidSignatureHeader
+		}
+
+		switch key {
+		case "t":
+			ts, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return 0, nil, ErrInvalidSignatureHeader
+			}
+			timestamp = ts
+		case SignatureVersion:
+			signatures = append(signatures, value)
+		}
+		// Неизвестные версии игнорируем для совместимости
+	}
+
+	if timestamp == 0 || len(signatures) == 0 {
+		return 0, nil, ErrInvalidSignatureHeader
+	}
+
+	return timestamp, signatures, nil
+}
+
+// VerifySignature проверяет заголовок подписи одним секретом
+// с окном допуска по умолчанию
+func VerifySignature(body []byte, header, secret string) bool {
+	return NewVerifier(DefaultSignatureTolerance, secret).Verify(header, body) == nil
+}
+
+// NewSecret генерирует случайный секрет подписи (32 байта в hex)
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
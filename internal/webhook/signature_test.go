@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignPayload_Format(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	header := SignPayload([]byte(`{"event":"pr.created"}`), ts, "new", "old")
+
+	parts := strings.Split(header, ",")
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts, got %d: %s", len(parts), header)
+	}
+	if parts[0] != "t=1700000000" {
+		t.Errorf("unexpected timestamp part: %s", parts[0])
+	}
+	for _, p := range parts[1:] {
+		if !strings.HasPrefix(p, "v1=") {
+			t.Errorf("expected v1 signature, got %s", p)
+		}
+	}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	body := []byte(`{"event":"pr.merged"}`)
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name    string
+		header  string
+		secrets []string
+		wantErr error
+	}{
+		{"valid", SignPayload(body, now, "secret"), []string{"secret"}, nil},
+		{"previous secret during rotation", SignPayload(body, now, "new", "old"), []string{"old"}, nil},
+		{"verifier has both secrets", SignPayload(body, now, "new"), []string{"old", "new"}, nil},
+		{"wrong secret", SignPayload(body, now, "secret"), []string{"other"}, ErrSignatureMismatch},
+		{"expired", SignPayload(body, now.Add(-10*time.Minute), "secret"), []string{"secret"}, ErrSignatureExpired},
+		{"from future", SignPayload(body, now.Add(10*time.Minute), "secret"), []string{"secret"}, ErrSignatureExpired},
+		{"missing", "", []string{"secret"}, ErrMissingSignature},
+		{"legacy body-only signature", "abcdef", []string{"secret"}, ErrInvalidSignatureHeader},
+		{"no signatures", "t=1700000000", []string{"secret"}, ErrInvalidSignatureHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVerifier(5*time.Minute, tt.secrets...)
+			v.now = func() time.Time { return now }
+
+			err := v.Verify(tt.header, body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifier_TamperedBody(t *testing.T) {
+	now := time.Now()
+	header := SignPayload([]byte(`{"pr_id":1}`), now, "secret")
+
+	if err := NewVerifier(0, "secret").Verify(header, []byte(`{"pr_id":2}`)); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("expected ErrSignatureMismatch, got %v", err)
+	}
+}
+
+func TestVerifier_VerifyRequest(t *testing.T) {
+	body := []byte(`{"event":"pr.closed"}`)
+	req := httptest.NewRequest("POST", "/hook", bytes.NewReader(body))
+	req.Header.Set(SignatureHeader, SignPayload(body, time.Now(), "secret"))
+
+	got, err := NewVerifier(0, "secret").VerifyRequest(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Errorf("expected body to be returned")
+	}
+}
+
+func TestSubscription_SigningSecrets(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	sub := &Subscription{Secret: "new", PreviousSecret: "old", PreviousSecretExpiresAt: &future}
+	if got := sub.signingSecrets(now); len(got) != 2 {
+		t.Errorf("expected both secrets during grace period, got %v", got)
+	}
+
+	sub.PreviousSecretExpiresAt = &past
+	if got := sub.signingSecrets(now); len(got) != 1 || got[0] != "new" {
+		t.Errorf("expected only current secret after grace period, got %v", got)
+	}
+}
@@ -0,0 +1,224 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+// SubscriptionsChannel канал Postgres LISTEN/NOTIFY об изменении подписок;
+// payload - ID подписки
+const SubscriptionsChannel = "webhook_subscriptions_changed"
+
+// SubscriptionStore хранит webhook подписки в таблице webhook_subscriptions
+type SubscriptionStore struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewSubscriptionStore создает новое хранилище подписок
+func NewSubscriptionStore(db *sql.DB, log *logger.Logger) *SubscriptionStore {
+	return &SubscriptionStore{
+		db:     db,
+		logger: log,
+	}
+}
+
+// List возвращает все подписки
+func (s *SubscriptionStore) List(ctx context.Context) ([]*Subscription, error) {
+	query := `
+		SELECT id, url, events, COALESCE(secret, ''), COALESCE(previous_secret, ''),
+		       previous_secret_expires_at, format, target, COALESCE(template, ''), filter, active, failure_streak, disabled_at, created_at
+		FROM webhook_subscriptions
+		ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []*Subscription{}
+	for rows.Next() {
+		sub := &Subscription{}
+		var events pq.StringArray
+		var filter []byte
+		if err := rows.Scan(&sub.ID, &sub.URL, &events, &sub.Secret, &sub.PreviousSecret,
+			&sub.PreviousSecretExpiresAt, &sub.Format, &sub.Target, &sub.Template, &filter, &sub.Active, &sub.FailureStreak, &sub.DisabledAt,
+			&sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		if !sub.Format.Valid() {
+			return nil, fmt.Errorf("subscription %d: %w: %q", sub.ID, ErrInvalidFormat, sub.Format)
+		}
+		for _, e := range events {
+			sub.Events = append(sub.Events, EventType(e))
+		}
+		if len(filter) > 0 {
+			sub.Filter = &Filter{}
+			if err := json.Unmarshal(filter, sub.Filter); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal filter of subscription %d: %w", sub.ID, err)
+			}
+		}
+		subs = append(subs, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate webhook subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+// IncrementFailureStreak атомарно увеличивает серию неудачных событий
+// подписки и возвращает новое значение. Серия общая для всех реплик.
+func (s *SubscriptionStore) IncrementFailureStreak(ctx context.Context, id int64) (int, error) {
+	var streak int
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE webhook_subscriptions
+		SET failure_streak = failure_streak + 1, updated_at = NOW()
+		WHERE id = $1
+		RETURNING failure_streak`,
+		id).Scan(&streak)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment failure streak: %w", err)
+	}
+	return streak, nil
+}
+
+// ResetFailureStreak сбрасывает серию неудачных событий подписки
+func (s *SubscriptionStore) ResetFailureStreak(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_subscriptions
+		SET failure_streak = 0, updated_at = NOW()
+		WHERE id = $1 AND failure_streak > 0`,
+		id)
+	if err != nil {
+		return fmt.Errorf("failed to reset failure streak: %w", err)
+	}
+	return nil
+}
+
+// Disable отключает активную подписку, если ее серия неудачных событий не
+// меньше minStreak: включение подписки между проверкой порога и отключением
+// сбрасывает серию. Возвращает false, если подписка не отключена этим вызовом.
+func (s *SubscriptionStore) Disable(ctx context.Context, id int64, minStreak int, reason string) (bool, error) {
+	var disabled bool
+	err := s.notifyInTx(ctx, id, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE webhook_subscriptions
+			SET active = false, disabled_at = NOW(), disabled_reason = $1, updated_at = NOW()
+			WHERE id = $2 AND active AND failure_streak >= $3`,
+			reason, id, minStreak)
+		if err != nil {
+			return fmt.Errorf("failed to disable subscription: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		disabled = rows > 0
+		return nil
+	})
+	return disabled, err
+}
+
+// Enable включает подписку и сбрасывает счетчик ошибок
+func (s *SubscriptionStore) Enable(ctx context.Context, id int64) error {
+	return s.notifyInTx(ctx, id, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE webhook_subscriptions
+			SET active = true, failure_streak = 0, disabled_at = NULL, disabled_reason = NULL, updated_at = NOW()
+			WHERE id = $1`,
+			id)
+		if err != nil {
+			return fmt.Errorf("failed to enable subscription: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows == 0 {
+			return ErrSubscriptionNotFound
+		}
+		return nil
+	})
+}
+
+// UpdateFormat меняет формат тела доставок подписки
+func (s *SubscriptionStore) UpdateFormat(ctx context.Context, id int64, format Format) error {
+	return s.notifyInTx(ctx, id, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			`UPDATE webhook_subscriptions SET format = $1, updated_at = NOW() WHERE id = $2`,
+			format, id)
+		if err != nil {
+			return fmt.Errorf("failed to update subscription format: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows == 0 {
+			return ErrSubscriptionNotFound
+		}
+		return nil
+	})
+}
+
+// RotateSecret заменяет секрет подписки, сохраняя текущий как предыдущий
+// до previousExpiresAt
+func (s *SubscriptionStore) RotateSecret(ctx context.Context, id int64, secret string, previousExpiresAt time.Time) error {
+	return s.notifyInTx(ctx, id, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE webhook_subscriptions
+			SET previous_secret = secret, previous_secret_expires_at = $1, secret = $2, updated_at = NOW()
+			WHERE id = $3`,
+			previousExpiresAt, secret, id)
+		if err != nil {
+			return fmt.Errorf("failed to rotate subscription secret: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows == 0 {
+			return ErrSubscriptionNotFound
+		}
+		return nil
+	})
+}
+
+// notifyInTx выполняет изменение подписки и NOTIFY в одной транзакции:
+// остальные реплики перечитают подписки только после commit
+func (s *SubscriptionStore) notifyInTx(ctx context.Context, id int64, change func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := change(tx); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT pg_notify($1, $2)`, SubscriptionsChannel, strconv.FormatInt(id, 10)); err != nil {
+		return fmt.Errorf("failed to notify subscription change: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -3,14 +3,14 @@ package webhook
 import (
 	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/user/pr-reviewer/internal/circuitbreaker"
+	"github.com/user/pr-reviewer/internal/featureflags"
 	"github.com/user/pr-reviewer/internal/logger"
 	"github.com/user/pr-reviewer/internal/models"
 )
@@ -27,23 +27,54 @@ const (
 	EventUserDeactivated  EventType = "user.deactivated"
 )
 
-// Payload данные webhook события
+// Payload данные webhook события.
+// ID стабилен между повторными доставками и позволяет получателю дедуплицировать события.
 type Payload struct {
+	ID        string                 `json:"id,omitempty"`
 	Event     EventType              `json:"event"`
+	Subject   string                 `json:"subject,omitempty"`
 	Timestamp time.Time              `json:"timestamp"`
 	Data      map[string]interface{} `json:"data"`
 }
 
 // Subscription подписка на webhook
 type Subscription struct {
-	ID        int64       `json:"id"`
-	URL       string      `json:"url"`
-	Events    []EventType `json:"events"`
-	Secret    string      `json:"secret,omitempty"`
-	Active    bool        `json:"active"`
-	CreatedAt time.Time   `json:"created_at"`
+	ID            int64       `json:"id"`
+	URL           string      `json:"url"`
+	Events        []EventType `json:"events"`
+	Secret        string      `json:"secret,omitempty"`
+	Format        Format      `json:"format"`
+	Target        Target      `json:"target"`
+	Template      string      `json:"template,omitempty"`
+	Filter        *Filter     `json:"filter,omitempty"`
+	Active        bool        `json:"active"`
+	FailureStreak int         `json:"failure_streak"`
+	DisabledAt    *time.Time  `json:"disabled_at,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+
+	// Предыдущий секрет продолжает подписывать запросы до окончания grace периода
+	PreviousSecret          string     `json:"-"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"`
 }
 
+// signingSecrets возвращает секреты, которыми подписывается доставка
+func (s *Subscription) signingSecrets(now time.Time) []string {
+	if s.Secret == "" {
+		return nil
+	}
+	secrets := []string{s.Secret}
+	if s.PreviousSecret != "" && s.PreviousSecretExpiresAt != nil && now.Before(*s.PreviousSecretExpiresAt) {
+		secrets = append(secrets, s.PreviousSecret)
+	}
+	return secrets
+}
+
+// errSubscriptionDisabled причина dead letter для автоматически отключенной подписки
+var errSubscriptionDisabled = errors.New("subscription disabled after repeated failures")
+
+// ErrWebhooksDisabled доставка выключена флагом webhooks
+var ErrWebhooksDisabled = errors.New("webhooks are disabled")
+
 // Deliverer интерфейс для доставки webhook
 type Deliverer interface {
 	Deliver(ctx context.Context, sub *Subscription, payload *Payload) error
@@ -52,6 +83,7 @@ type Deliverer interface {
 // HTTPDeliverer HTTP реализация доставки webhook
 type HTTPDeliverer struct {
 	client *http.Client
+	source string
 	logger *logger.Logger
 }
 
@@ -61,16 +93,24 @@ func NewHTTPDeliverer(log *logger.Logger) *HTTPDeliverer {
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		source: DefaultEventSource,
 		logger: log,
 	}
 }
 
+// SetSource задает CloudEvents source для подписок в формате CloudEvents
+func (d *HTTPDeliverer) SetSource(source string) {
+	if source != "" {
+		d.source = source
+	}
+}
+
 // Deliver отправляет webhook
 func (d *HTTPDeliverer) Deliver(ctx context.Context, sub *Subscription, payload *Payload) error {
-	// Сериализуем payload
-	body, err := json.Marshal(payload)
+	// Сериализуем payload в формате подписки
+	body, headers, err := encodePayload(sub.Format, payload, d.source)
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return err
 	}
 
 	// Создаем HTTP запрос
@@ -80,14 +120,18 @@ func (d *HTTPDeliverer) Deliver(ctx context.Context, sub *Subscription, payload
 	}
 
 	// Устанавливаем headers
-	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	// Заголовок и подпись используют время отправки, чтобы повторы и replay
+	// проходили проверку окна допуска у получателя. Время события - в payload.
+	now := time.Now()
 	req.Header.Set("X-Webhook-Event", string(payload.Event))
-	req.Header.Set("X-Webhook-Timestamp", payload.Timestamp.Format(time.RFC3339))
+	req.Header.Set("X-Webhook-Timestamp", now.UTC().Format(time.RFC3339))
 
 	// Добавляем HMAC signature если есть secret
-	if sub.Secret != "" {
-		signature := generateSignature(body, sub.Secret)
-		req.Header.Set("X-Webhook-Signature", signature)
+	if secrets := sub.signingSecrets(now); len(secrets) > 0 {
+		req.Header.Set(SignatureHeader, SignPayload(body, now, secrets...))
 	}
 
 	// Отправляем запрос
@@ -121,12 +165,25 @@ func (d *HTTPDeliverer) Deliver(ctx context.Context, sub *Subscription, payload
 	return nil
 }
 
-// Manager управляет webhook подписками и доставкой
+// HandlerName имя Manager в outbox relay
+const HandlerName = "webhooks"
+
+// Manager управляет webhook подписками и доставкой.
+// Если подключен outbox, события сохраняются в БД и доставляются через Relay;
+// иначе используется in-memory очередь (best effort).
 type Manager struct {
-	deliverer     Deliverer
-	subscriptions []*Subscription
-	logger        *logger.Logger
-	queue         chan *webhookJob
+	deliverer        Deliverer
+	subscriptions    []*Subscription
+	mu               sync.RWMutex
+	outbox           *Outbox
+	store            *SubscriptionStore
+	deadLetters      *DeadLetterStore
+	maxFailureStreak int
+	flags            *featureflags.Manager
+	breakers         map[int64]*circuitbreaker.CircuitBreaker
+	breakersMu       sync.Mutex
+	logger           *logger.Logger
+	queue            chan *webhookJob
 }
 
 type webhookJob struct {
@@ -139,6 +196,7 @@ func NewManager(deliverer Deliverer, log *logger.Logger) *Manager {
 	m := &Manager{
 		deliverer:     deliverer,
 		subscriptions: make([]*Subscription, 0),
+		breakers:      make(map[int64]*circuitbreaker.CircuitBreaker),
 		logger:        log,
 		queue:         make(chan *webhookJob, 100),
 	}
@@ -151,9 +209,88 @@ func NewManager(deliverer Deliverer, log *logger.Logger) *Manager {
 	return m
 }
 
+// SetOutbox подключает transactional outbox
+func (m *Manager) SetOutbox(outbox *Outbox) {
+	m.outbox = outbox
+}
+
+// SetSubscriptionStore подключает хранилище для сохранения состояния подписок
+func (m *Manager) SetSubscriptionStore(store *SubscriptionStore) {
+	m.store = store
+}
+
+// SetDeadLetters подключает хранилище dead letters
+func (m *Manager) SetDeadLetters(store *DeadLetterStore) {
+	m.deadLetters = store
+}
+
+// SetMaxFailureStreak задает количество событий подряд, не доставленных за все попытки,
+// после которого подписка автоматически отключается (0 - не отключать)
+func (m *Manager) SetMaxFailureStreak(n int) {
+	m.maxFailureStreak = n
+}
+
+// SetFeatureFlags подключает feature flags. Флаг webhooks включает доставку,
+// circuit_breaker - circuit breaker на каждую подписку; флаги проверяются
+// при каждой доставке.
+func (m *Manager) SetFeatureFlags(flags *featureflags.Manager) {
+	m.flags = flags
+}
+
+// Enabled проверяет флаг webhooks
+func (m *Manager) Enabled() bool {
+	return m.flags == nil || m.flags.IsEnabled(featureflags.FlagWebhooks)
+}
+
+// Reload перечитывает подписки из хранилища. Поля уже загруженных подписок
+// обновляются на месте под блокировкой: доставки, которые держат указатель
+// на подписку, видят новое состояние. Удаленные подписки исключаются.
+func (m *Manager) Reload(ctx context.Context) error {
+	if m.store == nil {
+		return nil
+	}
+	loaded, err := m.store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	var reenabled []int64
+	m.mu.Lock()
+	current := make(map[int64]*Subscription, len(m.subscriptions))
+	for _, sub := range m.subscriptions {
+		current[sub.ID] = sub
+	}
+	subs := make([]*Subscription, 0, len(loaded))
+	for _, sub := range loaded {
+		if existing, ok := current[sub.ID]; ok {
+			if !existing.Active && sub.Active {
+				reenabled = append(reenabled, sub.ID)
+			}
+			*existing = *sub
+			sub = existing
+		}
+		subs = append(subs, sub)
+	}
+	m.subscriptions = subs
+	m.mu.Unlock()
+
+	// Подписку включили на другой реплике: breaker этой реплики тоже сбрасывается
+	m.breakersMu.Lock()
+	for _, id := range reenabled {
+		delete(m.breakers, id)
+	}
+	m.breakersMu.Unlock()
+
+	m.logger.Debugw("Webhook subscriptions reloaded", "count", len(subs))
+	return nil
+}
+
 // Subscribe добавляет подписку
 func (m *Manager) Subscribe(sub *Subscription) {
+	m.mu.Lock()
 	m.subscriptions = append(m.subscriptions, sub)
+	m.mu.Unlock()
+
 	m.logger.Infow("Webhook subscription added",
 		"id", sub.ID,
 		"url", sub.URL,
@@ -163,42 +300,549 @@ func (m *Manager) Subscribe(sub *Subscription) {
 
 // Trigger отправляет webhook всем подписчикам
 func (m *Manager) Trigger(event EventType, data map[string]interface{}) {
+	// С outbox событие сохраняется в БД и не теряется при переполнении или
+	// рестарте; при выключенных webhooks relay откладывает его доставку
+	if m.outbox != nil {
+		err := m.outbox.Enqueue(context.Background(), nil, event, aggregateKeyFor(event, data), data)
+		if err != nil {
+			m.logger.Errorw("Failed to enqueue webhook event",
+				"event", event,
+				"error", err,
+			)
+		}
+		return
+	}
+
 	payload := &Payload{
+		ID:        newEventID(),
 		Event:     event,
+		Subject:   subjectFor(data),
 		Timestamp: time.Now(),
 		Data:      data,
 	}
 
+	// Без outbox отложить событие негде: сохраняем его в dead letters для
+	// replay после включения webhooks
+	if !m.Enabled() {
+		m.deferDisabled(payload)
+		return
+	}
+
 	// Находим все активные подписки на это событие
-	for _, sub := range m.subscriptions {
-		if !sub.Active {
+	for _, sub := range m.matchingSubscriptions(event, data) {
+		// Добавляем в очередь
+		select {
+		case m.queue <- &webhookJob{
+			subscription: sub,
+			payload:      payload,
+		}:
+		default:
+			m.logger.Warnw("Webhook queue is full, dropping event",
+				"subscription_id", sub.ID,
+				"event", event,
+			)
+		}
+	}
+}
+
+// deferDisabled сохраняет событие, пришедшее при выключенных webhooks
+func (m *Manager) deferDisabled(payload *Payload) {
+	for _, sub := range m.matchingSubscriptions(payload.Event, payload.Data) {
+		if m.deadLetters != nil && m.saveDeadLetter(context.Background(), sub, payload, nil, ErrWebhooksDisabled, 0) == nil {
+			continue
+		}
+		m.logger.Warnw("Webhooks are disabled, dropping event",
+			"subscription_id", sub.ID,
+			"event", payload.Event,
+		)
+	}
+}
+
+// HandleEvent доставляет событие из outbox всем подходящим подпискам.
+// Реализует EventHandler для Relay: подписки, которым событие уже доставлено,
+// пропускаются, успешные доставки отмечаются в событии. Пока webhooks
+// выключены флагом, доставка откладывается и событие остается в outbox.
+func (m *Manager) HandleEvent(ctx context.Context, event *OutboxEvent) error {
+	if !m.Enabled() {
+		return fmt.Errorf("%w: %w", ErrDeliveryDeferred, ErrWebhooksDisabled)
+	}
+
+	payload := event.Payload()
+
+	var errs []error
+	for _, sub := range m.subscribers(event.Event, event.Data) {
+		if event.IsDeliveredTo(sub.ID) {
+			continue
+		}
+
+		active, disabledAt := m.subscriptionState(sub)
+		if !active {
+			// Подписка отключена из-за серии ошибок: сохраняем событие для replay
+			if disabledAt != nil && m.deadLetters != nil {
+				if err := m.saveDeadLetter(ctx, sub, payload, &event.ID, errSubscriptionDisabled, 0); err == nil {
+					event.MarkDelivered(sub.ID)
+				}
+			}
+			continue
+		}
+
+		if err := m.deliver(ctx, sub, payload); err != nil {
+			errs = append(errs, fmt.Errorf("subscription %d: %w", sub.ID, err))
+			continue
+		}
+		event.MarkDelivered(sub.ID)
+	}
+
+	return errors.Join(errs...)
+}
+
+// HandleExhausted учитывает событие в серии ошибок подписок, которым его не
+// удалось доставить за все попытки relay, и сохраняет доставки в dead letters
+func (m *Manager) HandleExhausted(ctx context.Context, event *OutboxEvent, lastErr error) {
+	payload := event.Payload()
+	for _, sub := range m.subscribers(event.Event, event.Data) {
+		if event.IsDeliveredTo(sub.ID) {
+			continue
+		}
+		if active, _ := m.subscriptionState(sub); active {
+			m.recordFailure(ctx, sub, lastErr)
+		}
+		if m.deadLetters == nil {
 			continue
 		}
+		if err := m.saveDeadLetter(ctx, sub, payload, &event.ID, lastErr, event.Attempts); err == nil {
+			event.MarkDelivered(sub.ID)
+		}
+	}
+}
+
+// DeadLetters возвращает dead letters по фильтру
+func (m *Manager) DeadLetters(ctx context.Context, filter DeadLetterFilter) ([]*DeadLetter, error) {
+	if m.deadLetters == nil {
+		return []*DeadLetter{}, nil
+	}
+	return m.deadLetters.List(ctx, filter)
+}
+
+// Replay повторно доставляет dead letter его подписке
+func (m *Manager) Replay(ctx context.Context, id int64) (*DeadLetter, error) {
+	if !m.Enabled() {
+		return nil, ErrWebhooksDisabled
+	}
+	if m.deadLetters == nil {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	dl, err := m.deadLetters.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.replay(ctx, dl); err != nil {
+		return dl, err
+	}
+
+	now := time.Now()
+	dl.ReplayedAt = &now
+	return dl, nil
+}
+
+// ReplayBulk повторно доставляет все необработанные dead letters подписки
+// за указанный период
+func (m *Manager) ReplayBulk(ctx context.Context, filter DeadLetterFilter) (*ReplayResult, error) {
+	if !m.Enabled() {
+		return nil, ErrWebhooksDisabled
+	}
+
+	result := &ReplayResult{}
+	if m.deadLetters == nil {
+		return result, nil
+	}
+
+	filter.IncludeReplayed = false
+	filter.Offset = 0
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	}
+
+	for {
+		letters, err := m.deadLetters.List(ctx, filter)
+		if err != nil {
+			return result, err
+		}
+
+		for _, dl := range letters {
+			if err := m.replay(ctx, dl); err != nil {
+				result.Failed++
+				result.Errors = append(result.Errors, fmt.Sprintf("dead letter %d: %v", dl.ID, err))
+			} else {
+				result.Replayed++
+			}
+			filter.AfterID = dl.ID
+		}
+
+		if len(letters) < filter.Limit {
+			break
+		}
+	}
+
+	m.logger.Infow("Dead letters replayed",
+		"subscription_id", filter.SubscriptionID,
+		"replayed", result.Replayed,
+		"failed", result.Failed,
+	)
+
+	return result, nil
+}
+
+// replay доставляет dead letter и отмечает его при успехе
+func (m *Manager) replay(ctx context.Context, dl *DeadLetter) error {
+	sub := m.subscription(dl.SubscriptionID)
+	if sub == nil {
+		return ErrSubscriptionNotFound
+	}
+
+	if err := m.deliver(ctx, sub, dl.Payload); err != nil {
+		m.recordFailure(ctx, sub, err)
+		return err
+	}
 
+	return m.deadLetters.MarkReplayed(ctx, dl.ID)
+}
+
+// deliver выполняет одну попытку доставки; успешная доставка сбрасывает серию
+// неудачных событий подписки. Неудачная попытка в серию не входит: событие
+// учитывается один раз, когда его доставка окончательно не удалась
+// (см. recordFailure). С флагом circuit_breaker доставка идет через breaker
+// подписки.
+func (m *Manager) deliver(ctx context.Context, sub *Subscription, payload *Payload) error {
+	// Доставка читает копию: поля подписки меняются под блокировкой
+	// (ротация секрета, Reload)
+	m.mu.RLock()
+	snapshot := *sub
+	m.mu.RUnlock()
+
+	var err error
+	if m.flags == nil || !m.flags.IsEnabled(featureflags.FlagCircuitBreaker) {
+		err = m.deliverer.Deliver(ctx, &snapshot, payload)
+	} else {
+		_, err = m.breaker(sub.ID).Execute(func() (interface{}, error) {
+			return nil, m.deliverer.Deliver(ctx, &snapshot, payload)
+		})
+	}
+	if err == nil {
+		m.recordSuccess(ctx, sub)
+	}
+	return err
+}
+
+// breaker возвращает circuit breaker подписки
+func (m *Manager) breaker(subscriptionID int64) *circuitbreaker.CircuitBreaker {
+	m.breakersMu.Lock()
+	defer m.breakersMu.Unlock()
+
+	cb, exists := m.breakers[subscriptionID]
+	if !exists {
+		cb = circuitbreaker.New(circuitbreaker.NewDefaultConfig(fmt.Sprintf("webhook:%d", subscriptionID)), m.logger)
+		m.breakers[subscriptionID] = cb
+	}
+	return cb
+}
+
+// recordSuccess сбрасывает серию неудачных событий. Успешный replay dead
+// letter отключенной подписки включает ее снова: получатель снова доступен.
+func (m *Manager) recordSuccess(ctx context.Context, sub *Subscription) {
+	m.mu.Lock()
+	sub.FailureStreak = 0
+	enable := !sub.Active && sub.DisabledAt != nil
+	if enable {
+		sub.Active = true
+		sub.DisabledAt = nil
+	}
+	m.mu.Unlock()
+
+	if enable {
+		m.logger.Infow("Webhook subscription re-enabled after successful replay", "subscription_id", sub.ID)
+	}
+	if m.store == nil {
+		return
+	}
+
+	// Серия хранится в БД: ее могли увеличить другие реплики
+	if enable {
+		if err := m.store.Enable(ctx, sub.ID); err != nil {
+			m.logger.Warnw("Failed to persist re-enabled subscription", "subscription_id", sub.ID, "error", err)
+		}
+		return
+	}
+	if err := m.store.ResetFailureStreak(ctx, sub.ID); err != nil {
+		m.logger.Warnw("Failed to reset webhook failure streak", "subscription_id", sub.ID, "error", err)
+	}
+}
+
+// recordFailure учитывает событие, которое не удалось доставить подписке за
+// все попытки, и отключает подписку, когда серия достигает порога. С
+// хранилищем серия и отключение общие для всех реплик: остальные реплики
+// перечитывают отключенную подписку по уведомлению (см. Listener).
+func (m *Manager) recordFailure(ctx context.Context, sub *Subscription, deliveryErr error) {
+	var streak int
+	disable := false
+	if m.store != nil {
+		var err error
+		if streak, err = m.store.IncrementFailureStreak(ctx, sub.ID); err != nil {
+			m.logger.Warnw("Failed to persist webhook failure streak", "subscription_id", sub.ID, "error", err)
+			return
+		}
+		if m.maxFailureStreak > 0 && streak >= m.maxFailureStreak {
+			reason := fmt.Sprintf("%d consecutive failed events, last error: %v", streak, deliveryErr)
+			if disable, err = m.store.Disable(ctx, sub.ID, m.maxFailureStreak, reason); err != nil {
+				m.logger.Warnw("Failed to persist disabled subscription", "subscription_id", sub.ID, "error", err)
+			}
+		}
+	}
+
+	m.mu.Lock()
+	if m.store == nil {
+		sub.FailureStreak++
+		streak = sub.FailureStreak
+		disable = m.maxFailureStreak > 0 && streak >= m.maxFailureStreak && sub.Active
+	} else {
+		sub.FailureStreak = streak
+	}
+	if disable {
+		now := time.Now()
+		sub.Active = false
+		sub.DisabledAt = &now
+	}
+	m.mu.Unlock()
+
+	if disable {
+		m.logger.Errorw("Webhook subscription disabled after repeated failures",
+			"subscription_id", sub.ID,
+			"url", sub.URL,
+			"failure_streak", streak,
+		)
+	}
+}
+
+// saveDeadLetter сохраняет неудавшуюся доставку
+func (m *Manager) saveDeadLetter(ctx context.Context, sub *Subscription, payload *Payload, outboxID *int64, cause error, attempts int) error {
+	if m.deadLetters == nil {
+		return nil
+	}
+
+	err := m.deadLetters.Save(ctx, &DeadLetter{
+		SubscriptionID: sub.ID,
+		OutboxID:       outboxID,
+		Event:          payload.Event,
+		Payload:        payload,
+		Error:          cause.Error(),
+		Attempts:       attempts,
+	})
+	if err != nil {
+		m.logger.Errorw("Failed to save webhook dead letter",
+			"subscription_id", sub.ID,
+			"event", payload.Event,
+			"error", err,
+		)
+	}
+	return err
+}
+
+// RotateSecret устанавливает новый секрет подписки. Старый секрет продолжает
+// подписывать доставки в течение gracePeriod, чтобы получатель успел обновиться.
+// Секрет сохраняется в хранилище, остальные реплики перечитывают подписку
+// по уведомлению (см. Listener). Возвращает время окончания grace периода.
+func (m *Manager) RotateSecret(ctx context.Context, id int64, secret string, gracePeriod time.Duration) (time.Time, error) {
+	if secret == "" {
+		return time.Time{}, errors.New("secret is required")
+	}
+
+	expiresAt := time.Now().Add(gracePeriod)
+	if m.store != nil {
+		if err := m.store.RotateSecret(ctx, id, secret, expiresAt); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	sub := m.subscription(id)
+	if sub == nil {
+		if m.store == nil {
+			return time.Time{}, ErrSubscriptionNotFound
+		}
+		// Подписка создана на другой реплике и еще не загружена
+		if err := m.Reload(ctx); err != nil {
+			return time.Time{}, err
+		}
+	} else {
+		// Поля меняются на месте: серия ошибок продолжает обновлять ту же подписку
+		m.mu.Lock()
+		sub.PreviousSecret = sub.Secret
+		sub.PreviousSecretExpiresAt = &expiresAt
+		sub.Secret = secret
+		m.mu.Unlock()
+	}
+
+	m.logger.Infow("Webhook secret rotated",
+		"subscription_id", id,
+		"previous_secret_expires_at", expiresAt,
+	)
+	return expiresAt, nil
+}
+
+// Subscriptions возвращает копии всех подписок. С хранилищем подписки читаются
+// из него: серию ошибок увеличивают все реплики.
+func (m *Manager) Subscriptions(ctx context.Context) ([]Subscription, error) {
+	if m.store != nil {
+		loaded, err := m.store.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		subs := make([]Subscription, 0, len(loaded))
+		for _, sub := range loaded {
+			subs = append(subs, *sub)
+		}
+		return subs, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	subs := make([]Subscription, 0, len(m.subscriptions))
+	for _, sub := range m.subscriptions {
+		subs = append(subs, *sub)
+	}
+	return subs, nil
+}
+
+// SetFormat меняет формат тела доставок подписки. Формат сохраняется в
+// хранилище, остальные реплики перечитывают подписку по уведомлению.
+func (m *Manager) SetFormat(ctx context.Context, id int64, format Format) (*Subscription, error) {
+	if format == "" {
+		format = FormatPayload
+	}
+	if !format.Valid() {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFormat, format)
+	}
+
+	if m.store != nil {
+		if err := m.store.UpdateFormat(ctx, id, format); err != nil {
+			return nil, err
+		}
+	}
+
+	sub := m.subscription(id)
+	if sub == nil {
+		if m.store == nil {
+			return nil, ErrSubscriptionNotFound
+		}
+		// Подписка создана на другой реплике и еще не загружена
+		if err := m.Reload(ctx); err != nil {
+			return nil, err
+		}
+		if sub = m.subscription(id); sub == nil {
+			return nil, ErrSubscriptionNotFound
+		}
+	}
+
+	m.mu.Lock()
+	sub.Format = format
+	updated := *sub
+	m.mu.Unlock()
+
+	m.logger.Infow("Webhook subscription format changed", "subscription_id", id, "format", format)
+	return &updated, nil
+}
+
+// EnableSubscription включает подписку, отключенную после серии ошибок,
+// и сбрасывает счетчик ошибок и circuit breaker. Остальные реплики
+// перечитывают подписку по уведомлению (см. Listener).
+func (m *Manager) EnableSubscription(ctx context.Context, id int64) (*Subscription, error) {
+	if m.store != nil {
+		if err := m.store.Enable(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	sub := m.subscription(id)
+	if sub == nil {
+		if m.store == nil {
+			return nil, ErrSubscriptionNotFound
+		}
+		// Подписка создана на другой реплике и еще не загружена
+		if err := m.Reload(ctx); err != nil {
+			return nil, err
+		}
+		if sub = m.subscription(id); sub == nil {
+			return nil, ErrSubscriptionNotFound
+		}
+	}
+
+	m.mu.Lock()
+	sub.Active = true
+	sub.FailureStreak = 0
+	sub.DisabledAt = nil
+	enabled := *sub
+	m.mu.Unlock()
+
+	m.breakersMu.Lock()
+	delete(m.breakers, id)
+	m.breakersMu.Unlock()
+
+	m.logger.Infow("Webhook subscription enabled", "subscription_id", id)
+	return &enabled, nil
+}
+
+// subscription возвращает подписку по ID
+func (m *Manager) subscription(id int64) *Subscription {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, sub := range m.subscriptions {
+		if sub.ID == id {
+			return sub
+		}
+	}
+	return nil
+}
+
+// subscriptionState возвращает текущее состояние подписки
+func (m *Manager) subscriptionState(sub *Subscription) (bool, *time.Time) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return sub.Active, sub.DisabledAt
+}
+
+// subscribers возвращает все подписки (включая неактивные) на событие,
+// чей фильтр проходят данные события
+func (m *Manager) subscribers(event EventType, data map[string]interface{}) []*Subscription {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*Subscription, 0)
+	for _, sub := range m.subscriptions {
 		// Проверяем что подписка слушает это событие
-		subscribed := false
 		for _, e := range sub.Events {
 			if e == event {
-				subscribed = true
+				if sub.Filter.Matches(data) {
+					result = append(result, sub)
+				}
 				break
 			}
 		}
+	}
+	return result
+}
 
-		if subscribed {
-			// Добавляем в очередь
-			select {
-			case m.queue <- &webhookJob{
-				subscription: sub,
-				payload:      payload,
-			}:
-			default:
-				m.logger.Warnw("Webhook queue is full, dropping event",
-					"subscription_id", sub.ID,
-					"event", event,
-				)
-			}
+// matchingSubscriptions возвращает активные подписки на событие, прошедшие фильтр
+func (m *Manager) matchingSubscriptions(event EventType, data map[string]interface{}) []*Subscription {
+	result := make([]*Subscription, 0)
+	for _, sub := range m.subscribers(event, data) {
+		if active, _ := m.subscriptionState(sub); active {
+			result = append(result, sub)
 		}
 	}
+	return result
 }
 
 // worker обрабатывает webhook из очереди
@@ -214,6 +858,8 @@ func (m *Manager) worker() {
 				"event", job.payload.Event,
 				"error", err,
 			)
+			m.recordFailure(ctx, job.subscription, err)
+			_ = m.saveDeadLetter(ctx, job.subscription, job.payload, nil, err, 3)
 		}
 
 		cancel()
@@ -235,7 +881,7 @@ func (m *Manager) deliverWithRetry(ctx context.Context, sub *Subscription, paylo
 			}
 		}
 
-		err := m.deliverer.Deliver(ctx, sub, payload)
+		err := m.deliver(ctx, sub, payload)
 		if err == nil {
 			return nil
 		}
@@ -252,55 +898,19 @@ func (m *Manager) deliverWithRetry(ctx context.Context, sub *Subscription, paylo
 	return lastErr
 }
 
-// generateSignature генерирует HMAC signature
-func generateSignature(body []byte, secret string) string {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write(body)
-	return hex.EncodeToString(mac.Sum(nil))
-}
-
-// VerifySignature проверяет HMAC signature
-func VerifySignature(body []byte, signature, secret string) bool {
-	expected := generateSignature(body, secret)
-	return hmac.Equal([]byte(signature), []byte(expected))
-}
-
 // Helper функции для создания webhook events
 
 // TriggerPRCreated отправляет событие создания PR
 func (m *Manager) TriggerPRCreated(pr *models.PullRequest) {
-	payload := map[string]interface{}{
-		"pr_id":      pr.ID,
-		"title":      pr.Title,
-		"author_id":  pr.AuthorID,
-		"status":     pr.Status,
-		"reviewers":  pr.Reviewers,
-		"created_at": pr.CreatedAt,
-	}
-
-	// Add team_id if team is available
-	if pr.Team != nil {
-		payload["team_id"] = pr.Team.ID
-	}
-
-	m.Trigger(EventPRCreated, payload)
+	m.Trigger(EventPRCreated, PRCreatedData(pr))
 }
 
 // TriggerPRMerged отправляет событие слияния PR
 func (m *Manager) TriggerPRMerged(pr *models.PullRequest) {
-	m.Trigger(EventPRMerged, map[string]interface{}{
-		"pr_id":     pr.ID,
-		"title":     pr.Title,
-		"author_id": pr.AuthorID,
-		"merged_at": pr.MergedAt,
-	})
+	m.Trigger(EventPRMerged, PRMergedData(pr))
 }
 
 // TriggerReviewerAssigned отправляет событие назначения рецензента
 func (m *Manager) TriggerReviewerAssigned(prID int64, reviewerID int64) {
-	m.Trigger(EventReviewerAssigned, map[string]interface{}{
-		"pr_id":       prID,
-		"reviewer_id": reviewerID,
-		"assigned_at": time.Now(),
-	})
+	m.Trigger(EventReviewerAssigned, ReviewerAssignedData(int(prID), int(reviewerID)))
 }
@@ -0,0 +1,240 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/user/pr-reviewer/internal/circuitbreaker"
+	"github.com/user/pr-reviewer/internal/featureflags"
+	"github.com/user/pr-reviewer/internal/logger"
+)
+
+type countingDeliverer struct {
+	calls int
+	err   error
+}
+
+func (d *countingDeliverer) Deliver(_ context.Context, _ *Subscription, _ *Payload) error {
+	d.calls++
+	return d.err
+}
+
+func newTestManager(t *testing.T, d Deliverer) (*Manager, *featureflags.Manager) {
+	t.Helper()
+	log, err := logger.New("error", "test")
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	flags := featureflags.NewManager(nil, log)
+	m := NewManager(d, log)
+	m.SetFeatureFlags(flags)
+	m.Subscribe(&Subscription{ID: 1, URL: "https://example.com/hook", Events: []EventType{EventPRCreated}, Active: true})
+	return m, flags
+}
+
+func TestManager_WebhooksFlag(t *testing.T) {
+	d := &countingDeliverer{}
+	m, flags := newTestManager(t, d)
+	ctx := context.Background()
+
+	_ = flags.DisableFlag(featureflags.FlagWebhooks)
+	event := &OutboxEvent{ID: 1, Event: EventPRCreated, Data: map[string]interface{}{}}
+	if err := m.HandleEvent(ctx, event); !errors.Is(err, ErrDeliveryDeferred) {
+		t.Fatalf("expected disabled webhooks to defer event, got %v", err)
+	}
+	if d.calls != 0 || event.IsDeliveredTo(1) {
+		t.Error("expected no delivery while webhooks are disabled")
+	}
+	if _, err := m.Replay(ctx, 1); !errors.Is(err, ErrWebhooksDisabled) {
+		t.Errorf("expected ErrWebhooksDisabled on replay, got %v", err)
+	}
+
+	_ = flags.EnableFlag(featureflags.FlagWebhooks)
+	if err := m.HandleEvent(ctx, event); err != nil {
+		t.Fatalf("failed to handle event: %v", err)
+	}
+	if d.calls != 1 || !event.IsDeliveredTo(1) {
+		t.Error("expected event to be delivered after enabling webhooks")
+	}
+}
+
+func TestManager_CircuitBreakerFlag(t *testing.T) {
+	d := &countingDeliverer{err: errors.New("connection refused")}
+	m, flags := newTestManager(t, d)
+	_ = flags.EnableFlag(featureflags.FlagCircuitBreaker)
+	ctx := context.Background()
+	sub := m.subscription(1)
+
+	// Breaker по умолчанию открывается после трех ошибок подряд
+	for i := 0; i < 3; i++ {
+		_ = m.deliver(ctx, sub, &Payload{Event: EventPRCreated})
+	}
+	err := m.deliver(ctx, sub, &Payload{Event: EventPRCreated})
+	if !errors.Is(err, circuitbreaker.ErrOpenState) {
+		t.Fatalf("expected open circuit breaker, got %v", err)
+	}
+	if d.calls != 3 {
+		t.Errorf("expected open breaker to skip delivery, got %d calls", d.calls)
+	}
+
+	_ = flags.DisableFlag(featureflags.FlagCircuitBreaker)
+	_ = m.deliver(ctx, sub, &Payload{Event: EventPRCreated})
+	if d.calls != 4 {
+		t.Error("expected delivery without breaker when circuit_breaker is disabled")
+	}
+}
+
+func TestManager_EnableSubscription(t *testing.T) {
+	d := &countingDeliverer{err: errors.New("connection refused")}
+	m, _ := newTestManager(t, d)
+	m.SetMaxFailureStreak(2)
+	ctx := context.Background()
+	sub := m.subscription(1)
+
+	disable := func() {
+		for i := 0; i < 2; i++ {
+			event := &OutboxEvent{ID: int64(i), Event: EventPRCreated, Data: map[string]interface{}{}}
+			_ = m.HandleEvent(ctx, event)
+			m.HandleExhausted(ctx, event, errors.New("connection refused"))
+		}
+		if active, disabledAt := m.subscriptionState(sub); active || disabledAt == nil {
+			t.Fatal("expected subscription to be disabled after repeated failures")
+		}
+	}
+
+	disable()
+	enabled, err := m.EnableSubscription(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to enable subscription: %v", err)
+	}
+	if !enabled.Active || enabled.FailureStreak != 0 || enabled.DisabledAt != nil {
+		t.Errorf("expected active subscription with reset streak, got %+v", enabled)
+	}
+	if _, err := m.EnableSubscription(ctx, 42); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("expected ErrSubscriptionNotFound, got %v", err)
+	}
+
+	// Успешный replay dead letter включает подписку снова
+	disable()
+	d.err = nil
+	if err := m.deliver(ctx, sub, &Payload{Event: EventPRCreated}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if active, _ := m.subscriptionState(sub); !active {
+		t.Error("expected subscription to be re-enabled after successful delivery")
+	}
+}
+
+func TestManager_FailureStreakCountsEvents(t *testing.T) {
+	d := &countingDeliverer{err: errors.New("connection refused")}
+	m, _ := newTestManager(t, d)
+	m.SetMaxFailureStreak(2)
+	ctx := context.Background()
+	sub := m.subscription(1)
+
+	// Повторные попытки relay одного события не увеличивают серию
+	event := &OutboxEvent{ID: 1, Event: EventPRCreated, Data: map[string]interface{}{}}
+	for i := 0; i < 5; i++ {
+		_ = m.HandleEvent(ctx, event)
+	}
+	if sub.FailureStreak != 0 {
+		t.Fatalf("expected retries not to count, got streak %d", sub.FailureStreak)
+	}
+
+	m.HandleExhausted(ctx, event, d.err)
+	if sub.FailureStreak != 1 || !sub.Active {
+		t.Errorf("expected one failed event, got streak %d, active %v", sub.FailureStreak, sub.Active)
+	}
+
+	// Успешная доставка сбрасывает серию
+	d.err = nil
+	if err := m.HandleEvent(ctx, &OutboxEvent{ID: 2, Event: EventPRCreated, Data: map[string]interface{}{}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub.FailureStreak != 0 {
+		t.Errorf("expected streak to reset after success, got %d", sub.FailureStreak)
+	}
+}
+
+func TestManager_RotateSecret(t *testing.T) {
+	d := &countingDeliverer{err: errors.New("connection refused")}
+	m, _ := newTestManager(t, d)
+	ctx := context.Background()
+	sub := m.subscription(1)
+	sub.Secret = "old"
+
+	m.recordFailure(ctx, sub, d.err)
+	expiresAt, err := m.RotateSecret(ctx, 1, "new", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to rotate secret: %v", err)
+	}
+
+	// Подписка обновлена на месте: серия ошибок доставок не теряется
+	if m.subscription(1) != sub {
+		t.Fatal("expected rotation to update the subscription in place")
+	}
+	if sub.Secret != "new" || sub.PreviousSecret != "old" || !sub.PreviousSecretExpiresAt.Equal(expiresAt) {
+		t.Errorf("unexpected secrets after rotation: %+v", sub)
+	}
+	m.recordFailure(ctx, sub, d.err)
+	if sub.FailureStreak != 2 {
+		t.Errorf("expected failure streak 2, got %d", sub.FailureStreak)
+	}
+
+	if _, err := m.RotateSecret(ctx, 42, "new", time.Hour); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("expected ErrSubscriptionNotFound, got %v", err)
+	}
+}
+
+func TestManager_SetFormat(t *testing.T) {
+	m, _ := newTestManager(t, &countingDeliverer{})
+	ctx := context.Background()
+
+	sub, err := m.SetFormat(ctx, 1, FormatCloudEventsBinary)
+	if err != nil {
+		t.Fatalf("failed to set format: %v", err)
+	}
+	if sub.Format != FormatCloudEventsBinary || m.subscription(1).Format != FormatCloudEventsBinary {
+		t.Errorf("expected format %s, got %s", FormatCloudEventsBinary, m.subscription(1).Format)
+	}
+
+	if _, err := m.SetFormat(ctx, 1, "cloudevent"); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("expected ErrInvalidFormat, got %v", err)
+	}
+	if _, err := m.SetFormat(ctx, 42, FormatPayload); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("expected ErrSubscriptionNotFound, got %v", err)
+	}
+}
+
+func TestHTTPDeliverer_TimestampMatchesSignature(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	log, _ := logger.New("error", "test")
+	d := NewHTTPDeliverer(log)
+	sub := &Subscription{ID: 1, URL: server.URL, Secret: "secret"}
+	payload := &Payload{Event: EventPRCreated, Timestamp: time.Now().Add(-time.Hour)}
+	if err := d.Deliver(context.Background(), sub, payload); err != nil {
+		t.Fatalf("failed to deliver: %v", err)
+	}
+
+	sent, err := time.Parse(time.RFC3339, header.Get("X-Webhook-Timestamp"))
+	if err != nil {
+		t.Fatalf("invalid timestamp header: %v", err)
+	}
+	signedAt, _, err := parseSignatureHeader(header.Get(SignatureHeader))
+	if err != nil {
+		t.Fatalf("invalid signature header: %v", err)
+	}
+	if sent.Unix() != signedAt {
+		t.Errorf("expected timestamp header %d to match signature t=%d", sent.Unix(), signedAt)
+	}
+}
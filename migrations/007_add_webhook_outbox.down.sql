@@ -0,0 +1,2 @@
+-- Удаление transactional outbox
+DROP TABLE IF EXISTS webhook_outbox CASCADE;
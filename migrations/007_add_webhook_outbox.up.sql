@@ -0,0 +1,25 @@
+-- Transactional outbox для надёжной доставки событий
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    aggregate_key VARCHAR(100) NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, processed, failed
+    attempts INT NOT NULL DEFAULT 0,
+    delivered_to BIGINT[] NOT NULL DEFAULT '{}',
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    processed_at TIMESTAMP
+);
+
+-- Индексы
+CREATE INDEX idx_webhook_outbox_pending ON webhook_outbox(next_attempt_at, id)
+    WHERE status = 'pending';
+CREATE INDEX idx_webhook_outbox_aggregate ON webhook_outbox(aggregate_key, id)
+    WHERE status = 'pending';
+
+-- Комментарии
+COMMENT ON TABLE webhook_outbox IS 'Transactional outbox: события пишутся в одной транзакции с изменением данных';
+COMMENT ON COLUMN webhook_outbox.aggregate_key IS 'Ключ упорядочивания, например pr:42 или user:7';
+COMMENT ON COLUMN webhook_outbox.delivered_to IS 'ID подписок, которым событие уже доставлено';
@@ -0,0 +1,7 @@
+-- Удаление dead letters
+DROP TABLE IF EXISTS webhook_dead_letters CASCADE;
+
+-- Удаление столбцов
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS disabled_reason;
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS disabled_at;
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS failure_streak;
@@ -0,0 +1,26 @@
+-- Состояние подписок для автоотключения
+ALTER TABLE webhook_subscriptions ADD COLUMN failure_streak INT NOT NULL DEFAULT 0;
+ALTER TABLE webhook_subscriptions ADD COLUMN disabled_at TIMESTAMP;
+ALTER TABLE webhook_subscriptions ADD COLUMN disabled_reason TEXT;
+
+-- Dead letters: доставки, исчерпавшие все попытки
+CREATE TABLE IF NOT EXISTS webhook_dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL,
+    outbox_id BIGINT,
+    event VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    error TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    replayed_at TIMESTAMP
+);
+
+-- Индексы
+CREATE INDEX idx_webhook_dead_letters_subscription ON webhook_dead_letters(subscription_id, created_at);
+CREATE INDEX idx_webhook_dead_letters_pending ON webhook_dead_letters(created_at DESC)
+    WHERE replayed_at IS NULL;
+
+-- Комментарии
+COMMENT ON TABLE webhook_dead_letters IS 'Webhook доставки, исчерпавшие попытки; доступны для ручного replay';
+COMMENT ON COLUMN webhook_subscriptions.failure_streak IS 'Количество неудачных доставок подряд';
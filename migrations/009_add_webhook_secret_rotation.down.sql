@@ -0,0 +1,3 @@
+-- Удаление столбцов ротации секретов
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS previous_secret_expires_at;
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS previous_secret;
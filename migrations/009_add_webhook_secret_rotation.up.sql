@@ -0,0 +1,7 @@
+-- Ротация секретов: во время grace периода подпись считается обоими секретами
+ALTER TABLE webhook_subscriptions ADD COLUMN previous_secret VARCHAR(255);
+ALTER TABLE webhook_subscriptions ADD COLUMN previous_secret_expires_at TIMESTAMP;
+
+-- Комментарии
+COMMENT ON COLUMN webhook_subscriptions.previous_secret IS 'Предыдущий секрет, действует до previous_secret_expires_at';
+COMMENT ON COLUMN webhook_subscriptions.previous_secret_expires_at IS 'Окончание grace периода ротации секрета';
@@ -0,0 +1,2 @@
+-- Удаление формата webhook
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS format;
@@ -0,0 +1,5 @@
+-- Формат тела webhook: payload, cloudevents, cloudevents-binary
+ALTER TABLE webhook_subscriptions ADD COLUMN format VARCHAR(30) NOT NULL DEFAULT 'payload';
+
+-- Комментарии
+COMMENT ON COLUMN webhook_subscriptions.format IS 'Формат доставки: payload, cloudevents (structured), cloudevents-binary';
@@ -0,0 +1,2 @@
+-- Удаление фильтров подписок
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS filter;
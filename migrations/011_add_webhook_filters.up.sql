@@ -0,0 +1,5 @@
+-- Фильтры подписок: команды, авторы, рецензенты, метки, репозиторий
+ALTER TABLE webhook_subscriptions ADD COLUMN filter JSONB;
+
+-- Комментарии
+COMMENT ON COLUMN webhook_subscriptions.filter IS 'Фильтр событий: {"team_ids": [], "author_ids": [], "reviewer_ids": [], "labels": [], "repository": ""}';
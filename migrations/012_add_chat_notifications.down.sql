@@ -0,0 +1,4 @@
+-- Удаление столбцов чат-уведомлений
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS template;
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS target;
+ALTER TABLE users DROP COLUMN IF EXISTS chat_handle;
@@ -0,0 +1,11 @@
+-- Ник пользователя в чате для упоминаний (Slack member ID или Mattermost username)
+ALTER TABLE users ADD COLUMN chat_handle VARCHAR(100);
+
+-- Тип получателя подписки и шаблон сообщения для чатов
+ALTER TABLE webhook_subscriptions ADD COLUMN target VARCHAR(30) NOT NULL DEFAULT 'webhook';
+ALTER TABLE webhook_subscriptions ADD COLUMN template TEXT;
+
+-- Комментарии
+COMMENT ON COLUMN users.chat_handle IS 'Ник для упоминаний в чате: Slack member ID или Mattermost username';
+COMMENT ON COLUMN webhook_subscriptions.target IS 'Получатель: webhook, slack, mattermost';
+COMMENT ON COLUMN webhook_subscriptions.template IS 'Go text/template текста сообщения для чатов';
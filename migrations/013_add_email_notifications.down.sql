@@ -0,0 +1,5 @@
+-- Удаление настроек уведомлений
+DROP TABLE IF EXISTS user_notification_preferences CASCADE;
+
+-- Удаление email пользователя
+ALTER TABLE users DROP COLUMN IF EXISTS email;
@@ -0,0 +1,21 @@
+-- Email пользователя для уведомлений
+ALTER TABLE users ADD COLUMN email VARCHAR(255);
+
+-- Настройки уведомлений пользователя (opt-in: по умолчанию все выключено)
+CREATE TABLE IF NOT EXISTS user_notification_preferences (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    email_assignments BOOLEAN NOT NULL DEFAULT false,
+    email_digest BOOLEAN NOT NULL DEFAULT false,
+    last_digest_on DATE,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Индексы
+CREATE INDEX idx_user_notification_preferences_digest ON user_notification_preferences(user_id)
+    WHERE email_digest = true;
+
+-- Комментарии
+COMMENT ON TABLE user_notification_preferences IS 'Настройки email уведомлений пользователей';
+COMMENT ON COLUMN user_notification_preferences.email_assignments IS 'Письмо при назначении/переназначении рецензентом';
+COMMENT ON COLUMN user_notification_preferences.email_digest IS 'Ежедневный дайджест ожидающих ревью';
+COMMENT ON COLUMN user_notification_preferences.last_digest_on IS 'Дата последнего отправленного дайджеста (защита от повторной отправки)';
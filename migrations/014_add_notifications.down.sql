@@ -0,0 +1,5 @@
+-- Удаление настроек inbox
+ALTER TABLE user_notification_preferences DROP COLUMN IF EXISTS inbox_events;
+
+-- Удаление уведомлений
+DROP TABLE IF EXISTS notifications CASCADE;
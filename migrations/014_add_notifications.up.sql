@@ -0,0 +1,28 @@
+-- Уведомления в приложении (inbox)
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    pr_id INTEGER REFERENCES pull_requests(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    outbox_id BIGINT,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Outbox доставляет события at-least-once: повторная обработка не создает дубликатов
+CREATE UNIQUE INDEX idx_notifications_outbox ON notifications(user_id, outbox_id, event)
+    WHERE outbox_id IS NOT NULL;
+
+-- Индексы
+CREATE INDEX idx_notifications_user ON notifications(user_id, id DESC);
+CREATE INDEX idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+CREATE INDEX idx_notifications_created ON notifications(created_at);
+
+-- Типы событий, для которых пользователь получает уведомления (NULL - все)
+ALTER TABLE user_notification_preferences ADD COLUMN inbox_events TEXT[];
+
+-- Комментарии
+COMMENT ON TABLE notifications IS 'Уведомления пользователей в приложении';
+COMMENT ON COLUMN user_notification_preferences.inbox_events IS 'События для inbox уведомлений; NULL - все события';
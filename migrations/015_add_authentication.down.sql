@@ -0,0 +1,6 @@
+-- Удаление refresh токенов
+DROP TABLE IF EXISTS refresh_tokens CASCADE;
+
+-- Удаление пароля и роли пользователя
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+ALTER TABLE users DROP COLUMN IF EXISTS password_hash;
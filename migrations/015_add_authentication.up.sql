@@ -0,0 +1,28 @@
+-- Пароль (bcrypt) и роль пользователя
+ALTER TABLE users ADD COLUMN password_hash VARCHAR(255);
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'member'
+    CHECK (role IN ('admin', 'team_lead', 'member', 'readonly'));
+
+-- Refresh токены: хранится только SHA-256 хеш, при обновлении токен ротируется
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    family_id UUID NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    replaced_by BIGINT REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+    user_agent TEXT,
+    ip VARCHAR(45),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Индексы
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+
+-- Комментарии
+COMMENT ON TABLE refresh_tokens IS 'Refresh токены сессий (хранятся в виде хеша)';
+COMMENT ON COLUMN refresh_tokens.family_id IS 'Цепочка ротаций одной сессии: повторное использование отозванного токена отзывает всю цепочку';
+COMMENT ON COLUMN refresh_tokens.replaced_by IS 'Токен, выданный взамен при ротации';
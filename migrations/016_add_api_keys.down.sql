@@ -0,0 +1,3 @@
+-- Удаление API ключей и сервисных аккаунтов
+DROP TABLE IF EXISTS api_keys CASCADE;
+DROP TABLE IF EXISTS service_accounts CASCADE;
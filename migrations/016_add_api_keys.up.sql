@@ -0,0 +1,39 @@
+-- Сервисные аккаунты (CI боты и интеграции)
+CREATE TABLE IF NOT EXISTS service_accounts (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT,
+    role VARCHAR(20) NOT NULL DEFAULT 'member'
+        CHECK (role IN ('admin', 'team_lead', 'member', 'readonly')),
+    team_id INTEGER REFERENCES teams(id) ON DELETE SET NULL,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- API ключи сервисных аккаунтов: хранится только SHA-256 хеш
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    service_account_id INTEGER NOT NULL REFERENCES service_accounts(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Индексы
+CREATE INDEX idx_api_keys_service_account_id ON api_keys(service_account_id);
+
+-- Триггер для обновления updated_at
+CREATE TRIGGER update_service_accounts_updated_at BEFORE UPDATE ON service_accounts
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Комментарии
+COMMENT ON TABLE service_accounts IS 'Сервисные аккаунты для доступа по API ключам';
+COMMENT ON TABLE api_keys IS 'API ключи (prr_...), хранятся в виде хеша';
+COMMENT ON COLUMN api_keys.prefix IS 'Начало ключа для отображения в списке ключей';
+COMMENT ON COLUMN api_keys.scopes IS 'Разрешенные области доступа, например pr:write, stats:read';
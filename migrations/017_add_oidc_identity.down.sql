@@ -0,0 +1,4 @@
+-- Удаление привязки к IdP
+DROP INDEX IF EXISTS idx_users_oidc_identity;
+ALTER TABLE users DROP COLUMN IF EXISTS oidc_subject;
+ALTER TABLE users DROP COLUMN IF EXISTS oidc_issuer;
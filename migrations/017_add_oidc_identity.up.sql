@@ -0,0 +1,10 @@
+-- Учетная запись корпоративного IdP (OpenID Connect): issuer + subject
+ALTER TABLE users ADD COLUMN oidc_issuer VARCHAR(255);
+ALTER TABLE users ADD COLUMN oidc_subject VARCHAR(255);
+
+-- Индексы
+CREATE UNIQUE INDEX idx_users_oidc_identity ON users(oidc_issuer, oidc_subject)
+    WHERE oidc_subject IS NOT NULL;
+
+-- Комментарии
+COMMENT ON COLUMN users.oidc_subject IS 'Claim sub ID токена IdP, к которому привязан пользователь';
@@ -0,0 +1,2 @@
+-- Удаление ключей подписи JWT
+DROP TABLE IF EXISTS jwt_signing_keys;
@@ -0,0 +1,17 @@
+-- Ключи подписи access токенов (RS256/EdDSA), общие для всех реплик
+CREATE TABLE IF NOT EXISTS jwt_signing_keys (
+    id VARCHAR(64) PRIMARY KEY,
+    algorithm VARCHAR(16) NOT NULL CHECK (algorithm IN ('RS256', 'EdDSA')),
+    private_key TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    retired_at TIMESTAMP
+);
+
+-- Индексы
+CREATE INDEX idx_jwt_signing_keys_created_at ON jwt_signing_keys(created_at DESC);
+
+-- Комментарии
+COMMENT ON TABLE jwt_signing_keys IS 'Ключи подписи JWT; публичные части отдаются в /.well-known/jwks.json';
+COMMENT ON COLUMN jwt_signing_keys.id IS 'Идентификатор ключа (kid в заголовке токена)';
+COMMENT ON COLUMN jwt_signing_keys.private_key IS 'Приватный ключ в PKCS#8 PEM';
+COMMENT ON COLUMN jwt_signing_keys.retired_at IS 'Время вывода из работы; токены принимаются до конца grace периода';
@@ -0,0 +1,3 @@
+-- Удаление автора-сервисного аккаунта из audit log
+DROP INDEX IF EXISTS idx_audit_logs_service_account_id;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS service_account_id;
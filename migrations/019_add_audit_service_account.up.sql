@@ -0,0 +1,10 @@
+-- Автор изменения в audit log может быть сервисным аккаунтом (запрос по API ключу)
+ALTER TABLE audit_logs ADD COLUMN service_account_id BIGINT;
+
+-- Индексы
+CREATE INDEX idx_audit_logs_service_account_id ON audit_logs(service_account_id)
+    WHERE service_account_id IS NOT NULL;
+
+-- Комментарии
+COMMENT ON COLUMN audit_logs.service_account_id IS 'Сервисный аккаунт, выполнивший действие по API ключу';
+COMMENT ON COLUMN audit_logs.changes IS 'Разница до/после: {"поле": {"old": ..., "new": ...}}';
@@ -0,0 +1,4 @@
+-- Удаление цепочки хешей audit log
+DROP TABLE IF EXISTS audit_chain;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS hash;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS prev_hash;
@@ -0,0 +1,22 @@
+-- Цепочка хешей audit log: каждая запись хранит SHA-256 своего содержимого
+-- вместе с хешем предыдущей записи, поэтому изменение или удаление записи
+-- обнаруживается проверкой цепочки. Записи до миграции остаются без хеша.
+ALTER TABLE audit_logs ADD COLUMN prev_hash VARCHAR(64);
+ALTER TABLE audit_logs ADD COLUMN hash VARCHAR(64);
+
+-- Голова цепочки: последняя запись и ее хеш. Строка блокируется при каждой
+-- записи в audit log, что упорядочивает цепочку между репликами, и позволяет
+-- обнаружить удаление последних записей.
+CREATE TABLE IF NOT EXISTS audit_chain (
+    id SMALLINT PRIMARY KEY CHECK (id = 1),
+    last_id BIGINT,
+    last_hash VARCHAR(64) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO audit_chain (id) VALUES (1) ON CONFLICT DO NOTHING;
+
+-- Комментарии
+COMMENT ON COLUMN audit_logs.prev_hash IS 'Хеш предыдущей записи цепочки, пустой у первой записи';
+COMMENT ON COLUMN audit_logs.hash IS 'SHA-256 содержимого записи и prev_hash (hex)';
+COMMENT ON TABLE audit_chain IS 'Голова цепочки хешей audit log';
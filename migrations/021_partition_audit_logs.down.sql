@@ -0,0 +1,41 @@
+-- Возврат audit_logs к обычной таблице. Архивированные партиции не восстанавливаются.
+DROP TABLE IF EXISTS audit_archives;
+
+ALTER TABLE audit_logs RENAME TO audit_logs_partitioned;
+ALTER INDEX audit_logs_pkey RENAME TO audit_logs_partitioned_pkey;
+
+CREATE TABLE audit_logs (
+    id BIGINT PRIMARY KEY DEFAULT nextval('audit_logs_id_seq'),
+    timestamp TIMESTAMP NOT NULL DEFAULT NOW(),
+    action VARCHAR(50) NOT NULL,
+    entity VARCHAR(50) NOT NULL,
+    entity_id BIGINT NOT NULL,
+    user_id BIGINT,
+    user_email VARCHAR(255),
+    ip VARCHAR(45),
+    user_agent TEXT,
+    request_id VARCHAR(100),
+    changes JSONB,
+    description TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    service_account_id BIGINT,
+    prev_hash VARCHAR(64),
+    hash VARCHAR(64)
+);
+
+ALTER SEQUENCE audit_logs_id_seq OWNED BY audit_logs.id;
+
+INSERT INTO audit_logs SELECT
+    id, timestamp, action, entity, entity_id, user_id, user_email, ip, user_agent,
+    request_id, changes, description, created_at, service_account_id, prev_hash, hash
+FROM audit_logs_partitioned;
+
+DROP TABLE audit_logs_partitioned;
+
+CREATE INDEX idx_audit_logs_timestamp ON audit_logs(timestamp DESC);
+CREATE INDEX idx_audit_logs_user_id ON audit_logs(user_id) WHERE user_id IS NOT NULL;
+CREATE INDEX idx_audit_logs_entity ON audit_logs(entity, entity_id);
+CREATE INDEX idx_audit_logs_action ON audit_logs(action);
+CREATE INDEX idx_audit_logs_request_id ON audit_logs(request_id) WHERE request_id IS NOT NULL;
+CREATE INDEX idx_audit_logs_service_account_id ON audit_logs(service_account_id)
+    WHERE service_account_id IS NOT NULL;
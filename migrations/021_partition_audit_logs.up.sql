@@ -0,0 +1,94 @@
+-- Партиционирование audit_logs по месяцам (RANGE по timestamp).
+-- Новые партиции создает и старые архивирует audit.Retention; партиция
+-- по умолчанию принимает записи, для месяца которых партиции еще нет.
+ALTER TABLE audit_logs RENAME TO audit_logs_legacy;
+ALTER INDEX audit_logs_pkey RENAME TO audit_logs_legacy_pkey;
+
+CREATE TABLE audit_logs (
+    id BIGINT NOT NULL DEFAULT nextval('audit_logs_id_seq'),
+    timestamp TIMESTAMP NOT NULL DEFAULT NOW(),
+    action VARCHAR(50) NOT NULL,
+    entity VARCHAR(50) NOT NULL,
+    entity_id BIGINT NOT NULL,
+    user_id BIGINT,
+    user_email VARCHAR(255),
+    ip VARCHAR(45),
+    user_agent TEXT,
+    request_id VARCHAR(100),
+    changes JSONB,
+    description TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    service_account_id BIGINT,
+    prev_hash VARCHAR(64),
+    hash VARCHAR(64),
+    -- Ключ партиционированной таблицы должен включать колонку партиционирования
+    PRIMARY KEY (id, timestamp)
+) PARTITION BY RANGE (timestamp);
+
+-- Последовательность переходит к новой таблице, иначе удалится вместе со старой
+ALTER SEQUENCE audit_logs_id_seq OWNED BY audit_logs.id;
+
+CREATE TABLE audit_logs_default PARTITION OF audit_logs DEFAULT;
+
+-- Партиции для существующих записей и на два месяца вперед
+DO $$
+DECLARE
+    month_start TIMESTAMP;
+BEGIN
+    FOR month_start IN
+        SELECT generate_series(
+            date_trunc('month', COALESCE((SELECT MIN(timestamp) FROM audit_logs_legacy), NOW())),
+            date_trunc('month', NOW()) + INTERVAL '2 months',
+            INTERVAL '1 month')
+    LOOP
+        EXECUTE format(
+            'CREATE TABLE IF NOT EXISTS %I PARTITION OF audit_logs FOR VALUES FROM (%L) TO (%L)',
+            'audit_logs_' || to_char(month_start, 'YYYY_MM'),
+            month_start,
+            month_start + INTERVAL '1 month');
+    END LOOP;
+END $$;
+
+INSERT INTO audit_logs (
+    id, timestamp, action, entity, entity_id, user_id, user_email, ip, user_agent,
+    request_id, changes, description, created_at, service_account_id, prev_hash, hash
+)
+SELECT
+    id, timestamp, action, entity, entity_id, user_id, user_email, ip, user_agent,
+    request_id, changes, description, created_at, service_account_id, prev_hash, hash
+FROM audit_logs_legacy;
+
+DROP TABLE audit_logs_legacy;
+
+-- Индексы создаются на каждой партиции
+CREATE INDEX idx_audit_logs_timestamp ON audit_logs(timestamp DESC);
+CREATE INDEX idx_audit_logs_user_id ON audit_logs(user_id) WHERE user_id IS NOT NULL;
+CREATE INDEX idx_audit_logs_entity ON audit_logs(entity, entity_id);
+CREATE INDEX idx_audit_logs_action ON audit_logs(action);
+CREATE INDEX idx_audit_logs_request_id ON audit_logs(request_id) WHERE request_id IS NOT NULL;
+CREATE INDEX idx_audit_logs_service_account_id ON audit_logs(service_account_id)
+    WHERE service_account_id IS NOT NULL;
+
+-- Архивы отсоединенных партиций. Хеш последней записи архива - начало
+-- цепочки для записей, оставшихся в БД.
+CREATE TABLE IF NOT EXISTS audit_archives (
+    id BIGSERIAL PRIMARY KEY,
+    partition_name VARCHAR(63) NOT NULL UNIQUE,
+    period_start TIMESTAMP NOT NULL,
+    period_end TIMESTAMP NOT NULL,
+    file_path TEXT NOT NULL,
+    entries BIGINT NOT NULL,
+    last_id BIGINT,
+    last_hash VARCHAR(64) NOT NULL DEFAULT '',
+    archived_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Комментарии
+COMMENT ON TABLE audit_logs IS 'Audit log для отслеживания всех действий пользователей, партиции по месяцам';
+COMMENT ON COLUMN audit_logs.action IS 'Тип действия: create, update, delete, read, login, logout';
+COMMENT ON COLUMN audit_logs.entity IS 'Тип сущности: user, team, pull_request, reviewer, service_account';
+COMMENT ON COLUMN audit_logs.changes IS 'Разница до/после: {"поле": {"old": ..., "new": ...}}';
+COMMENT ON COLUMN audit_logs.service_account_id IS 'Сервисный аккаунт, выполнивший действие по API ключу';
+COMMENT ON COLUMN audit_logs.prev_hash IS 'Хеш предыдущей записи цепочки, пустой у первой записи';
+COMMENT ON COLUMN audit_logs.hash IS 'SHA-256 содержимого записи и prev_hash (hex)';
+COMMENT ON TABLE audit_archives IS 'Партиции audit log, выгруженные в NDJSON архивы';
@@ -0,0 +1,2 @@
+-- Удаление хранимых feature flags
+DROP TABLE IF EXISTS feature_flags;
@@ -0,0 +1,14 @@
+-- Feature flags, измененные через admin API. Переопределяют значения по
+-- умолчанию из кода; изменения рассылаются репликам через NOTIFY feature_flags.
+CREATE TABLE IF NOT EXISTS feature_flags (
+    key VARCHAR(100) PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    description TEXT NOT NULL DEFAULT '',
+    rollout JSONB,
+    metadata JSONB,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Комментарии
+COMMENT ON TABLE feature_flags IS 'Feature flags, переопределенные через /admin/flags';
+COMMENT ON COLUMN feature_flags.rollout IS 'Постепенный раскат: процент, пользователи, команды';
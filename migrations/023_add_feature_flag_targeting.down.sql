@@ -0,0 +1,4 @@
+-- Удаление правил таргетинга и вариантов feature flags
+ALTER TABLE feature_flags DROP COLUMN IF EXISTS default_variant;
+ALTER TABLE feature_flags DROP COLUMN IF EXISTS variants;
+ALTER TABLE feature_flags DROP COLUMN IF EXISTS rules;
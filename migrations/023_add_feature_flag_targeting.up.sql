@@ -0,0 +1,8 @@
+-- Правила таргетинга и варианты feature flags
+ALTER TABLE feature_flags ADD COLUMN rules JSONB;
+ALTER TABLE feature_flags ADD COLUMN variants JSONB;
+ALTER TABLE feature_flags ADD COLUMN default_variant VARCHAR(100) NOT NULL DEFAULT '';
+
+-- Комментарии
+COMMENT ON COLUMN feature_flags.rules IS 'Правила таргетинга по порядку: [{"attribute", "operator", "value"/"values", "variant"}]';
+COMMENT ON COLUMN feature_flags.variants IS 'Значения вариантов флага: {"имя": JSON значение}';
@@ -0,0 +1,5 @@
+-- Удаление доставок outbox по обработчикам; распределенные события
+-- возвращаются в очередь
+UPDATE webhook_outbox SET status = 'pending' WHERE status = 'dispatched';
+DROP TABLE IF EXISTS webhook_outbox_deliveries;
+COMMENT ON COLUMN webhook_outbox.status IS NULL;
@@ -0,0 +1,30 @@
+-- Доставки событий outbox обработчикам relay (webhooks, inbox, stream, email).
+-- У каждого обработчика своя очередь по aggregate_key: ошибка одного
+-- обработчика не задерживает остальные. Relay захватывает доставку арендой
+-- (locked_until, claimed_by) и выполняет ее вне транзакции.
+CREATE TABLE IF NOT EXISTS webhook_outbox_deliveries (
+    outbox_id BIGINT NOT NULL REFERENCES webhook_outbox(id) ON DELETE CASCADE,
+    handler VARCHAR(50) NOT NULL,
+    aggregate_key VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, processed, failed
+    attempts INT NOT NULL DEFAULT 0,
+    delivered_to BIGINT[] NOT NULL DEFAULT '{}',
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP,
+    claimed_by VARCHAR(100),
+    processed_at TIMESTAMP,
+    PRIMARY KEY (outbox_id, handler)
+);
+
+-- Индексы
+CREATE INDEX idx_webhook_outbox_deliveries_pending ON webhook_outbox_deliveries(next_attempt_at, outbox_id)
+    WHERE status = 'pending';
+CREATE INDEX idx_webhook_outbox_deliveries_queue ON webhook_outbox_deliveries(handler, aggregate_key, outbox_id)
+    WHERE status = 'pending';
+
+-- Комментарии
+COMMENT ON TABLE webhook_outbox_deliveries IS 'Доставка события outbox одному обработчику relay';
+COMMENT ON COLUMN webhook_outbox_deliveries.locked_until IS 'Аренда доставки: до этого времени доставку выполняет claimed_by';
+COMMENT ON COLUMN webhook_outbox_deliveries.claimed_by IS 'Экземпляр relay, захвативший доставку';
+COMMENT ON COLUMN webhook_outbox.status IS 'pending - ждет распределения по обработчикам, dispatched - доставляется, processed, failed';
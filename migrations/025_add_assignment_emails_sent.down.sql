@@ -0,0 +1,2 @@
+-- Удаление журнала отправленных писем о назначении
+DROP TABLE IF EXISTS assignment_emails_sent;
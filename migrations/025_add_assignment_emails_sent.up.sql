@@ -0,0 +1,11 @@
+-- Отправленные письма о назначении рецензентом: повторная доставка события
+-- outbox не отправляет письмо второй раз
+CREATE TABLE IF NOT EXISTS assignment_emails_sent (
+    outbox_id BIGINT NOT NULL REFERENCES webhook_outbox(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sent_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (outbox_id, user_id)
+);
+
+-- Комментарии
+COMMENT ON TABLE assignment_emails_sent IS 'Письма о назначении, отправленные по событию outbox (защита от повторной отправки)';
@@ -0,0 +1,6 @@
+-- Удаление репозитория и меток PR
+DROP INDEX IF EXISTS idx_pull_requests_repository;
+
+ALTER TABLE pull_requests
+    DROP COLUMN IF EXISTS labels,
+    DROP COLUMN IF EXISTS repository;
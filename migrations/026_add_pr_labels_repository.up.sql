@@ -0,0 +1,10 @@
+-- Репозиторий и метки PR: по ним фильтруются webhook подписки
+ALTER TABLE pull_requests
+    ADD COLUMN IF NOT EXISTS repository VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS labels TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_pull_requests_repository ON pull_requests(repository);
+
+-- Комментарии
+COMMENT ON COLUMN pull_requests.repository IS 'Репозиторий PR (owner/name)';
+COMMENT ON COLUMN pull_requests.labels IS 'Метки PR';
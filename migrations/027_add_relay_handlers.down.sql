@@ -0,0 +1,2 @@
+-- Удаление реестра обработчиков relay
+DROP TABLE IF EXISTS webhook_relay_handlers;
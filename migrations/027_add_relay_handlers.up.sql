@@ -0,0 +1,18 @@
+-- Обработчики relay, зарегистрированные хотя бы одной репликой.
+-- Доставки новых событий создаются для всех обработчиков из реестра, а не
+-- только для обработчиков реплики, которая распределяет событие: иначе
+-- событие терялось бы для обработчика, подключенного не на всех репликах.
+CREATE TABLE IF NOT EXISTS webhook_relay_handlers (
+    name VARCHAR(50) PRIMARY KEY,
+    registered_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Обработчики, у которых уже есть доставки
+INSERT INTO webhook_relay_handlers (name)
+SELECT DISTINCT handler FROM webhook_outbox_deliveries
+ON CONFLICT DO NOTHING;
+
+-- Комментарии
+COMMENT ON TABLE webhook_relay_handlers IS 'Реестр обработчиков relay; удаленный из кода обработчик нужно удалить и отсюда';
+COMMENT ON COLUMN webhook_relay_handlers.last_seen_at IS 'Последний запуск реплики с этим обработчиком';
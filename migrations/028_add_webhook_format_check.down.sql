@@ -0,0 +1,3 @@
+-- Удаление ограничения формата webhook
+ALTER TABLE webhook_subscriptions
+DROP CONSTRAINT IF EXISTS webhook_subscriptions_format_check;
@@ -0,0 +1,5 @@
+-- Формат webhook ограничен поддерживаемыми значениями: опечатка
+-- (например, cloudevent) раньше молча отправляла обычный payload
+ALTER TABLE webhook_subscriptions
+ADD CONSTRAINT webhook_subscriptions_format_check
+CHECK (format IN ('payload', 'cloudevents', 'cloudevents-binary'));
@@ -0,0 +1,2 @@
+-- Откат описания колонки; зашифрованные ключи остаются зашифрованными
+COMMENT ON COLUMN jwt_signing_keys.private_key IS 'Приватный ключ в PKCS#8 PEM';
@@ -0,0 +1,3 @@
+-- Приватные ключи подписи хранятся зашифрованными ключом JWT_KEY_ENCRYPTION_KEY.
+-- Ключи, сохраненные открытым PEM, сервис шифрует при старте.
+COMMENT ON COLUMN jwt_signing_keys.private_key IS 'Приватный ключ в PKCS#8 PEM, зашифрованный AES-256-GCM (префикс enc:v1:)';
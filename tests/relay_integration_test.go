@@ -0,0 +1,213 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/pr-reviewer/internal/logger"
+	"github.com/user/pr-reviewer/internal/webhook"
+)
+
+// recordingHandler запоминает порядок доставленных событий
+type recordingHandler struct {
+	mu  sync.Mutex
+	ids []int64
+	err error
+}
+
+func (h *recordingHandler) HandleEvent(_ context.Context, event *webhook.OutboxEvent) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ids = append(h.ids, event.ID)
+	return h.err
+}
+
+func (h *recordingHandler) delivered() []int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]int64(nil), h.ids...)
+}
+
+func newIntegrationRelay(t *testing.T) *webhook.Relay {
+	t.Helper()
+	log, err := logger.New("error", "test")
+	require.NoError(t, err)
+
+	cfg := webhook.DefaultRelayConfig()
+	cfg.BaseBackoff = time.Millisecond
+	cfg.DeferDelay = time.Hour
+	return webhook.NewRelay(testDB.DB, cfg, log)
+}
+
+func cleanupOutbox(t *testing.T) {
+	t.Helper()
+	_, err := testDB.Exec("TRUNCATE TABLE webhook_outbox, webhook_outbox_deliveries, webhook_relay_handlers RESTART IDENTITY CASCADE")
+	require.NoError(t, err)
+}
+
+// drain выполняет пачки, пока relay находит доставки
+func drain(t *testing.T, relays ...*webhook.Relay) {
+	t.Helper()
+	for i := 0; i < 20; i++ {
+		total := 0
+		for _, r := range relays {
+			n, err := r.ProcessBatch(context.Background())
+			require.NoError(t, err)
+			total += n
+		}
+		if total == 0 {
+			return
+		}
+	}
+	t.Fatal("relay did not drain the outbox")
+}
+
+func enqueue(t *testing.T, key string, n int) []int64 {
+	t.Helper()
+	log, err := logger.New("error", "test")
+	require.NoError(t, err)
+	outbox := webhook.NewOutbox(testDB.DB, log)
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, outbox.Enqueue(context.Background(), nil, webhook.EventPRCreated, key, map[string]interface{}{"pr_id": i}))
+	}
+
+	rows, err := testDB.Query("SELECT id FROM webhook_outbox WHERE aggregate_key = $1 ORDER BY id", key)
+	require.NoError(t, err)
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestRelay_DeliversInOrderPerAggregate(t *testing.T) {
+	cleanupOutbox(t)
+	defer cleanupOutbox(t)
+
+	h := &recordingHandler{}
+	r := newIntegrationRelay(t)
+	r.AddHandler("recorder", h)
+
+	ids := enqueue(t, "pr:1", 3)
+	drain(t, r)
+
+	assert.Equal(t, ids, h.delivered())
+
+	var pending int
+	require.NoError(t, testDB.QueryRow("SELECT COUNT(*) FROM webhook_outbox WHERE status <> 'processed'").Scan(&pending))
+	assert.Zero(t, pending)
+}
+
+func TestRelay_WaitsForUndispatchedEarlierEvent(t *testing.T) {
+	cleanupOutbox(t)
+	defer cleanupOutbox(t)
+
+	h := &recordingHandler{}
+	r := newIntegrationRelay(t)
+	r.AddHandler("recorder", h)
+	ids := enqueue(t, "pr:2", 2)
+
+	// Первое событие заблокировано fanOut другой реплики: этот fanOut
+	// пропустит его (SKIP LOCKED) и распределит только второе
+	tx, err := testDB.Begin()
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback() }()
+	_, err = tx.Exec("SELECT id FROM webhook_outbox WHERE id = $1 FOR UPDATE", ids[0])
+	require.NoError(t, err)
+
+	n, err := r.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n, "expected second event to wait for the first one")
+	assert.Empty(t, h.delivered())
+
+	require.NoError(t, tx.Rollback())
+	drain(t, r)
+	assert.Equal(t, ids, h.delivered())
+}
+
+func TestRelay_FansOutToHandlersOfOtherReplicas(t *testing.T) {
+	cleanupOutbox(t)
+	defer cleanupOutbox(t)
+
+	inbox := &recordingHandler{}
+	email := &recordingHandler{}
+	withoutEmail := newIntegrationRelay(t)
+	withoutEmail.AddHandler("inbox", inbox)
+	withEmail := newIntegrationRelay(t)
+	withEmail.AddHandler("email", email)
+
+	// Обе реплики зарегистрировали обработчики до появления события
+	_, err := withEmail.ProcessBatch(context.Background())
+	require.NoError(t, err)
+
+	ids := enqueue(t, "pr:3", 1)
+	// Событие распределяет реплика без обработчика email
+	_, err = withoutEmail.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	drain(t, withEmail, withoutEmail)
+
+	assert.Equal(t, ids, inbox.delivered())
+	assert.Equal(t, ids, email.delivered())
+}
+
+func TestRelay_DeferredDeliveryKeepsAttempts(t *testing.T) {
+	cleanupOutbox(t)
+	defer cleanupOutbox(t)
+
+	h := &recordingHandler{err: webhook.ErrDeliveryDeferred}
+	r := newIntegrationRelay(t)
+	r.AddHandler("recorder", h)
+	ids := enqueue(t, "pr:4", 1)
+
+	_, err := r.ProcessBatch(context.Background())
+	require.NoError(t, err)
+
+	var status string
+	var attempts int
+	var deferred bool
+	require.NoError(t, testDB.QueryRow(`
+		SELECT status, attempts, next_attempt_at > NOW() + INTERVAL '30 minutes'
+		FROM webhook_outbox_deliveries WHERE outbox_id = $1`, ids[0]).Scan(&status, &attempts, &deferred))
+	assert.Equal(t, webhook.OutboxStatusPending, status)
+	assert.Zero(t, attempts)
+	assert.True(t, deferred, "expected deferred delivery to be rescheduled after DeferDelay")
+
+	// Отложенная доставка не захватывается повторно до истечения задержки
+	n, err := r.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Len(t, h.delivered(), 1)
+}
+
+func TestRelay_FailedDeliveryRetriesThenFails(t *testing.T) {
+	cleanupOutbox(t)
+	defer cleanupOutbox(t)
+
+	h := &recordingHandler{err: errors.New("boom")}
+	r := newIntegrationRelay(t)
+	r.AddHandler("recorder", h)
+	ids := enqueue(t, "pr:5", 1)
+
+	for i := 0; i < webhook.DefaultRelayConfig().MaxAttempts; i++ {
+		_, err := testDB.Exec("UPDATE webhook_outbox_deliveries SET next_attempt_at = NOW() WHERE status = 'pending'")
+		require.NoError(t, err)
+		_, err = r.ProcessBatch(context.Background())
+		require.NoError(t, err)
+	}
+
+	var status string
+	require.NoError(t, testDB.QueryRow("SELECT status FROM webhook_outbox WHERE id = $1", ids[0]).Scan(&status))
+	assert.Equal(t, webhook.OutboxStatusFailed, status)
+	assert.Len(t, h.delivered(), webhook.DefaultRelayConfig().MaxAttempts)
+}
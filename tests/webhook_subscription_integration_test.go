@@ -0,0 +1,95 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/pr-reviewer/internal/logger"
+	"github.com/user/pr-reviewer/internal/webhook"
+)
+
+// failingDeliverer имитирует недоступного получателя
+type failingDeliverer struct{}
+
+func (failingDeliverer) Deliver(context.Context, *webhook.Subscription, *webhook.Payload) error {
+	return errors.New("connection refused")
+}
+
+// newReplicaManager создает webhook manager одной реплики с общим хранилищем
+func newReplicaManager(t *testing.T, maxFailureStreak int) *webhook.Manager {
+	t.Helper()
+	log, err := logger.New("error", "test")
+	require.NoError(t, err)
+
+	m := webhook.NewManager(failingDeliverer{}, log)
+	m.SetSubscriptionStore(webhook.NewSubscriptionStore(testDB.DB, log))
+	m.SetMaxFailureStreak(maxFailureStreak)
+	require.NoError(t, m.Reload(context.Background()))
+	return m
+}
+
+func createSubscription(t *testing.T) int64 {
+	t.Helper()
+	var id int64
+	require.NoError(t, testDB.QueryRow(`
+		INSERT INTO webhook_subscriptions (url, events)
+		VALUES ('https://example.com/hook', ARRAY['pr.created'])
+		RETURNING id`).Scan(&id))
+	t.Cleanup(func() {
+		_, _ = testDB.Exec("DELETE FROM webhook_subscriptions WHERE id = $1", id)
+	})
+	return id
+}
+
+func subscriptionState(t *testing.T, m *webhook.Manager, id int64) webhook.Subscription {
+	t.Helper()
+	subs, err := m.Subscriptions(context.Background())
+	require.NoError(t, err)
+	for _, sub := range subs {
+		if sub.ID == id {
+			return sub
+		}
+	}
+	t.Fatalf("subscription %d not found", id)
+	return webhook.Subscription{}
+}
+
+func TestWebhookSubscription_FailureStreakSharedAcrossReplicas(t *testing.T) {
+	ctx := context.Background()
+	id := createSubscription(t)
+	first := newReplicaManager(t, 2)
+	second := newReplicaManager(t, 2)
+
+	// Каждая реплика не смогла доставить по одному событию; повторы
+	// одного события серию не увеличивают
+	for i, m := range []*webhook.Manager{first, second} {
+		event := &webhook.OutboxEvent{ID: int64(i + 1), Event: webhook.EventPRCreated, Data: map[string]interface{}{}}
+		for attempt := 0; attempt < 3; attempt++ {
+			require.Error(t, m.HandleEvent(ctx, event))
+		}
+		m.HandleExhausted(ctx, event, errors.New("connection refused"))
+	}
+
+	var streak int
+	var active bool
+	require.NoError(t, testDB.QueryRow("SELECT failure_streak, active FROM webhook_subscriptions WHERE id = $1", id).Scan(&streak, &active))
+	assert.Equal(t, 2, streak)
+	assert.False(t, active, "expected subscription to be disabled after two failed events across replicas")
+
+	// Первая реплика узнает об отключении по уведомлению (Listener вызывает Reload)
+	require.NoError(t, first.Reload(ctx))
+	assert.False(t, subscriptionState(t, first, id).Active)
+
+	// Включение на второй реплике видно первой после Reload
+	_, err := second.EnableSubscription(ctx, id)
+	require.NoError(t, err)
+	require.NoError(t, first.Reload(ctx))
+	sub := subscriptionState(t, first, id)
+	assert.True(t, sub.Active)
+	assert.Zero(t, sub.FailureStreak)
+}